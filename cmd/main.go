@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"ecoquiz/internal/config"
 	"ecoquiz/internal/db"
 	"ecoquiz/internal/handlers"
+	"ecoquiz/internal/jobs"
+	"ecoquiz/internal/mailer"
+	middleware "ecoquiz/internal/middlewares"
+	"ecoquiz/internal/ratelimit"
 	"ecoquiz/internal/repos"
 	"ecoquiz/internal/routes"
 	"ecoquiz/internal/services"
 	"ecoquiz/internal/utils"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -27,27 +34,149 @@ func main() {
 	userRepo := repos.NewUserRepo(pool)
 	communityRepo := repos.NewCommunityRepo(pool)
 	quizRepo := repos.NewQuizRepo(pool)
+	quizVersionRepo := repos.NewQuizVersionRepo(pool)
 	questionRepo := repos.NewQuestionRepo(pool)
 	optionRepo := repos.NewOptionRepo(pool)
 	commentRepo := repos.NewCommentRepo(pool)
+	sessionRepo := repos.NewSessionRepo(pool)
+	identityRepo := repos.NewIdentityRepo(pool)
+	userTokenRepo := repos.NewUserTokenRepo(pool)
+	accessTokenRepo := repos.NewAccessTokenRepo(pool)
+	twoFactorRepo := repos.NewTwoFactorRepo(pool)
+	exportRepo := repos.NewExportRepo(pool)
 
-	authService := services.NewAuthService(userRepo, cfg.JwtSecret)
-	userService := services.NewUserService(userRepo, communityRepo, quizRepo)
-	oauthCfg := utils.GoogleConfig(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL)
+	passwordHasher, err := utils.NewPasswordHasher(
+		cfg.PasswordHashAlgorithm,
+		cfg.BcryptCost,
+		uint32(cfg.Argon2Time),
+		uint32(cfg.Argon2MemoryKB),
+		uint8(cfg.Argon2Threads),
+	)
+	if err != nil {
+		log.Fatal("invalid password hashing config: ", err)
+	}
+
+	twoFactorKey := cfg.TwoFactorEncryptionKey
+	if twoFactorKey == "" {
+		log.Println("TWO_FACTOR_ENCRYPTION_KEY is not set, falling back to JWTSecret")
+		twoFactorKey = cfg.JwtSecret
+	}
+	secretBox, err := utils.NewSecretBox(twoFactorKey)
+	if err != nil {
+		log.Fatal("invalid two-factor encryption key: ", err)
+	}
+
+	rateStore := ratelimit.NewMemoryStore()
+	limiter := ratelimit.NewLimiter(rateStore)
+	loginLockout := ratelimit.NewLockout(
+		rateStore,
+		cfg.LoginLockoutThreshold,
+		time.Duration(cfg.LoginFailureWindowMinutes)*time.Minute,
+		time.Duration(cfg.LoginLockoutBaseSeconds)*time.Second,
+		time.Duration(cfg.LoginLockoutMaxMinutes)*time.Minute,
+	)
+
+	var mail mailer.Mailer
+	switch cfg.MailDriver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			log.Fatal("MAIL_DRIVER=smtp needs SMTP_HOST")
+		}
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case "log":
+		// The log driver writes reset and verification links to the logs,
+		// so it has to be asked for by name.
+		mail = mailer.NewLogMailer(cfg.MailFrom, cfg.MailLogDir)
+	case "":
+		log.Fatal("MAIL_DRIVER is not set: use smtp, or log for local development")
+	default:
+		log.Fatal("unsupported MAIL_DRIVER: ", cfg.MailDriver)
+	}
+
+	authService := services.NewAuthService(
+		userRepo,
+		sessionRepo,
+		identityRepo,
+		userTokenRepo,
+		twoFactorRepo,
+		mail,
+		passwordHasher,
+		secretBox,
+		loginLockout,
+		services.AuthSettings{
+			JwtSecret:        cfg.JwtSecret,
+			AccessTTL:        time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute,
+			RefreshTTL:       time.Duration(cfg.RefreshTokenTTLDays) * 24 * time.Hour,
+			EmailVerifyTTL:   time.Duration(cfg.EmailVerifyTTLHours) * time.Hour,
+			PasswordResetTTL: time.Duration(cfg.PasswordResetTTLMinutes) * time.Minute,
+			ClientURL:        cfg.ClientURL,
+			TOTPIssuer:       cfg.TOTPIssuer,
+			PasswordPolicy: utils.PasswordPolicy{
+				MinLength:          cfg.PasswordMinLength,
+				MinCharClasses:     cfg.PasswordMinCharClasses,
+				RejectCommon:       cfg.PasswordRejectCommon,
+				RejectPersonalInfo: cfg.PasswordRejectPersonalInfo,
+			},
+		},
+	)
+	userService := services.NewUserService(
+		userRepo,
+		communityRepo,
+		quizRepo,
+		sessionRepo,
+		accessTokenRepo,
+		exportRepo,
+		time.Duration(cfg.AccountDeletionGraceDays)*24*time.Hour,
+	)
+	accessTokenService := services.NewAccessTokenService(accessTokenRepo)
+	oauthCfg := utils.GoogleConfig(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL, cfg.GoogleAuthURL, cfg.GoogleTokenURL)
+	idTokenVerifier := utils.NewIDTokenVerifier(
+		utils.NewJWKSKeySource(cfg.GoogleJWKSURL, nil),
+		cfg.GoogleClientID,
+		strings.Split(cfg.GoogleIssuers, ",")...,
+	)
 	communityService := services.NewCommunityService(communityRepo, userRepo, quizRepo)
-	quizService := services.NewQuizService(quizRepo, questionRepo, optionRepo, userRepo, communityRepo, commentRepo)
+	quizService := services.NewQuizService(
+		quizRepo,
+		quizVersionRepo,
+		questionRepo,
+		optionRepo,
+		userRepo,
+		communityRepo,
+		commentRepo,
+		cfg.RequireVerifiedEmailToQuiz,
+		time.Duration(cfg.AttemptGraceSeconds)*time.Second,
+	)
 	commentService := services.NewCommentService(commentRepo, questionRepo)
 
-	authHandler := handlers.NewAuthHandler(*authService, oauthCfg, cfg.ClientURL)
-	userHandler := handlers.NewUserHandler(*userService)
+	authHandler := handlers.NewAuthHandler(*authService, oauthCfg, idTokenVerifier, cfg.ClientURL)
+	userHandler := handlers.NewUserHandler(*userService, *authService)
+	accessTokenHandler := handlers.NewAccessTokenHandler(*accessTokenService)
 	communityHandler := handlers.NewCommunityHandler(*communityService)
 	quizHandler := handlers.NewQuizHandler(*quizService)
 	commentHandler := handlers.NewCommentHandler(*commentService)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go jobs.Every(jobsCtx, "purge-deleted-accounts", time.Hour, userService.PurgeDeletedAccounts)
+	go jobs.Every(jobsCtx, "expire-quiz-attempts", time.Minute, quizService.ExpireOverdueAttempts)
+	go jobs.Every(jobsCtx, "apply-quiz-schedules", time.Minute, quizService.ApplyQuizSchedules)
+
 	r := gin.Default()
 
+	// Without trusted proxies ClientIP is the peer address, so per-IP limits
+	// cannot be dodged with a forged X-Forwarded-For.
+	var trustedProxies []string
+	if cfg.TrustedProxies != "" {
+		trustedProxies = strings.Split(cfg.TrustedProxies, ",")
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("invalid TRUSTED_PROXIES: ", err)
+	}
+
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
@@ -58,10 +187,17 @@ func main() {
 		r,
 		authHandler,
 		userHandler,
+		accessTokenHandler,
 		communityHandler,
 		quizHandler,
 		commentHandler,
-		cfg.JwtSecret,
+		middleware.JWTAuth(cfg.JwtSecret, sessionRepo, accessTokenService),
+		routes.RateLimits{
+			Auth:          middleware.RateLimitByIP(limiter, "auth", ratelimit.Rule{Limit: cfg.AuthRateLimitPerMinute, Window: time.Minute}),
+			Register:      middleware.RateLimitByIP(limiter, "register", ratelimit.Rule{Limit: cfg.RegisterRateLimitPerHour, Window: time.Hour}),
+			CreateQuiz:    middleware.RateLimitByUser(limiter, "create_quiz", ratelimit.Rule{Limit: cfg.QuizCreateLimitPerHour, Window: time.Hour}),
+			CreateComment: middleware.RateLimitByUser(limiter, "create_comment", ratelimit.Rule{Limit: cfg.CommentCreateLimitPerMinute, Window: time.Minute}),
+		},
 	)
 
 	r.Run(":" + cfg.Port)
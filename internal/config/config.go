@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -13,8 +14,54 @@ type Config struct {
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectURL  string
+	GoogleAuthURL      string
+	GoogleTokenURL     string
+	GoogleJWKSURL      string
+	GoogleIssuers      string
 	JwtSecret          string
 	ClientURL          string
+
+	PasswordHashAlgorithm string
+	BcryptCost            int
+	Argon2Time            int
+	Argon2MemoryKB        int
+	Argon2Threads         int
+
+	AccessTokenTTLMinutes int
+	RefreshTokenTTLDays   int
+
+	MailDriver                 string
+	MailFrom                   string
+	MailLogDir                 string
+	SMTPHost                   string
+	SMTPPort                   string
+	SMTPUsername               string
+	SMTPPassword               string
+	EmailVerifyTTLHours        int
+	PasswordResetTTLMinutes    int
+	RequireVerifiedEmailToQuiz bool
+
+	TOTPIssuer             string
+	TwoFactorEncryptionKey string
+
+	TrustedProxies              string
+	AuthRateLimitPerMinute      int
+	RegisterRateLimitPerHour    int
+	LoginLockoutThreshold       int
+	LoginFailureWindowMinutes   int
+	LoginLockoutBaseSeconds     int
+	LoginLockoutMaxMinutes      int
+	QuizCreateLimitPerHour      int
+	CommentCreateLimitPerMinute int
+
+	AccountDeletionGraceDays int
+
+	PasswordMinLength          int
+	PasswordMinCharClasses     int
+	PasswordRejectCommon       bool
+	PasswordRejectPersonalInfo bool
+
+	AttemptGraceSeconds int
 }
 
 func Load() *Config {
@@ -29,8 +76,54 @@ func Load() *Config {
 		GoogleClientID:     Getenv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: Getenv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:  Getenv("GOOGLE_REDIRECT_URL", ""),
+		GoogleAuthURL:      Getenv("GOOGLE_AUTH_URL", ""),
+		GoogleTokenURL:     Getenv("GOOGLE_TOKEN_URL", ""),
+		GoogleJWKSURL:      Getenv("GOOGLE_JWKS_URL", "https://www.googleapis.com/oauth2/v3/certs"),
+		GoogleIssuers:      Getenv("GOOGLE_ISSUERS", "https://accounts.google.com,accounts.google.com"),
 		JwtSecret:          Getenv("JWTSecret", "your-jwt-secrect"),
 		ClientURL:          Getenv("CLIENT_URL", "http://localhost:5173"),
+
+		PasswordHashAlgorithm: Getenv("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+		BcryptCost:            GetenvInt("BCRYPT_COST", 12),
+		Argon2Time:            GetenvInt("ARGON2_TIME", 3),
+		Argon2MemoryKB:        GetenvInt("ARGON2_MEMORY_KB", 64*1024),
+		Argon2Threads:         GetenvInt("ARGON2_THREADS", 2),
+
+		AccessTokenTTLMinutes: GetenvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLDays:   GetenvInt("REFRESH_TOKEN_TTL_DAYS", 30),
+
+		MailDriver:                 Getenv("MAIL_DRIVER", ""),
+		MailFrom:                   Getenv("MAIL_FROM", "EcoQuiz <no-reply@ecoquiz.local>"),
+		MailLogDir:                 Getenv("MAIL_LOG_DIR", ""),
+		SMTPHost:                   Getenv("SMTP_HOST", ""),
+		SMTPPort:                   Getenv("SMTP_PORT", "587"),
+		SMTPUsername:               Getenv("SMTP_USERNAME", ""),
+		SMTPPassword:               Getenv("SMTP_PASSWORD", ""),
+		EmailVerifyTTLHours:        GetenvInt("EMAIL_VERIFY_TTL_HOURS", 48),
+		PasswordResetTTLMinutes:    GetenvInt("PASSWORD_RESET_TTL_MINUTES", 30),
+		RequireVerifiedEmailToQuiz: GetenvBool("REQUIRE_VERIFIED_EMAIL_TO_CREATE_QUIZ", false),
+
+		TOTPIssuer:             Getenv("TOTP_ISSUER", "EcoQuiz"),
+		TwoFactorEncryptionKey: Getenv("TWO_FACTOR_ENCRYPTION_KEY", ""),
+
+		TrustedProxies:              Getenv("TRUSTED_PROXIES", ""),
+		AuthRateLimitPerMinute:      GetenvInt("AUTH_RATE_LIMIT_PER_MINUTE", 10),
+		RegisterRateLimitPerHour:    GetenvInt("REGISTER_RATE_LIMIT_PER_HOUR", 5),
+		LoginLockoutThreshold:       GetenvInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+		LoginFailureWindowMinutes:   GetenvInt("LOGIN_FAILURE_WINDOW_MINUTES", 15),
+		LoginLockoutBaseSeconds:     GetenvInt("LOGIN_LOCKOUT_BASE_SECONDS", 30),
+		LoginLockoutMaxMinutes:      GetenvInt("LOGIN_LOCKOUT_MAX_MINUTES", 60),
+		QuizCreateLimitPerHour:      GetenvInt("QUIZ_CREATE_LIMIT_PER_HOUR", 20),
+		CommentCreateLimitPerMinute: GetenvInt("COMMENT_CREATE_LIMIT_PER_MINUTE", 5),
+
+		AccountDeletionGraceDays: GetenvInt("ACCOUNT_DELETION_GRACE_DAYS", 14),
+
+		PasswordMinLength:          GetenvInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordMinCharClasses:     GetenvInt("PASSWORD_MIN_CHAR_CLASSES", 2),
+		PasswordRejectCommon:       GetenvBool("PASSWORD_REJECT_COMMON", true),
+		PasswordRejectPersonalInfo: GetenvBool("PASSWORD_REJECT_PERSONAL_INFO", true),
+
+		AttemptGraceSeconds: GetenvInt("ATTEMPT_GRACE_SECONDS", 30),
 	}
 }
 
@@ -40,3 +133,19 @@ func Getenv(key, defaultValue string) string {
 	}
 	return os.Getenv(key)
 }
+
+func GetenvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func GetenvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -1,33 +1,196 @@
 package dto_quiz
 
+import "time"
+
 type CreateQuizRequest struct {
-	CommunityID     string     `json:"community_id" binding:"required,uuid"`
-	Title           string     `json:"title" binding:"required,max=200"`
-	Description     string     `json:"description" binding:"max=1000"`
-	DurationMinutes int        `json:"duration_minutes" binding:"gte=0"`
-	IsPublished     bool       `json:"is_published"`
-	Questions       []Question `json:"questions,omitempty"`
+	CommunityID      string     `json:"community_id" binding:"required,uuid"`
+	Title            string     `json:"title" binding:"required,max=200"`
+	Description      string     `json:"description" binding:"max=1000"`
+	DurationMinutes  int        `json:"duration_minutes" binding:"gte=0"`
+	IsPublished      bool       `json:"is_published"` // Used when Status is empty
+	Status           string     `json:"status" binding:"omitempty,oneof=draft scheduled published closed archived"`
+	PublishAt        *time.Time `json:"publish_at"`
+	CloseAt          *time.Time `json:"close_at"`
+	ShuffleQuestions bool       `json:"shuffle_questions"`
+	ShuffleOptions   bool       `json:"shuffle_options"`
+	MaxAttempts      *int       `json:"max_attempts" binding:"omitempty,gte=1"`
+	CooldownMinutes  int        `json:"cooldown_minutes" binding:"gte=0"`
+	ScoringRule      string     `json:"scoring_rule" binding:"omitempty,oneof=first best latest average"`
+	PassMark         *float64   `json:"pass_mark" binding:"omitempty,gte=0,lte=100"` // Percentage of the maximum points
+	License          string     `json:"license" binding:"omitempty,oneof=all_rights_reserved cc_by cc0"`
+	Pools            []Pool     `json:"pools,omitempty" binding:"omitempty,dive"`
+	Questions        []Question `json:"questions,omitempty"`
+}
+
+// Pool draws DrawCount random questions from those matching Tag and
+// Difficulty; leave a filter empty to match any question.
+type Pool struct {
+	Tag        string `json:"tag" binding:"max=50"`
+	Difficulty string `json:"difficulty"`
+	DrawCount  int    `json:"draw_count" binding:"required,gte=1"`
+}
+
+type SetPoolsRequest struct {
+	Pools []Pool `json:"pools" binding:"dive"`
 }
 type Question struct {
-	QuestionText  string   `json:"question_text" binding:"required"`
-	Explanation   string   `json:"explanation"`
-	CorrectAnswer string   `json:"correct_answer"`
-	OrderIndex    int      `json:"order_index"`
-	Options       []Option `json:"options,omitempty"`
+	QuestionText    string           `json:"question_text" binding:"required"`
+	Explanation     string           `json:"explanation"`
+	CorrectAnswer   string           `json:"correct_answer"`
+	OrderIndex      int              `json:"order_index"`
+	QuestionType    string           `json:"question_type"`
+	ScoringStrategy string           `json:"scoring_strategy"`
+	Settings        QuestionSettings `json:"settings"`
+	Tag             string           `json:"tag" binding:"max=50"`
+	Difficulty      string           `json:"difficulty"`
+	Points          float64          `json:"points" binding:"gte=0,lte=1000"` // 0 means the default of 1
+	NegativePoints  float64          `json:"negative_points" binding:"gte=0,lte=1000"`
+	Options         []Option         `json:"options,omitempty"`
+}
+
+// QuestionSettings holds the grading rules of short-answer and numeric
+// questions; other types ignore it.
+type QuestionSettings struct {
+	AcceptedAnswers []string           `json:"accepted_answers,omitempty"`
+	MaxEditDistance int                `json:"max_edit_distance,omitempty"`
+	Tolerance       float64            `json:"tolerance,omitempty"`
+	Unit            string             `json:"unit,omitempty"`
+	UnitFactors     map[string]float64 `json:"unit_factors,omitempty"`
 }
 
+// Option is a choice, an item to put in order (listed in the correct order),
+// or the left side of a matching pair whose right side is MatchText.
 type Option struct {
-	Text      string `json:"text" binding:"required"`
-	IsCorrect bool   `json:"is_correct"`
+	Text      string  `json:"text" binding:"required"`
+	IsCorrect bool    `json:"is_correct"`
+	MatchText *string `json:"match_text"`
 }
 
+// UpdateQuizRequest leaves a setting unchanged when its field is left out.
+// A max_attempts or pass_mark of 0 removes the limit or pass mark.
+type UpdateQuizRequest struct {
+	Title            string     `json:"title" binding:"required,max=200"`
+	Description      string     `json:"description" binding:"max=1000"`
+	DurationMinutes  int        `json:"duration_minutes" binding:"gte=0"`
+	IsPublished      *bool      `json:"is_published"` // Used when Status is empty; leaving both out keeps the lifecycle
+	Status           string     `json:"status" binding:"omitempty,oneof=draft scheduled published closed archived"`
+	PublishAt        *time.Time `json:"publish_at"`
+	CloseAt          *time.Time `json:"close_at"`
+	ShuffleQuestions *bool      `json:"shuffle_questions"`
+	ShuffleOptions   *bool      `json:"shuffle_options"`
+	MaxAttempts      *int       `json:"max_attempts" binding:"omitempty,gte=0"`
+	CooldownMinutes  *int       `json:"cooldown_minutes" binding:"omitempty,gte=0"`
+	ScoringRule      *string    `json:"scoring_rule" binding:"omitempty,oneof=first best latest average"`
+	PassMark         *float64   `json:"pass_mark" binding:"omitempty,gte=0,lte=100"`                     // Percentage of the maximum points
+	License          string     `json:"license" binding:"omitempty,oneof=all_rights_reserved cc_by cc0"` // Unchanged when empty
+}
+
+// ForkQuizRequest names the community the copy goes to. Title and License
+// default to the source quiz's.
+type ForkQuizRequest struct {
+	CommunityID string `json:"community_id" binding:"required,uuid"`
+	Title       string `json:"title" binding:"max=200"`
+	License     string `json:"license" binding:"omitempty,oneof=all_rights_reserved cc_by cc0"`
+}
+
+type UpdateQuestionRequest struct {
+	QuestionText    string           `json:"question_text" binding:"required"`
+	Explanation     string           `json:"explanation"`
+	CorrectAnswer   string           `json:"correct_answer"`
+	OrderIndex      int              `json:"order_index"`
+	QuestionType    string           `json:"question_type"`
+	ScoringStrategy string           `json:"scoring_strategy"`
+	Settings        QuestionSettings `json:"settings"`
+	Tag             string           `json:"tag" binding:"max=50"`
+	Difficulty      string           `json:"difficulty"`
+	Points          float64          `json:"points" binding:"gte=0,lte=1000"` // 0 means the default of 1
+	NegativePoints  float64          `json:"negative_points" binding:"gte=0,lte=1000"`
+	Options         []Option         `json:"options" binding:"omitempty,dive"` // nil keeps the current options
+}
+
+// ReorderQuestionsRequest lists every question of the quiz in its new order.
+type ReorderQuestionsRequest struct {
+	QuestionIDs []string `json:"question_ids" binding:"required,min=1,dive,uuid"`
+}
+
+// AddQuestionsRequest adds up to 100 questions at once.
+type AddQuestionsRequest struct {
+	Questions []Question `json:"questions" binding:"required,min=1,max=100,dive"`
+}
+
+type UpdateOptionRequest struct {
+	Text      string  `json:"text" binding:"required"`
+	IsCorrect bool    `json:"is_correct"`
+	MatchText *string `json:"match_text"`
+}
+
+// SubmitQuizRequest may be empty when every answer was autosaved. Answers
+// sent here replace those saved for the same questions.
 type SubmitQuizRequest struct {
-	Answers         []Answer `json:"answers" binding:"required"` // questionID -> answer
-	DurationMinutes int      `json:"duration_minutes" binding:"gte=0"`
+	Answers []Answer `json:"answers" binding:"dive"`
 }
 
+// Answer is matched to its question by QuestionID, not by position. Which
+// other field is read depends on the question type:
+//
+//   - single_choice: OptionID
+//   - multiple_choice: OptionIDs
+//   - true_false: Value
+//   - short_answer, numeric: Text
+//   - ordering: Order, every option ID in the chosen order
+//   - matching: Matches, one per option
+//
+// An answer that picks nothing leaves its question unanswered, which is
+// also what leaving the question out does. Flagged marks the question for
+// review; it is kept with the result and does not affect grading.
 type Answer struct {
-	OptionID string   `json:"option_id" binding:"required,uuid"`
-	QuestionID string `json:"question_id" binding:"required,uuid"`
-	AnswerText string `json:"answer_text" binding:"required"`
+	QuestionID string   `json:"question_id" binding:"required,uuid"`
+	OptionID   string   `json:"option_id" binding:"omitempty,uuid"`
+	OptionIDs  []string `json:"option_ids" binding:"omitempty,dive,uuid"`
+	Value      *bool    `json:"value"`
+	Text       string   `json:"text" binding:"max=1000"`
+	Order      []string `json:"order" binding:"omitempty,dive,uuid"`
+	Matches    []Match  `json:"matches" binding:"omitempty,dive"`
+	Flagged    bool     `json:"flagged"`
+}
+
+// Match pairs an option with one of the question's match_choices.
+type Match struct {
+	OptionID string `json:"option_id" binding:"required,uuid"`
+	Match    string `json:"match" binding:"required"`
+}
+
+// QuizDocument is the versioned JSON format quizzes are exported to and
+// imported from. Version goes up whenever a change would make an older
+// document import differently.
+type QuizDocument struct {
+	Format  string       `json:"format"` // always "ecoquiz.quiz"
+	Version int          `json:"version"`
+	Quiz    ExportedQuiz `json:"quiz"`
+}
+
+// ExportedQuiz is a quiz's content and settings, without anything tied to
+// where it lives (community, creator, lifecycle).
+type ExportedQuiz struct {
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	DurationMinutes  int        `json:"duration_minutes"`
+	ShuffleQuestions bool       `json:"shuffle_questions"`
+	ShuffleOptions   bool       `json:"shuffle_options"`
+	MaxAttempts      *int       `json:"max_attempts"`
+	CooldownMinutes  int        `json:"cooldown_minutes"`
+	ScoringRule      string     `json:"scoring_rule"`
+	PassMark         *float64   `json:"pass_mark,omitempty"`
+	Pools            []Pool     `json:"pools"`
+	Questions        []Question `json:"questions"`
+}
+
+// ImportQuizRequest comes with the uploaded file as multipart form fields.
+// Format is guessed from the file name when left empty, and Title replaces
+// the one in the file (CSV and GIFT files carry none).
+type ImportQuizRequest struct {
+	CommunityID string `form:"community_id" binding:"omitempty,uuid"`
+	Format      string `form:"format" binding:"omitempty,oneof=json csv gift qti"`
+	Title       string `form:"title" binding:"max=200"`
+	DryRun      bool   `form:"dry_run"`
 }
@@ -1,5 +1,7 @@
 package dto_quiz
 
+import "time"
+
 type QuizResponse struct {
 	ID                string    `json:"id"`
 	Community         Community `json:"community"`
@@ -38,6 +40,21 @@ type QuizDetailResponse struct {
 	Title             string             `json:"title"`
 	Description       string             `json:"description"`
 	DurationMinutes   int                `json:"duration_minutes"`
+	Status            string             `json:"status"`
+	PublishAt         *time.Time         `json:"publish_at"`
+	CloseAt           *time.Time         `json:"close_at"`
+	ShuffleQuestions  bool               `json:"shuffle_questions"`
+	ShuffleOptions    bool               `json:"shuffle_options"`
+	MaxAttempts       *int               `json:"max_attempts"`
+	CooldownMinutes   int                `json:"cooldown_minutes"`
+	ScoringRule       string             `json:"scoring_rule"`
+	PassMark          *float64           `json:"pass_mark"`
+	License           string             `json:"license"`
+	CanFork           bool               `json:"can_fork"`
+	ForkedFrom        *ForkSource        `json:"forked_from"` // nil unless the quiz is a fork
+	MaxPoints         *float64           `json:"max_points"`  // nil when pools draw the questions, as each attempt then has its own
+	AttemptsUsed      int                `json:"attempts_used"`
+	NextAttemptAt     *time.Time         `json:"next_attempt_at,omitempty"` // Set while the cooldown blocks a new attempt
 	LikesCount        int                `json:"likes_count"`
 	IsNew             bool               `json:"is_new"`
 	IsLike            bool               `json:"is_like"`
@@ -49,6 +66,14 @@ type QuizDetailResponse struct {
 	CurrentAttemptID  *string            `json:"current_attempt_id,omitempty"`
 }
 
+// ForkSource credits the quiz a fork was copied from. QuizID and Title are
+// empty once that quiz is deleted, and Author once its author is.
+type ForkSource struct {
+	QuizID string `json:"quiz_id,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Author *User  `json:"author"`
+}
+
 type CommunityDetail struct {
 	ID        string  `json:"id"`
 	Name      string  `json:"name"`
@@ -59,8 +84,11 @@ type CommunityDetail struct {
 
 type LeaderboardEntry struct {
 	AttemptID        string  `json:"attempt_id"`
+	TimeTakenSeconds int     `json:"time_taken_seconds"`
 	TimeTakenMinutes int     `json:"time_taken_minutes"`
 	Score            float64 `json:"score"`
+	Percentage       float64 `json:"percentage"`
+	AttemptsCount    int     `json:"attempts_count"`
 	SubmittedAt      string  `json:"submitted_at"`
 	User             User    `json:"user"`
 }
@@ -79,10 +107,42 @@ type TakeQuizResponse struct {
 	Questions []QuestionTake `json:"questions"`
 }
 
+// StartQuizResponse describes the attempt the server is timing. DeadlineAt is
+// nil for quizzes without a time limit.
+type StartQuizResponse struct {
+	AttemptID     string     `json:"attempt_id"`
+	AttemptNumber int        `json:"attempt_number"`
+	StartedAt     time.Time  `json:"started_at"`
+	DeadlineAt    *time.Time `json:"deadline_at"`
+	ServerTime    time.Time  `json:"server_time"`
+}
+
+// ResumeQuizResponse is what a client needs to pick a running attempt back
+// up: its timing, its questions in the order first shown, and the answers
+// saved so far.
+type ResumeQuizResponse struct {
+	Attempt          StartQuizResponse `json:"attempt"`
+	RemainingSeconds *int              `json:"remaining_seconds"` // nil without a time limit
+	Quiz             TakeQuizResponse  `json:"quiz"`
+	Answers          []SavedAnswer     `json:"answers"`
+}
+
+// SavedAnswer is an answer autosaved during an attempt, in the form it was
+// sent.
+type SavedAnswer struct {
+	Answer
+	SavedAt time.Time `json:"saved_at"`
+}
+
 type QuestionTake struct {
-	QuestionID   string       `json:"question_id"`
-	QuestionText string       `json:"question_text"`
-	Options      []OptionTake `json:"options"`
+	QuestionID     string       `json:"question_id"`
+	QuestionText   string       `json:"question_text"`
+	QuestionType   string       `json:"question_type"`
+	Points         float64      `json:"points"`
+	NegativePoints float64      `json:"negative_points,omitempty"` // Taken off for a wrong answer
+	Options        []OptionTake `json:"options"`
+	MatchChoices   []string     `json:"match_choices,omitempty"` // matching: right-hand sides to pick from
+	Unit           string       `json:"unit,omitempty"`          // numeric: unit the answer is expected in
 }
 
 type OptionTake struct {
@@ -93,13 +153,26 @@ type OptionTake struct {
 // UserAttemptWithQuiz represents a quiz attempt with quiz details for profile
 type UserAttemptWithQuiz struct {
 	Quiz             QuizInfo `json:"quiz"`
-	Score            int      `json:"score"`
+	Score            float64  `json:"score"`
+	TimeTakenSeconds int      `json:"timeTakenSeconds"`
 	TimeTakenMinutes int      `json:"timeTakenMinutes"`
 	AttemptNumber    int      `json:"attemptNumber"`
 	Percentage       float64  `json:"percentage"`
 	CompletedAt      string   `json:"completedAt"`
 }
 
+// UserQuizResult is a user's official result on one quiz, combining their
+// attempts as the quiz's scoring rule says.
+type UserQuizResult struct {
+	Quiz          QuizInfo `json:"quiz"`
+	AttemptID     string   `json:"attemptId"`
+	ScoringRule   string   `json:"scoringRule"`
+	Score         float64  `json:"score"`
+	Percentage    float64  `json:"percentage"`
+	AttemptsCount int      `json:"attemptsCount"`
+	CompletedAt   string   `json:"completedAt"`
+}
+
 // QuizInfo represents minimal quiz info for profile attempts
 type QuizInfo struct {
 	ID             string `json:"id"`
@@ -112,29 +185,58 @@ type QuizResultResponse struct {
 	AttemptID        string           `json:"attempt_id"`
 	QuizID           string           `json:"quiz_id"`
 	QuizTitle        string           `json:"quiz_title"`
-	Score            int              `json:"score"`
+	QuizVersion      int              `json:"quiz_version"`
+	Score            float64          `json:"score"` // Raw points, negative marks included
+	MaxPoints        float64          `json:"max_points"`
 	TotalQuestions   int              `json:"total_questions"`
 	Percentage       float64          `json:"percentage"`
+	PassMark         *float64         `json:"pass_mark"`
+	Passed           *bool            `json:"passed"` // nil when the quiz had no pass mark
+	Unanswered       int              `json:"unanswered"`
+	Status           string           `json:"status"`
+	TimeTakenSeconds int              `json:"time_taken_seconds"`
 	TimeTakenMinutes int              `json:"time_taken_minutes"`
 	CompletedAt      string           `json:"completed_at"`
 	Questions        []QuestionResult `json:"questions"`
 }
 
 type QuestionResult struct {
-	QuestionID    string            `json:"question_id"`
-	QuestionText  string            `json:"question_text"`
-	Explanation   string            `json:"explanation"`
-	CorrectAnswer string            `json:"correct_answer"`
-	UserAnswer    *string           `json:"user_answer"` // Option ID picked by user
-	IsCorrect     bool              `json:"is_correct"`
-	Options       []OptionWithStats `json:"options"`
-	Comments      []CommentRes      `json:"comments"`
+	QuestionID      string            `json:"question_id"`
+	QuestionText    string            `json:"question_text"`
+	QuestionType    string            `json:"question_type"`
+	ScoringStrategy string            `json:"scoring_strategy"`
+	Explanation     string            `json:"explanation"`
+	CorrectAnswer   string            `json:"correct_answer"`
+	UserAnswer      *string           `json:"user_answer"`  // First option ID picked by user
+	UserAnswers     []string          `json:"user_answers"` // Every option ID picked by user
+	TextAnswer      *string           `json:"text_answer,omitempty"`
+	Order           []string          `json:"order,omitempty"`
+	Matches         []MatchResult     `json:"matches,omitempty"`
+	AcceptedAnswers []string          `json:"accepted_answers,omitempty"`
+	Unit            string            `json:"unit,omitempty"`
+	IsCorrect       bool              `json:"is_correct"`
+	Result          string            `json:"result"` // correct - partial - wrong - unanswered
+	Flagged         bool              `json:"flagged"`
+	TimesShown      int               `json:"times_shown"` // Finished attempts that were asked this question
+	Points          float64           `json:"points"`
+	MaxPoints       float64           `json:"max_points"`
+	NegativePoints  float64           `json:"negative_points"`
+	Options         []OptionWithStats `json:"options"`
+	Comments        []CommentRes      `json:"comments"`
+}
+
+type MatchResult struct {
+	OptionID  string `json:"option_id"`
+	Match     string `json:"match"`
+	IsCorrect bool   `json:"is_correct"`
 }
 
 type OptionWithStats struct {
 	OptionID       string  `json:"option_id"`
 	Text           string  `json:"text"`
 	IsCorrect      bool    `json:"is_correct"`
+	Position       int     `json:"position"`
+	MatchText      *string `json:"match_text,omitempty"`
 	SelectionCount int     `json:"selection_count"`
 	Percentage     float64 `json:"percentage"`
 }
@@ -147,3 +249,32 @@ type CommentRes struct {
 	CommentText string  `json:"comment_text"`
 	CreatedAt   string  `json:"created_at"`
 }
+
+// ImportReport describes what an import read from the file. Quiz is the
+// request the file was turned into; it is only created when Valid and not a
+// dry run, and QuizID is then set.
+type ImportReport struct {
+	Format    string            `json:"format"`
+	Valid     bool              `json:"valid"`
+	Questions int               `json:"questions"`
+	Errors    []ImportIssue     `json:"errors"`
+	Quiz      CreateQuizRequest `json:"quiz"`
+	QuizID    string            `json:"quiz_id,omitempty"`
+}
+
+// ImportIssue is a problem found in an imported file. Line is 1-based and
+// File is only set for files read out of an archive; Question is the 1-based
+// number of the question it concerns, when it concerns one.
+type ImportIssue struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Question int    `json:"question,omitempty"`
+	Message  string `json:"message"`
+}
+
+// QuizExport is a quiz written out in one of the export formats.
+type QuizExport struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
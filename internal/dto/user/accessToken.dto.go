@@ -0,0 +1,16 @@
+package dto_user
+
+import "ecoquiz/internal/models"
+
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// CreatedAccessToken is the only response that carries the plaintext token;
+// it cannot be recovered afterwards.
+type CreatedAccessToken struct {
+	models.PersonalAccessToken
+	Token string `json:"token"`
+}
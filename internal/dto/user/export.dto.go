@@ -0,0 +1,58 @@
+package dto_user
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+const ExportFormatVersion = 1
+
+// UserExport is a personal data export: one JSON document per section.
+type UserExport struct {
+	FormatVersion int                        `json:"format_version"`
+	ExportedAt    time.Time                  `json:"exported_at"`
+	Sections      map[string]json.RawMessage `json:"sections"`
+}
+
+// WriteZip writes the export as a ZIP with a manifest.json and one
+// <section>.json file per section.
+func (e *UserExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	names := make([]string, 0, len(e.Sections))
+	for name := range e.Sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifest, err := json.MarshalIndent(map[string]any{
+		"format_version": e.FormatVersion,
+		"exported_at":    e.ExportedAt,
+		"files":          names,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "manifest.json", manifest, e.ExportedAt); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := writeZipFile(zw, name+".json", e.Sections[name], e.ExportedAt); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte, modified time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
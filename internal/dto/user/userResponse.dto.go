@@ -10,16 +10,30 @@ type Profile struct {
 	Banner      *string     `json:"banner"`
 	Communities []Community `json:"communities"`
 	Attempts    []Attempt   `json:"attempts"`
+	Results     []Result    `json:"results"` // Official result per quiz under its scoring rule
 	CreatedAt   time.Time   `json:"createdAt"`
+	// DeletionScheduledAt is set while the account is pending deletion.
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt"`
 }
 
 type Attempt struct {
-	Quiz             Quiz   `json:"quiz"`
-	Score            int    `json:"score"`
-	TimeTakenMinutes int    `json:"timeTakenMinutes"`
-	AttemptNumber    int    `json:"attemptNumber"`
-	Percentage       int    `json:"percentage"`
-	CompletedAt      string `json:"completedAt"`
+	Quiz             Quiz    `json:"quiz"`
+	Score            float64 `json:"score"`
+	TimeTakenSeconds int     `json:"timeTakenSeconds"`
+	TimeTakenMinutes int     `json:"timeTakenMinutes"`
+	AttemptNumber    int     `json:"attemptNumber"`
+	Percentage       int     `json:"percentage"`
+	CompletedAt      string  `json:"completedAt"`
+}
+
+type Result struct {
+	Quiz          Quiz    `json:"quiz"`
+	AttemptID     string  `json:"attemptId"`
+	ScoringRule   string  `json:"scoringRule"`
+	Score         float64 `json:"score"`
+	Percentage    float64 `json:"percentage"`
+	AttemptsCount int     `json:"attemptsCount"`
+	CompletedAt   string  `json:"completedAt"`
 }
 
 type Community struct {
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	dto_user "ecoquiz/internal/dto/user"
+	"ecoquiz/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessTokenHandler struct {
+	accessTokenService services.AccessTokenService
+}
+
+func NewAccessTokenHandler(accessTokenService services.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{accessTokenService: accessTokenService}
+}
+
+func (h *AccessTokenHandler) ListTokens(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	tokens, err := h.accessTokenService.ListTokens(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req dto_user.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.accessTokenService.CreateToken(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"token": token})
+}
+
+func (h *AccessTokenHandler) RevokeToken(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	if err := h.accessTokenService.RevokeToken(c.Request.Context(), userID, c.Param("tokenID")); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked"})
+}
@@ -1,26 +1,46 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"ecoquiz/internal/services"
 	"ecoquiz/internal/utils"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"golang.org/x/oauth2"
 )
 
+const (
+	accessTokenCookie  = "access_token"
+	refreshTokenCookie = "refresh_token"
+	refreshCookiePath  = "/api/auth"
+
+	oauthStateCookie = "oauth_state"
+	oauthNonceCookie = "oauth_nonce"
+	oauthLinkCookie  = "oauth_link_intent"
+	googleLinkCookie = "google_link"
+	oauthCookiePath  = "/api/auth/google"
+	oauthCookieTTL   = 10 * 60
+
+	twoFactorCookie     = "two_factor_challenge"
+	twoFactorCookiePath = "/api/auth/2fa"
+	twoFactorCookieTTL  = 5 * 60
+)
+
 type AuthHandler struct {
 	authService services.AuthService
 	oauthCfg    *oauth2.Config
+	verifier    *utils.IDTokenVerifier
 	clientURL   string
 }
 
-func NewAuthHandler(authService services.AuthService, oauthCfg *oauth2.Config, clientURL string) *AuthHandler {
+func NewAuthHandler(authService services.AuthService, oauthCfg *oauth2.Config, verifier *utils.IDTokenVerifier, clientURL string) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
 		oauthCfg:    oauthCfg,
+		verifier:    verifier,
 		clientURL:   clientURL,
 	}
 }
@@ -28,29 +48,20 @@ func NewAuthHandler(authService services.AuthService, oauthCfg *oauth2.Config, c
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=6"`
+		Email    string `json:"email" binding:"omitempty,email"`
+		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	jwtToken, err := h.authService.Register(c.Request.Context(), req.Username, req.Email, req.Password)
+	tokens, err := h.authService.Register(c.Request.Context(), req.Username, req.Email, req.Password, sessionMeta(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie(
-		"access_token",
-		jwtToken,
-		3600*24,
-		"/",
-		"",
-		false,
-		true,
-	)
+	setAuthCookies(c, tokens)
 	c.JSON(http.StatusOK, gin.H{"message": "registered successfully"})
 }
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -62,31 +73,77 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	fmt.Println(req)
-	jwtToken, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
+	result, err := h.authService.Login(c.Request.Context(), req.Email, req.Password, sessionMeta(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusUnauthorized)
 		return
 	}
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie(
-		"access_token",
-		jwtToken,
-		3600*24,
-		"/",
-		"",
-		false,
-		true,
-	)
-	c.JSON(http.StatusOK, gin.H{"message": "logged in successfully"})
+	respondSignIn(c, result, "logged in successfully")
 }
 
+// GoogleLogin starts the OAuth flow. The state and nonce are random per
+// attempt and bound to this browser through short-lived cookies, which the
+// callback checks to stop login CSRF and id_token replay.
 func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	url := h.oauthCfg.AuthCodeURL("state")
+	h.startGoogleFlow(c)
+}
+
+// GoogleLink starts the same OAuth flow for a signed-in user who wants to
+// attach their Google account.
+func (h *AuthHandler) GoogleLink(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	intent, err := h.authService.GoogleLinkIntent(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthLinkCookie, intent, oauthCookieTTL, oauthCookiePath, "", false, true)
+	h.startGoogleFlow(c)
+}
+
+func (h *AuthHandler) startGoogleFlow(c *gin.Context) {
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start google login"})
+		return
+	}
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start google login"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, oauthCookieTTL, oauthCookiePath, "", false, true)
+	c.SetCookie(oauthNonceCookie, nonce, oauthCookieTTL, oauthCookiePath, "", false, true)
+
+	url := h.oauthCfg.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce))
 	c.Redirect(http.StatusTemporaryRedirect, url)
 }
 
 func (h *AuthHandler) GoogleCallback(c *gin.Context) {
+	expectedState, _ := c.Cookie(oauthStateCookie)
+	nonce, _ := c.Cookie(oauthNonceCookie)
+	linkIntent, _ := c.Cookie(oauthLinkCookie)
+	c.SetCookie(oauthStateCookie, "", -1, oauthCookiePath, "", false, true)
+	c.SetCookie(oauthNonceCookie, "", -1, oauthCookiePath, "", false, true)
+	c.SetCookie(oauthLinkCookie, "", -1, oauthCookiePath, "", false, true)
+
+	state := c.Query("state")
+	if expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
+		return
+	}
+	if oauthErr := c.Query("error"); oauthErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "google login failed: " + oauthErr})
+		return
+	}
+
 	code := c.Query("code")
 	if code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "code not provided"})
@@ -105,42 +162,355 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 		return
 	}
 
-	token, _, err := new(jwt.Parser).ParseUnverified(idToken, jwt.MapClaims{})
+	claims, err := h.verifier.Verify(c.Request.Context(), idToken, nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if linkIntent != "" {
+		if err := h.authService.LinkGoogle(c.Request.Context(), linkIntent, claims); err != nil {
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/profile?linked=google", h.clientURL))
+		return
+	}
+
+	result, err := h.authService.GoogleHandle(c.Request.Context(), claims, sessionMeta(c))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	if result.LinkToken != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(googleLinkCookie, result.LinkToken, oauthCookieTTL, oauthCookiePath, "", false, true)
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/link-account?provider=google", h.clientURL))
+		return
+	}
+	if result.ChallengeToken != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(twoFactorCookie, result.ChallengeToken, twoFactorCookieTTL, twoFactorCookiePath, "", false, true)
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/login/two-factor", h.clientURL))
+		return
+	}
+	setAuthCookies(c, result.Tokens)
+	redirectURL := fmt.Sprintf("%s/home", h.clientURL)
+	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+}
+
+// ConfirmGoogleLink finishes a Google sign-in whose email belongs to an
+// existing password account: the user proves ownership with that password.
+func (h *AuthHandler) ConfirmGoogleLink(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	linkToken, err := c.Cookie(googleLinkCookie)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "link request expired, sign in with Google again"})
+		return
+	}
+
+	result, err := h.authService.ConfirmGoogleLink(c.Request.Context(), linkToken, req.Password, sessionMeta(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id token"})
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.SetCookie(googleLinkCookie, "", -1, oauthCookiePath, "", false, true)
+	respondSignIn(c, result, "google account linked")
+}
+
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	userID := c.GetString("userID")
+	identities, err := h.authService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	userID := c.GetString("userID")
+	provider := c.Param("provider")
+
+	if err := h.authService.UnlinkIdentity(c.Request.Context(), userID, provider); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": provider + " unlinked"})
+}
 
-	claims := token.Claims.(jwt.MapClaims)
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	refreshToken, _ := c.Cookie(refreshTokenCookie)
 
-	jwtToken, err := h.authService.GoogleHandle(c.Request.Context(), claims)
+	tokens, err := h.authService.Refresh(c.Request.Context(), refreshToken, sessionMeta(c))
 	if err != nil {
+		clearAuthCookies(c)
+		respondError(c, err, http.StatusUnauthorized)
+		return
+	}
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusOK, gin.H{"message": "token refreshed"})
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	refreshToken, _ := c.Cookie(refreshTokenCookie)
+
+	if err := h.authService.Logout(c.Request.Context(), refreshToken, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authService.LogoutAll(c.Request.Context(), userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out from all devices"})
+}
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	if err := h.authService.ResendVerificationEmail(c.Request.Context(), userID); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ChangePassword(c.Request.Context(), userID, c.GetString("sessionID"), req.CurrentPassword, req.NewPassword); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "password changed, other sessions have been signed out"})
+}
+
+// VerifyTwoFactor completes a login that required a second factor. The
+// challenge token comes from the login response body, or from a cookie when
+// the login went through the Google redirect.
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ChallengeToken == "" {
+		req.ChallengeToken, _ = c.Cookie(twoFactorCookie)
+	}
+
+	tokens, err := h.authService.VerifyTwoFactorLogin(c.Request.Context(), req.ChallengeToken, req.Code, sessionMeta(c))
+	if err != nil {
+		respondError(c, err, http.StatusUnauthorized)
+		return
+	}
+	c.SetCookie(twoFactorCookie, "", -1, twoFactorCookiePath, "", false, true)
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusOK, gin.H{"message": "logged in successfully"})
+}
+
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	status, err := h.authService.TwoFactorStatus(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	setup, err := h.authService.SetupTwoFactor(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(c.Request.Context(), userID, req.Code); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// respondSignIn sets the session cookies, or tells the client a second factor
+// is needed and hands back the challenge token for VerifyTwoFactor.
+func respondSignIn(c *gin.Context, result *services.SignInResult, message string) {
+	if result.ChallengeToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     result.ChallengeToken,
+		})
+		return
+	}
+	setAuthCookies(c, result.Tokens)
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func sessionMeta(c *gin.Context) services.SessionMeta {
+	return services.SessionMeta{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+func setAuthCookies(c *gin.Context, tokens *services.AuthTokens) {
 	c.SetSameSite(http.SameSiteLaxMode)
 	c.SetCookie(
-		"access_token",
-		jwtToken,
-		3600*24,
+		accessTokenCookie,
+		tokens.AccessToken,
+		int(time.Until(tokens.AccessExpiresAt).Seconds()),
 		"/",
 		"",
 		false,
 		true,
 	)
-	redirectURL := fmt.Sprintf("%s/home", h.clientURL)
-	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-}
-
-func (h *AuthHandler) Logout(c *gin.Context) {
 	c.SetCookie(
-		"access_token",
-		"",
-		-1,
-		"/",
+		refreshTokenCookie,
+		tokens.RefreshToken,
+		int(time.Until(tokens.RefreshExpiresAt).Seconds()),
+		refreshCookiePath,
 		"",
 		false,
 		true,
 	)
-	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie(accessTokenCookie, "", -1, "/", "", false, true)
+	c.SetCookie(refreshTokenCookie, "", -1, refreshCookiePath, "", false, true)
 }
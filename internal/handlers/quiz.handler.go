@@ -3,6 +3,10 @@ package handlers
 import (
 	dto_quiz "ecoquiz/internal/dto/quiz"
 	"ecoquiz/internal/services"
+	sharedErrors "ecoquiz/internal/shared/errors"
+	"errors"
+	"fmt"
+	"io"
 
 	"net/http"
 
@@ -32,7 +36,7 @@ func (h *QuizHandler) CreateQuiz(c *gin.Context) {
 	quizId, err := h.quizService.CreateQuiz(c.Request.Context(), userID, &quizRequest)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"quiz_id": quizId})
@@ -66,12 +70,25 @@ func (h *QuizHandler) TakeQuiz(c *gin.Context) {
 	}
 	quiz, err := h.quizService.TakeQuiz(c.Request.Context(), userID, quizID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"quiz": quiz})
 }
 
+func (h *QuizHandler) StartQuiz(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	attempt, err := h.quizService.StartQuiz(c.Request.Context(), userID, quizID)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attempt": attempt})
+}
+
 func (h *QuizHandler) SubmitQuiz(c *gin.Context) {
 	userID := c.GetString("userID")
 	quizID := c.Param("id")
@@ -83,19 +100,61 @@ func (h *QuizHandler) SubmitQuiz(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Quiz ID is required"})
 		return
 	}
+	// The body may be left out when every answer was autosaved.
 	var submitRequest dto_quiz.SubmitQuizRequest
-	if err := c.ShouldBindJSON(&submitRequest); err != nil {
+	if err := c.ShouldBindJSON(&submitRequest); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 	result, err := h.quizService.SubmitQuiz(c.Request.Context(), userID, quizID, &submitRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"result": result})
 }
 
+func (h *QuizHandler) SaveAnswer(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	var answer dto_quiz.Answer
+	if err := c.ShouldBindJSON(&answer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	saved, err := h.quizService.SaveAnswer(c.Request.Context(), userID, quizID, &answer)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"answer": saved})
+}
+
+func (h *QuizHandler) ClearAnswer(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+	questionID := c.Param("questionId")
+
+	if err := h.quizService.ClearAnswer(c.Request.Context(), userID, quizID, questionID); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Answer cleared successfully"})
+}
+
+func (h *QuizHandler) ResumeQuiz(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	resumed, err := h.quizService.ResumeQuiz(c.Request.Context(), userID, quizID)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"resume": resumed})
+}
+
 func (h *QuizHandler) ToggleLike(c *gin.Context) {
 	userID := c.GetString("userID")
 	quizID := c.Param("id")
@@ -129,7 +188,7 @@ func (h *QuizHandler) GetQuizByID(c *gin.Context) {
 
 	quiz, err := h.quizService.GetQuizByID(c.Request.Context(), userID, quizID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -137,6 +196,7 @@ func (h *QuizHandler) GetQuizByID(c *gin.Context) {
 }
 
 func (h *QuizHandler) GetQuizResult(c *gin.Context) {
+	userID := c.GetString("userID")
 	attemptID := c.Param("id")
 
 	if attemptID == "" {
@@ -144,9 +204,9 @@ func (h *QuizHandler) GetQuizResult(c *gin.Context) {
 		return
 	}
 
-	result, err := h.quizService.GetQuizResult(c.Request.Context(), attemptID)
+	result, err := h.quizService.GetQuizResult(c.Request.Context(), userID, attemptID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -154,6 +214,7 @@ func (h *QuizHandler) GetQuizResult(c *gin.Context) {
 }
 
 func (h *QuizHandler) AddQuestion(c *gin.Context) {
+	userID := c.GetString("userID")
 	quizID := c.Param("id")
 	if quizID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Quiz ID is required"})
@@ -166,10 +227,230 @@ func (h *QuizHandler) AddQuestion(c *gin.Context) {
 		return
 	}
 
-	if err := h.quizService.AddQuestion(c.Request.Context(), quizID, &qReq); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	questionID, err := h.quizService.AddQuestion(c.Request.Context(), userID, quizID, &qReq)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question added successfully", "question_id": questionID})
+}
+
+func (h *QuizHandler) AddQuestions(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	var req dto_quiz.AddQuestionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	questionIDs, err := h.quizService.AddQuestions(c.Request.Context(), userID, quizID, &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"question_ids": questionIDs})
+}
+
+func (h *QuizHandler) ReorderQuestions(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	var req dto_quiz.ReorderQuestionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.quizService.ReorderQuestions(c.Request.Context(), userID, quizID, &req); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Questions reordered successfully"})
+}
+
+func (h *QuizHandler) UpdateQuiz(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	var req dto_quiz.UpdateQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.quizService.UpdateQuiz(c.Request.Context(), userID, quizID, &req); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quiz updated successfully"})
+}
+
+func (h *QuizHandler) SetPools(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	var req dto_quiz.SetPoolsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.quizService.SetPools(c.Request.Context(), userID, quizID, &req); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pools updated successfully"})
+}
+
+func (h *QuizHandler) DeleteQuiz(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	if err := h.quizService.DeleteQuiz(c.Request.Context(), userID, quizID); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quiz deleted successfully"})
+}
+
+func (h *QuizHandler) UpdateQuestion(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+	questionID := c.Param("questionId")
+
+	var req dto_quiz.UpdateQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.quizService.UpdateQuestion(c.Request.Context(), userID, quizID, questionID, &req); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question updated successfully"})
+}
+
+func (h *QuizHandler) DeleteQuestion(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+	questionID := c.Param("questionId")
+
+	if err := h.quizService.DeleteQuestion(c.Request.Context(), userID, quizID, questionID); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question deleted successfully"})
+}
+
+func (h *QuizHandler) UpdateOption(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+	questionID := c.Param("questionId")
+	optionID := c.Param("optionId")
+
+	var req dto_quiz.UpdateOptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.quizService.UpdateOption(c.Request.Context(), userID, quizID, questionID, optionID, &req); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Option updated successfully"})
+}
+
+func (h *QuizHandler) DeleteOption(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+	questionID := c.Param("questionId")
+	optionID := c.Param("optionId")
+
+	if err := h.quizService.DeleteOption(c.Request.Context(), userID, quizID, questionID, optionID); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Option deleted successfully"})
+}
+
+// ExportQuiz sends the quiz as a download in the format named by ?format=
+// (json by default).
+func (h *QuizHandler) ExportQuiz(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	export, err := h.quizService.ExportQuiz(c.Request.Context(), userID, quizID, c.DefaultQuery("format", "json"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
+
+// ImportQuiz reads an uploaded question bank. A dry run only reports what
+// was read; otherwise a file without errors becomes a draft quiz.
+func (h *QuizHandler) ImportQuiz(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req dto_quiz.ImportQuizRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	report, err := h.quizService.ImportQuiz(c.Request.Context(), userID, &req, file)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case req.DryRun:
+		c.JSON(http.StatusOK, gin.H{"report": report})
+	case !report.Valid:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the file has errors", "code": sharedErrors.ErrInvalidImport, "report": report})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"quiz_id": report.QuizID, "report": report})
+	}
+}
+
+func (h *QuizHandler) ForkQuiz(c *gin.Context) {
+	userID := c.GetString("userID")
+	quizID := c.Param("id")
+
+	var req dto_quiz.ForkQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	forkID, err := h.quizService.ForkQuiz(c.Request.Context(), userID, quizID, &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Question added successfully"})
+	c.JSON(http.StatusCreated, gin.H{"quiz_id": forkID})
 }
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	sharedErrors "ecoquiz/internal/shared/errors"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError writes err using its AppError status and code when it has
+// one, and falls back to the given status for plain errors.
+func respondError(c *gin.Context, err error, fallbackStatus int) {
+	var appErr *sharedErrors.AppError
+	if errors.As(err, &appErr) {
+		if appErr.RetryAfter > 0 {
+			setRetryAfter(c, appErr.RetryAfter)
+		}
+		c.JSON(appErr.StatusCode, gin.H{"error": appErr.Message, "code": appErr.Code})
+		return
+	}
+	c.JSON(fallbackStatus, gin.H{"error": err.Error()})
+}
+
+// setRetryAfter writes the delay in whole seconds, rounded up so clients never
+// retry too early.
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
@@ -4,18 +4,24 @@ import (
 	dto_user "ecoquiz/internal/dto/user"
 
 	"ecoquiz/internal/services"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
 	userService services.UserService
+	authService services.AuthService
 }
 
-func NewUserHandler(userService services.UserService) *UserHandler {
+func NewUserHandler(userService services.UserService, authService services.AuthService) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		authService: authService,
 	}
 }
 func (h *UserHandler) Profile(c *gin.Context) {
@@ -109,3 +115,77 @@ func (h *UserHandler) UpdateBanner(c *gin.Context) {
 		"banner": bannerURL,
 	})
 }
+
+// DeleteAccount schedules the account for deletion after the grace period.
+// The caller must re-authenticate, see AuthService.Reauthenticate.
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	// The body is optional for accounts that re-authenticate by recent sign-in.
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.Reauthenticate(c.Request.Context(), userID, c.GetString("sessionID"), req.Password, req.Code); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	deleteAt, err := h.userService.ScheduleDeletion(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "account scheduled for deletion, sign in before the deadline to cancel",
+		"deletion_scheduled_at": deleteAt,
+	})
+}
+
+func (h *UserHandler) CancelDeletion(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	if err := h.userService.CancelDeletion(c.Request.Context(), userID); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "account deletion cancelled"})
+}
+
+// ExportData sends a download of everything stored about the user, as a ZIP
+// by default or as a single JSON document with ?format=json.
+func (h *UserHandler) ExportData(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be zip or json"})
+		return
+	}
+
+	export, err := h.userService.ExportData(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("ecoquiz-export-%s.%s", export.ExportedAt.Format(time.DateOnly), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+
+	if format == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := export.WriteZip(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
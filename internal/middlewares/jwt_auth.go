@@ -1,8 +1,10 @@
 package middleware
 
 import (
-
+	"context"
+	"ecoquiz/internal/utils"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -14,22 +16,56 @@ type JwtClaims struct {
 	jwt.RegisteredClaims
 }
 
-func JWTAuth(secret string) gin.HandlerFunc {
+// SessionChecker reports whether the session an access token was issued for
+// is still active (not revoked and not expired).
+type SessionChecker interface {
+	IsActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// AccessTokenAuthenticator resolves a personal access token to its owner and
+// granted scopes.
+type AccessTokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (userID string, scopes []string, err error)
+}
+
+// JWTAuth accepts either an `Authorization: Bearer` header or the
+// access_token cookie. Bearer values carrying the personal access token
+// prefix are checked against accessTokens; everything else must be a session
+// JWT.
+func JWTAuth(secret string, sessions SessionChecker, accessTokens AccessTokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		tokenString, err := c.Cookie("access_token")
-		if err != nil {
+		tokenString := bearerToken(c)
+		if tokenString == "" {
+			tokenString, _ = c.Cookie("access_token")
+		}
+		if tokenString == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "access token not found",
 			})
 			return
 		}
 
+		if strings.HasPrefix(tokenString, utils.PersonalAccessTokenPrefix) {
+			userID, scopes, err := accessTokens.Authenticate(c.Request.Context(), tokenString)
+			if err != nil {
+				abortWithError(c, err, http.StatusInternalServerError)
+				return
+			}
+
+			c.Set("userID", userID)
+			c.Set("tokenScopes", scopes)
+
+			c.Next()
+			return
+		}
+
 		token, err := jwt.Parse(
 			tokenString,
 			func(token *jwt.Token) (interface{}, error) {
 				return []byte(secret), nil
 			},
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
 		)
 
 		if err != nil || !token.Valid {
@@ -41,13 +77,43 @@ func JWTAuth(secret string) gin.HandlerFunc {
 
 		claims := token.Claims.(jwt.MapClaims)
 
-		userID := claims["sub"].(string)
-		email := claims["em"].(string)
+		userID, _ := claims["sub"].(string)
+		email, _ := claims["em"].(string)
+		sessionID, _ := claims["sid"].(string)
+		if userID == "" || sessionID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid token",
+			})
+			return
+		}
+
+		active, err := sessions.IsActive(c.Request.Context(), sessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to check session",
+			})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "session revoked",
+			})
+			return
+		}
 
 		c.Set("userID", userID)
 		c.Set("email", email)
-
+		c.Set("sessionID", sessionID)
 
 		c.Next()
 	}
 }
+
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
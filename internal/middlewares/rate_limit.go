@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"ecoquiz/internal/ratelimit"
+	sharedErrors "ecoquiz/internal/shared/errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitByIP limits requests per client IP. name keeps the buckets of
+// different routes apart.
+func RateLimitByIP(limiter *ratelimit.Limiter, name string, rule ratelimit.Rule) gin.HandlerFunc {
+	return rateLimit(limiter, rule, func(c *gin.Context) string {
+		return name + ":ip:" + c.ClientIP()
+	})
+}
+
+// RateLimitByUser limits requests per authenticated user and must run after
+// JWTAuth.
+func RateLimitByUser(limiter *ratelimit.Limiter, name string, rule ratelimit.Rule) gin.HandlerFunc {
+	return rateLimit(limiter, rule, func(c *gin.Context) string {
+		return name + ":user:" + c.GetString("userID")
+	})
+}
+
+func rateLimit(limiter *ratelimit.Limiter, rule ratelimit.Rule, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, retryAfter, err := limiter.Allow(c.Request.Context(), key(c), rule)
+		if err != nil {
+			// Fail open: an unavailable store should not take the API down.
+			log.Printf("rate limiter error: %v", err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "too many requests, try again later",
+				"code":  sharedErrors.ErrRateLimited,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	sharedErrors "ecoquiz/internal/shared/errors"
+	goErrors "errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope rejects requests authenticated with a personal access token
+// that was not granted scope. Session logins carry no scopes and always pass.
+// It must run after JWTAuth.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("tokenScopes")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		scopes, _ := value.([]string)
+		if !slices.Contains(scopes, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "token is missing the " + scope + " scope",
+				"code":  sharedErrors.ErrInsufficientScope,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession only lets through requests authenticated with a login
+// session, keeping account-level operations such as managing tokens or
+// sign-in methods out of reach of personal access tokens.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("sessionID") == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "this action requires an interactive login",
+				"code":  sharedErrors.ErrSessionRequired,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortWithError(c *gin.Context, err error, fallbackStatus int) {
+	var appErr *sharedErrors.AppError
+	if goErrors.As(err, &appErr) {
+		c.AbortWithStatusJSON(appErr.StatusCode, gin.H{"error": appErr.Message, "code": appErr.Code})
+		return
+	}
+	c.AbortWithStatusJSON(fallbackStatus, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN google_id VARCHAR(255) UNIQUE;
+
+UPDATE users u
+SET google_id = i.provider_subject
+FROM user_identities i
+WHERE i.user_id = u.id AND i.provider = 'google';
+
+DROP TABLE IF EXISTS user_identities;
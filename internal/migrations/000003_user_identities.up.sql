@@ -0,0 +1,24 @@
+-- =====================
+-- User Identities (external sign-in providers)
+-- =====================
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    provider_subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, provider_subject),
+    UNIQUE (user_id, provider)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- Only accounts created through Google sign-in carry a real Google subject;
+-- password accounts were given a random placeholder that is dropped here.
+INSERT INTO user_identities (user_id, provider, provider_subject, email)
+SELECT id, 'google', google_id, email
+FROM users
+WHERE password_hash IS NULL AND google_id IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN google_id;
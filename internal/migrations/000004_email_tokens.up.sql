@@ -0,0 +1,23 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Google only signs in accounts whose email it has verified.
+UPDATE users u
+SET email_verified_at = NOW()
+FROM user_identities i
+WHERE i.user_id = u.id AND i.provider = 'google';
+
+-- =====================
+-- User Tokens (email verification, password reset)
+-- =====================
+CREATE TABLE user_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (purpose IN ('verify_email', 'reset_password'))
+);
+
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_quiz_attempts_version_id;
+ALTER TABLE quiz_attempts DROP COLUMN IF EXISTS quiz_version_id;
+DROP TABLE IF EXISTS quiz_versions;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS current_version;
+
+DELETE FROM user_answers ua
+WHERE NOT EXISTS (SELECT 1 FROM questions q WHERE q.id = ua.question_id)
+   OR NOT EXISTS (SELECT 1 FROM options o WHERE o.id = ua.option_id);
+
+ALTER TABLE user_answers
+    ADD CONSTRAINT user_answers_question_id_fkey
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE;
+ALTER TABLE user_answers
+    ADD CONSTRAINT user_answers_option_id_fkey
+    FOREIGN KEY (option_id) REFERENCES options(id) ON DELETE CASCADE;
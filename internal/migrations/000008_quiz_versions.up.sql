@@ -0,0 +1,64 @@
+-- =====================
+-- Quiz versions
+-- =====================
+-- Every edit to a quiz stores a snapshot of its questions and options so
+-- attempts keep rendering the content they were taken against.
+ALTER TABLE quizzes ADD COLUMN current_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE quiz_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (quiz_id, version)
+);
+
+ALTER TABLE quiz_attempts
+    ADD COLUMN quiz_version_id UUID REFERENCES quiz_versions(id) ON DELETE SET NULL;
+
+-- Answers outlive the questions and options they point at once those are
+-- edited away; the pinned snapshot is the source of truth for old attempts.
+ALTER TABLE user_answers DROP CONSTRAINT IF EXISTS user_answers_question_id_fkey;
+ALTER TABLE user_answers DROP CONSTRAINT IF EXISTS user_answers_option_id_fkey;
+
+-- Backfill version 1 for existing quizzes and pin their attempts to it.
+UPDATE quizzes SET current_version = 1;
+
+INSERT INTO quiz_versions (quiz_id, version, snapshot)
+SELECT
+    q.id,
+    1,
+    jsonb_build_object(
+        'title', q.title,
+        'description', COALESCE(q.description, ''),
+        'duration_minutes', COALESCE(q.duration_minutes, 0),
+        'questions', COALESCE((
+            SELECT jsonb_agg(jsonb_build_object(
+                'id', qs.id,
+                'question_text', qs.question_text,
+                'explanation', COALESCE(qs.explanation, ''),
+                'correct_answer', COALESCE(qs.correct_answer, ''),
+                'order_index', qs.order_index,
+                'options', COALESCE((
+                    SELECT jsonb_agg(jsonb_build_object(
+                        'id', o.id,
+                        'text', o.text,
+                        'is_correct', o.is_correct
+                    ))
+                    FROM options o
+                    WHERE o.question_id = qs.id
+                ), '[]'::jsonb)
+            ) ORDER BY qs.order_index)
+            FROM questions qs
+            WHERE qs.quiz_id = q.id
+        ), '[]'::jsonb)
+    )
+FROM quizzes q;
+
+UPDATE quiz_attempts qa
+SET quiz_version_id = qv.id
+FROM quiz_versions qv
+WHERE qv.quiz_id = qa.quiz_id AND qv.version = 1;
+
+CREATE INDEX idx_quiz_attempts_version_id ON quiz_attempts(quiz_version_id);
@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS idx_quiz_attempts_in_progress;
+
+DELETE FROM quiz_attempts WHERE status = 'in_progress';
+
+ALTER TABLE quiz_attempts
+    ALTER COLUMN completed_at SET DEFAULT NOW(),
+    ALTER COLUMN completed_at SET NOT NULL;
+
+ALTER TABLE quiz_attempts DROP CONSTRAINT IF EXISTS quiz_attempts_time_taken_seconds_check;
+UPDATE quiz_attempts SET time_taken_seconds = time_taken_seconds / 60;
+ALTER TABLE quiz_attempts ALTER COLUMN time_taken_seconds DROP NOT NULL;
+ALTER TABLE quiz_attempts ALTER COLUMN time_taken_seconds DROP DEFAULT;
+ALTER TABLE quiz_attempts RENAME COLUMN time_taken_seconds TO time_taken_minutes;
+ALTER TABLE quiz_attempts
+    ADD CONSTRAINT quiz_attempts_time_taken_minutes_check CHECK (time_taken_minutes >= 0);
+
+ALTER TABLE quiz_attempts
+    DROP CONSTRAINT IF EXISTS quiz_attempts_status_check,
+    DROP COLUMN IF EXISTS deadline_at,
+    DROP COLUMN IF EXISTS started_at,
+    DROP COLUMN IF EXISTS status;
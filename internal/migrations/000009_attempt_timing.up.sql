@@ -0,0 +1,32 @@
+-- =====================
+-- Server-side attempt timing
+-- =====================
+-- Attempts are created when the user starts a quiz and finished on submit or
+-- by the expiry sweeper. Durations are measured by the server in seconds.
+ALTER TABLE quiz_attempts
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'completed',
+    ADD COLUMN started_at TIMESTAMP,
+    ADD COLUMN deadline_at TIMESTAMP,
+    ADD CONSTRAINT quiz_attempts_status_check
+        CHECK (status IN ('in_progress', 'completed', 'expired'));
+
+ALTER TABLE quiz_attempts RENAME COLUMN time_taken_minutes TO time_taken_seconds;
+ALTER TABLE quiz_attempts DROP CONSTRAINT IF EXISTS quiz_attempts_time_taken_minutes_check;
+UPDATE quiz_attempts SET time_taken_seconds = COALESCE(time_taken_seconds, 0) * 60;
+ALTER TABLE quiz_attempts
+    ALTER COLUMN time_taken_seconds SET DEFAULT 0,
+    ALTER COLUMN time_taken_seconds SET NOT NULL,
+    ADD CONSTRAINT quiz_attempts_time_taken_seconds_check CHECK (time_taken_seconds >= 0);
+
+UPDATE quiz_attempts
+SET started_at = completed_at - make_interval(secs => time_taken_seconds);
+ALTER TABLE quiz_attempts
+    ALTER COLUMN started_at SET DEFAULT NOW(),
+    ALTER COLUMN started_at SET NOT NULL;
+
+ALTER TABLE quiz_attempts
+    ALTER COLUMN completed_at DROP NOT NULL,
+    ALTER COLUMN completed_at DROP DEFAULT;
+
+CREATE INDEX idx_quiz_attempts_in_progress ON quiz_attempts(deadline_at)
+    WHERE status = 'in_progress';
@@ -0,0 +1,18 @@
+ALTER TABLE quiz_attempts ALTER COLUMN score TYPE INTEGER USING ROUND(score)::INTEGER;
+
+-- Keep a single selection per question before restoring the old constraint.
+DELETE FROM user_answers ua
+USING user_answers other
+WHERE ua.attempt_id = other.attempt_id
+  AND ua.question_id = other.question_id
+  AND ua.id > other.id;
+
+ALTER TABLE user_answers DROP CONSTRAINT IF EXISTS user_answers_attempt_question_option_key;
+ALTER TABLE user_answers
+    ADD CONSTRAINT user_answers_attempt_id_question_id_key UNIQUE (attempt_id, question_id);
+
+ALTER TABLE questions
+    DROP CONSTRAINT IF EXISTS questions_scoring_strategy_check,
+    DROP CONSTRAINT IF EXISTS questions_question_type_check,
+    DROP COLUMN IF EXISTS scoring_strategy,
+    DROP COLUMN IF EXISTS question_type;
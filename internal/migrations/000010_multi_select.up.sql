@@ -0,0 +1,18 @@
+-- =====================
+-- Multi-select questions and partial credit
+-- =====================
+ALTER TABLE questions
+    ADD COLUMN question_type VARCHAR(20) NOT NULL DEFAULT 'single_choice',
+    ADD COLUMN scoring_strategy VARCHAR(20) NOT NULL DEFAULT 'all_or_nothing',
+    ADD CONSTRAINT questions_question_type_check
+        CHECK (question_type IN ('single_choice', 'multiple_choice')),
+    ADD CONSTRAINT questions_scoring_strategy_check
+        CHECK (scoring_strategy IN ('all_or_nothing', 'proportional', 'right_minus_wrong'));
+
+-- One row per selected option, so a question can have several.
+ALTER TABLE user_answers DROP CONSTRAINT IF EXISTS user_answers_attempt_id_question_id_key;
+ALTER TABLE user_answers
+    ADD CONSTRAINT user_answers_attempt_question_option_key UNIQUE (attempt_id, question_id, option_id);
+
+-- Partially correct answers score fractions of a point.
+ALTER TABLE quiz_attempts ALTER COLUMN score TYPE NUMERIC(8,2);
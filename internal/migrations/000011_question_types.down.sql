@@ -0,0 +1,18 @@
+DELETE FROM user_answers WHERE option_id IS NULL;
+DELETE FROM questions
+WHERE question_type NOT IN ('single_choice', 'multiple_choice');
+
+ALTER TABLE user_answers
+    DROP COLUMN IF EXISTS position,
+    DROP COLUMN IF EXISTS text_answer,
+    ALTER COLUMN option_id SET NOT NULL;
+
+ALTER TABLE options
+    DROP COLUMN IF EXISTS match_text,
+    DROP COLUMN IF EXISTS position;
+
+ALTER TABLE questions
+    DROP CONSTRAINT IF EXISTS questions_question_type_check,
+    ADD CONSTRAINT questions_question_type_check
+        CHECK (question_type IN ('single_choice', 'multiple_choice')),
+    DROP COLUMN IF EXISTS settings;
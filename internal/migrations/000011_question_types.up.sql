@@ -0,0 +1,35 @@
+-- =====================
+-- Typed questions
+-- =====================
+-- settings holds the type-specific grading rules: accepted alternatives and
+-- typo tolerance for short answers, tolerance and units for numeric answers.
+ALTER TABLE questions
+    ADD COLUMN settings JSONB NOT NULL DEFAULT '{}'::jsonb,
+    DROP CONSTRAINT IF EXISTS questions_question_type_check,
+    ADD CONSTRAINT questions_question_type_check
+        CHECK (question_type IN (
+            'single_choice', 'multiple_choice', 'true_false',
+            'short_answer', 'numeric', 'ordering', 'matching'
+        ));
+
+-- position is the option's display order and, for ordering questions, its
+-- correct place. match_text is the right-hand side of a matching pair.
+ALTER TABLE options
+    ADD COLUMN position INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN match_text TEXT;
+
+UPDATE options o
+SET position = ranked.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY question_id ORDER BY ctid) - 1 AS position
+    FROM options
+) ranked
+WHERE ranked.id = o.id;
+
+-- True/false, short-answer and numeric answers store text_answer with no
+-- option. Ordering answers store one row per item with its position;
+-- matching answers one row per item with the chosen match in text_answer.
+ALTER TABLE user_answers
+    ALTER COLUMN option_id DROP NOT NULL,
+    ADD COLUMN text_answer TEXT,
+    ADD COLUMN position INTEGER;
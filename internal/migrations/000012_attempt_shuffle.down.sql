@@ -0,0 +1,6 @@
+ALTER TABLE quiz_attempts
+    DROP COLUMN IF EXISTS shuffle_seed;
+
+ALTER TABLE quizzes
+    DROP COLUMN IF EXISTS shuffle_options,
+    DROP COLUMN IF EXISTS shuffle_questions;
@@ -0,0 +1,13 @@
+-- =====================
+-- Per-attempt shuffling
+-- =====================
+-- The flags are part of the quiz content and copied into each version, so an
+-- attempt is shuffled according to the version it was started on.
+ALTER TABLE quizzes
+    ADD COLUMN shuffle_questions BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN shuffle_options BOOLEAN NOT NULL DEFAULT false;
+
+-- shuffle_seed fixes the order one learner sees. NULL for attempts started
+-- before shuffling existed, which keep the stored order.
+ALTER TABLE quiz_attempts
+    ADD COLUMN shuffle_seed BIGINT;
@@ -0,0 +1,9 @@
+ALTER TABLE quiz_attempts
+    DROP COLUMN IF EXISTS question_ids;
+
+DROP TABLE IF EXISTS quiz_pools;
+
+ALTER TABLE questions
+    DROP CONSTRAINT IF EXISTS questions_difficulty_check,
+    DROP COLUMN IF EXISTS difficulty,
+    DROP COLUMN IF EXISTS tag;
@@ -0,0 +1,30 @@
+-- =====================
+-- Question pools
+-- =====================
+-- Questions can be labelled so pools can draw from a subset of the quiz.
+-- An empty string means unlabelled.
+ALTER TABLE questions
+    ADD COLUMN tag VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN difficulty VARCHAR(10) NOT NULL DEFAULT '',
+    ADD CONSTRAINT questions_difficulty_check
+        CHECK (difficulty IN ('', 'easy', 'medium', 'hard'));
+
+-- Each pool draws draw_count random questions from those matching its tag
+-- and difficulty (an empty filter matches every question). A quiz without
+-- pools asks every question.
+CREATE TABLE IF NOT EXISTS quiz_pools (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    tag VARCHAR(50) NOT NULL DEFAULT '',
+    difficulty VARCHAR(10) NOT NULL DEFAULT ''
+        CHECK (difficulty IN ('', 'easy', 'medium', 'hard')),
+    draw_count INTEGER NOT NULL CHECK (draw_count > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (quiz_id, position)
+);
+
+-- The questions drawn for an attempt. NULL for attempts that predate pools,
+-- which were asked every question of their version.
+ALTER TABLE quiz_attempts
+    ADD COLUMN question_ids UUID[];
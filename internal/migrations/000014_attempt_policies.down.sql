@@ -0,0 +1,6 @@
+DROP VIEW IF EXISTS official_attempts;
+
+ALTER TABLE quizzes
+    DROP COLUMN IF EXISTS scoring_rule,
+    DROP COLUMN IF EXISTS cooldown_minutes,
+    DROP COLUMN IF EXISTS max_attempts;
@@ -0,0 +1,58 @@
+-- =====================
+-- Attempt policies
+-- =====================
+-- max_attempts NULL means unlimited. scoring_rule picks which finished
+-- attempts make up a learner's official result: the first, the best, the
+-- latest, or the average of all of them.
+ALTER TABLE quizzes
+    ADD COLUMN max_attempts INTEGER CHECK (max_attempts > 0),
+    ADD COLUMN cooldown_minutes INTEGER NOT NULL DEFAULT 0 CHECK (cooldown_minutes >= 0),
+    ADD COLUMN scoring_rule VARCHAR(10) NOT NULL DEFAULT 'first'
+        CHECK (scoring_rule IN ('first', 'best', 'latest', 'average'));
+
+-- One row per learner and quiz with their official result under the quiz's
+-- current scoring rule. Leaderboards, quiz averages and profiles all read
+-- from here so they agree. For 'average' the row carries the latest attempt.
+CREATE OR REPLACE VIEW official_attempts AS
+WITH finished AS (
+    SELECT
+        qa.id,
+        qa.quiz_id,
+        qa.user_id,
+        qa.score,
+        qa.percentage,
+        qa.time_taken_seconds,
+        qa.attempt_number,
+        qa.completed_at,
+        q.scoring_rule,
+        COUNT(*) OVER learner AS attempts_count,
+        AVG(qa.score) OVER learner AS average_score,
+        AVG(qa.percentage) OVER learner AS average_percentage,
+        AVG(qa.time_taken_seconds) OVER learner AS average_time,
+        ROW_NUMBER() OVER (learner ORDER BY qa.attempt_number ASC) AS first_rank,
+        ROW_NUMBER() OVER (learner ORDER BY qa.attempt_number DESC) AS latest_rank,
+        ROW_NUMBER() OVER (
+            learner ORDER BY qa.percentage DESC, qa.time_taken_seconds ASC, qa.attempt_number ASC
+        ) AS best_rank
+    FROM quiz_attempts qa
+    JOIN quizzes q ON q.id = qa.quiz_id
+    WHERE qa.status <> 'in_progress'
+    WINDOW learner AS (PARTITION BY qa.quiz_id, qa.user_id)
+)
+SELECT
+    id AS attempt_id,
+    quiz_id,
+    user_id,
+    scoring_rule,
+    attempts_count,
+    CASE WHEN scoring_rule = 'average' THEN ROUND(average_score, 2) ELSE score END AS score,
+    CASE WHEN scoring_rule = 'average' THEN ROUND(average_percentage, 2) ELSE percentage END AS percentage,
+    CASE WHEN scoring_rule = 'average' THEN ROUND(average_time)::INTEGER ELSE time_taken_seconds END AS time_taken_seconds,
+    completed_at
+FROM finished
+WHERE CASE scoring_rule
+    WHEN 'best' THEN best_rank = 1
+    WHEN 'latest' THEN latest_rank = 1
+    WHEN 'average' THEN latest_rank = 1
+    ELSE first_rank = 1
+END;
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_quizzes_closing;
+DROP INDEX IF EXISTS idx_quizzes_scheduled;
+
+ALTER TABLE quizzes DROP COLUMN is_published;
+ALTER TABLE quizzes ADD COLUMN is_published BOOLEAN NOT NULL DEFAULT false;
+UPDATE quizzes SET is_published = (status = 'published');
+
+ALTER TABLE quizzes
+    DROP CONSTRAINT IF EXISTS quizzes_scheduled_check,
+    DROP CONSTRAINT IF EXISTS quizzes_window_check,
+    DROP COLUMN IF EXISTS close_at,
+    DROP COLUMN IF EXISTS publish_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,32 @@
+-- =====================
+-- Quiz lifecycle
+-- =====================
+-- draft: being written, visible to its editors only.
+-- scheduled: goes live at publish_at; until then like a draft.
+-- published: listed and open for attempts until close_at, if any.
+-- closed: no new attempts or submissions; results stay readable.
+-- archived: like closed, and no longer listed.
+-- A scheduler job moves quizzes from scheduled to published and from
+-- published to closed as the timestamps pass.
+ALTER TABLE quizzes
+    ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'scheduled', 'published', 'closed', 'archived')),
+    ADD COLUMN publish_at TIMESTAMP,
+    ADD COLUMN close_at TIMESTAMP,
+    ADD CONSTRAINT quizzes_window_check
+        CHECK (publish_at IS NULL OR close_at IS NULL OR close_at > publish_at),
+    ADD CONSTRAINT quizzes_scheduled_check
+        CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+UPDATE quizzes SET status = CASE WHEN is_published THEN 'published' ELSE 'draft' END;
+
+-- is_published is kept for the queries and clients that read it, but is now
+-- derived from status.
+ALTER TABLE quizzes DROP COLUMN is_published;
+ALTER TABLE quizzes
+    ADD COLUMN is_published BOOLEAN GENERATED ALWAYS AS (status = 'published') STORED;
+
+CREATE INDEX IF NOT EXISTS idx_quizzes_scheduled
+    ON quizzes (publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_quizzes_closing
+    ON quizzes (close_at) WHERE status = 'published' AND close_at IS NOT NULL;
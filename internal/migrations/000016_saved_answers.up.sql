@@ -0,0 +1,13 @@
+-- =====================
+-- Saved answers
+-- =====================
+-- Answers autosaved while an attempt runs, one per question, kept as the
+-- learner sent them. They are graded into user_answers on submission and
+-- removed then, so unsubmitted work never shows up in results or stats.
+CREATE TABLE IF NOT EXISTS saved_answers (
+    attempt_id UUID NOT NULL REFERENCES quiz_attempts(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL,
+    answer JSONB NOT NULL,
+    saved_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (attempt_id, question_id)
+);
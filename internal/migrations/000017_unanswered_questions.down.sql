@@ -0,0 +1,2 @@
+ALTER TABLE quiz_attempts
+    DROP COLUMN IF EXISTS flagged_question_ids;
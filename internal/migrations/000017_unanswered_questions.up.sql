@@ -0,0 +1,8 @@
+-- =====================
+-- Unanswered questions
+-- =====================
+-- user_answers.option_id has been nullable since 000011, so a skipped
+-- question needs nothing there: it simply has no rows. What is new is the
+-- list of questions the learner marked for review, kept with the attempt.
+ALTER TABLE quiz_attempts
+    ADD COLUMN flagged_question_ids UUID[] NOT NULL DEFAULT '{}';
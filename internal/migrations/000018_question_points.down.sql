@@ -0,0 +1,10 @@
+ALTER TABLE quiz_attempts
+    DROP COLUMN IF EXISTS passed,
+    DROP COLUMN IF EXISTS max_points;
+
+ALTER TABLE quizzes
+    DROP COLUMN IF EXISTS pass_mark;
+
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS negative_points,
+    DROP COLUMN IF EXISTS points;
@@ -0,0 +1,22 @@
+-- =====================
+-- Question points
+-- =====================
+-- Each question is worth points (1 by default) and may cost negative_points
+-- when answered wrong; skipped questions never cost anything. A quiz may set
+-- a pass mark as a percentage of its maximum points.
+ALTER TABLE questions
+    ADD COLUMN points NUMERIC(6,2) NOT NULL DEFAULT 1 CHECK (points > 0),
+    ADD COLUMN negative_points NUMERIC(6,2) NOT NULL DEFAULT 0 CHECK (negative_points >= 0);
+
+ALTER TABLE quizzes
+    ADD COLUMN pass_mark NUMERIC(5,2) CHECK (pass_mark BETWEEN 0 AND 100);
+
+-- score now holds the raw points earned, which negative marking can take
+-- below zero; percentage stays between 0 and 100. passed is NULL when the
+-- quiz had no pass mark.
+ALTER TABLE quiz_attempts
+    ADD COLUMN max_points NUMERIC(8,2) NOT NULL DEFAULT 0,
+    ADD COLUMN passed BOOLEAN;
+
+-- Every question used to be worth one point.
+UPDATE quiz_attempts SET max_points = total_questions;
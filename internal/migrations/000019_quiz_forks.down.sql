@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_quizzes_forked_from;
+
+ALTER TABLE quizzes
+    DROP COLUMN IF EXISTS forked_from_user_id,
+    DROP COLUMN IF EXISTS forked_from_quiz_id,
+    DROP COLUMN IF EXISTS license;
@@ -0,0 +1,15 @@
+-- =====================
+-- Quiz forks
+-- =====================
+-- license decides whether others may fork a quiz: all_rights_reserved keeps
+-- it to its editors, cc_by allows forks that credit the author and cc0
+-- allows any fork. Forks keep a link to the quiz and author they came from,
+-- cleared if either is deleted.
+ALTER TABLE quizzes
+    ADD COLUMN license VARCHAR(20) NOT NULL DEFAULT 'all_rights_reserved'
+        CHECK (license IN ('all_rights_reserved', 'cc_by', 'cc0')),
+    ADD COLUMN forked_from_quiz_id UUID REFERENCES quizzes(id) ON DELETE SET NULL,
+    ADD COLUMN forked_from_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_quizzes_forked_from ON quizzes(forked_from_quiz_id)
+    WHERE forked_from_quiz_id IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_quiz_id_order_index_key;
+ALTER TABLE questions
+    ADD CONSTRAINT questions_quiz_id_order_index_key UNIQUE (quiz_id, order_index);
@@ -0,0 +1,9 @@
+-- =====================
+-- Question order
+-- =====================
+-- A deferrable unique constraint is checked once the statement is done
+-- rather than row by row, so one UPDATE can swap or shift order indexes.
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_quiz_id_order_index_key;
+ALTER TABLE questions
+    ADD CONSTRAINT questions_quiz_id_order_index_key UNIQUE (quiz_id, order_index)
+        DEFERRABLE INITIALLY IMMEDIATE;
@@ -0,0 +1,2 @@
+ALTER TABLE quiz_attempts
+    DROP COLUMN IF EXISTS pass_mark;
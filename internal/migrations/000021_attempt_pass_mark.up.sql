@@ -0,0 +1,15 @@
+-- =====================
+-- Attempt pass mark
+-- =====================
+-- An attempt keeps the pass mark it was graded against, so its result still
+-- explains passed after the quiz's pass mark changes. NULL when the quiz had
+-- no pass mark.
+ALTER TABLE quiz_attempts
+    ADD COLUMN pass_mark NUMERIC(5,2);
+
+-- The mark earlier attempts were graded against was not kept; the quiz's
+-- current one is the best guess.
+UPDATE quiz_attempts a
+SET pass_mark = q.pass_mark
+FROM quizzes q
+WHERE a.quiz_id = q.id AND a.passed IS NOT NULL;
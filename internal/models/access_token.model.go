@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Scopes a personal access token can be granted. Session (cookie) logins are
+// not scoped.
+const (
+	ScopeProfileRead      = "profile:read"
+	ScopeProfileWrite     = "profile:write"
+	ScopeCommunitiesRead  = "communities:read"
+	ScopeCommunitiesWrite = "communities:write"
+	ScopeQuizzesRead      = "quizzes:read"
+	ScopeQuizzesWrite     = "quizzes:write"
+	ScopeCommentsWrite    = "comments:write"
+)
+
+var AccessTokenScopes = []string{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeCommunitiesRead,
+	ScopeCommunitiesWrite,
+	ScopeQuizzesRead,
+	ScopeQuizzesWrite,
+	ScopeCommentsWrite,
+}
+
+// personal_access_tokens (
+//     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+//     name VARCHAR(100) NOT NULL,
+//     token_prefix VARCHAR(16) NOT NULL,
+//     token_hash TEXT UNIQUE NOT NULL,
+//     scopes TEXT[] NOT NULL DEFAULT '{}',
+//     expires_at TIMESTAMP,
+//     last_used_at TIMESTAMP,
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+//     UNIQUE (user_id, name)
+// );
+
+type PersonalAccessToken struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	TokenHash   string     `json:"-"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const ProviderGoogle = "google"
+
+// user_identities (
+//     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+//     provider VARCHAR(32) NOT NULL,
+//     provider_subject VARCHAR(255) NOT NULL,
+//     email VARCHAR(255),
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+//     UNIQUE (provider, provider_subject),
+//     UNIQUE (user_id, provider)
+// );
+
+type UserIdentity struct {
+	ID              string    `json:"id"`
+	UserID          string    `json:"user_id"`
+	Provider        string    `json:"provider"`
+	ProviderSubject string    `json:"-"`
+	Email           string    `json:"email"`
+	CreatedAt       time.Time `json:"created_at"`
+}
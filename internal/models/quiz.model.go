@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // quizzes (
 //     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -10,26 +13,77 @@ import "time"
 //     description TEXT,
 //     duration_minutes INTEGER,
 //     likes_count INTEGER DEFAULT 0,
-//     is_published BOOLEAN DEFAULT false,
+//     status VARCHAR(10) NOT NULL DEFAULT 'draft', -- draft | scheduled | published | closed | archived
+//     publish_at TIMESTAMP, -- when a scheduled quiz goes live
+//     close_at TIMESTAMP, -- when a published quiz stops taking attempts
+//     is_published BOOLEAN GENERATED ALWAYS AS (status = 'published') STORED,
+//     current_version INTEGER NOT NULL DEFAULT 0,
+//     shuffle_questions BOOLEAN NOT NULL DEFAULT false,
+//     shuffle_options BOOLEAN NOT NULL DEFAULT false,
+//     max_attempts INTEGER, -- NULL means unlimited
+//     cooldown_minutes INTEGER NOT NULL DEFAULT 0,
+//     scoring_rule VARCHAR(10) NOT NULL DEFAULT 'first', -- first | best | latest | average
+//     pass_mark NUMERIC(5,2), -- percentage needed to pass; NULL means no pass mark
+//     license VARCHAR(20) NOT NULL DEFAULT 'all_rights_reserved', -- all_rights_reserved | cc_by | cc0
+//     forked_from_quiz_id UUID REFERENCES quizzes(id) ON DELETE SET NULL,
+//     forked_from_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
 //     created_at TIMESTAMP DEFAULT NOW(),
 //     updated_at TIMESTAMP DEFAULT NOW()
 // )
 
 type Quiz struct {
-	ID              string    `json:"id"`
-	CommunityID     string    `json:"community_id"`
-	CreatorID       string    `json:"creator_id"`
-	Title           string    `json:"title"`
-	Description     string    `json:"description"`
-	DurationMinutes int       `json:"duration_minutes"`
-	LikesCount      int       `json:"likes_count"`
-	IsPublished     bool      `json:"is_published"`
-	CreatedAt       time.Time `json:"created_at"`
-	AverageScore    float64   `json:"average_score"`
-	StudentsCount   int       `json:"students_count"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID               string     `json:"id"`
+	CommunityID      string     `json:"community_id"`
+	CreatorID        string     `json:"creator_id"`
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	DurationMinutes  int        `json:"duration_minutes"`
+	LikesCount       int        `json:"likes_count"`
+	IsPublished      bool       `json:"is_published"`
+	Status           string     `json:"status"`
+	PublishAt        *time.Time `json:"publish_at"`
+	CloseAt          *time.Time `json:"close_at"`
+	ShuffleQuestions bool       `json:"shuffle_questions"`
+	ShuffleOptions   bool       `json:"shuffle_options"`
+	MaxAttempts      *int       `json:"max_attempts"`
+	CooldownMinutes  int        `json:"cooldown_minutes"`
+	ScoringRule      string     `json:"scoring_rule"`
+	PassMark         *float64   `json:"pass_mark"`
+	License          string     `json:"license"`
+	ForkedFromQuiz   *string    `json:"forked_from_quiz_id"`
+	ForkedFromUser   *string    `json:"forked_from_user_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	AverageScore     float64    `json:"average_score"`
+	StudentsCount    int        `json:"students_count"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
+// Quiz lifecycle states. Only published quizzes inside their window take
+// attempts; drafts and scheduled quizzes are visible to their editors only.
+const (
+	QuizDraft     = "draft"
+	QuizScheduled = "scheduled"
+	QuizPublished = "published"
+	QuizClosed    = "closed"
+	QuizArchived  = "archived"
+)
+
+// Licenses decide who may fork a quiz. Its editors always may.
+const (
+	LicenseAllRightsReserved = "all_rights_reserved"
+	LicenseCCBY              = "cc_by"
+	LicenseCC0               = "cc0"
+)
+
+// Scoring rules decide which finished attempts make up a learner's official
+// result on a quiz. See the official_attempts view.
+const (
+	ScoringRuleFirst   = "first"
+	ScoringRuleBest    = "best"
+	ScoringRuleLatest  = "latest"
+	ScoringRuleAverage = "average"
+)
+
 //questions
 //     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 //     quiz_id UUID REFERENCES quizzes(id) ON DELETE CASCADE,
@@ -37,69 +91,179 @@ type Quiz struct {
 //     explanation TEXT,
 //     correct_answer TEXT NOT NULL,
 //     order_index INT,
+//     question_type VARCHAR(20) NOT NULL DEFAULT 'single_choice',
+//     scoring_strategy VARCHAR(20) NOT NULL DEFAULT 'all_or_nothing',
+//     settings JSONB NOT NULL DEFAULT '{}',
+//     tag VARCHAR(50) NOT NULL DEFAULT '',
+//     difficulty VARCHAR(10) NOT NULL DEFAULT '', -- '' | easy | medium | hard
+//     points NUMERIC(6,2) NOT NULL DEFAULT 1,
+//     negative_points NUMERIC(6,2) NOT NULL DEFAULT 0, -- taken off for a wrong answer
 //     created_at TIMESTAMP DEFAULT NOW(),
-//     updated_at TIMESTAMP DEFAULT NOW()
+//     updated_at TIMESTAMP DEFAULT NOW(),
+//     UNIQUE (quiz_id, order_index) DEFERRABLE INITIALLY IMMEDIATE
 //
 
 type Question struct {
-	ID            string    `json:"id"`
-	QuizID        string    `json:"quiz_id"`
-	QuestionText  string    `json:"question_text"`
-	Explanation   string    `json:"explanation"`
-	CorrectAnswer string    `json:"correct_answer"`
-	OrderIndex    int       `json:"order_index"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID              string           `json:"id"`
+	QuizID          string           `json:"quiz_id"`
+	QuestionText    string           `json:"question_text"`
+	Explanation     string           `json:"explanation"`
+	CorrectAnswer   string           `json:"correct_answer"`
+	OrderIndex      int              `json:"order_index"`
+	QuestionType    string           `json:"question_type"`
+	ScoringStrategy string           `json:"scoring_strategy"`
+	Settings        QuestionSettings `json:"settings"`
+	Tag             string           `json:"tag"`
+	Difficulty      string           `json:"difficulty"`
+	Points          float64          `json:"points"`
+	NegativePoints  float64          `json:"negative_points"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
 }
 
-//options
-//   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-//   question_id UUID REFERENCES questions(id) ON DELETE CASCADE,
-//   text TEXT NOT NULL,
-//   is_correct BOOLEAN DEFAULT FALSE
+// Question types. Choice, ordering and matching questions are built from
+// options; the others are graded against CorrectAnswer and Settings.
+const (
+	QuestionSingleChoice   = "single_choice"
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionTrueFalse      = "true_false"
+	QuestionShortAnswer    = "short_answer"
+	QuestionNumeric        = "numeric"
+	QuestionOrdering       = "ordering"
+	QuestionMatching       = "matching"
+)
+
+// QuestionSettings carries the grading rules that only some question types
+// use. CorrectAnswer stays the canonical answer: "true"/"false" for
+// true_false, the expected text for short_answer and the number for numeric.
+type QuestionSettings struct {
+	// short_answer: other answers that also count as correct, and how many
+	// typos (edits) are forgiven. Comparison ignores case and spacing.
+	AcceptedAnswers []string `json:"accepted_answers,omitempty"`
+	MaxEditDistance int      `json:"max_edit_distance,omitempty"`
+
+	// numeric: allowed absolute difference, the unit CorrectAnswer is in, and
+	// other accepted units with the factor converting them to Unit.
+	Tolerance   float64            `json:"tolerance,omitempty"`
+	Unit        string             `json:"unit,omitempty"`
+	UnitFactors map[string]float64 `json:"unit_factors,omitempty"`
+}
+
+// Question difficulties. Questions may also be left unrated ("").
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// Scoring strategies decide how much of a point a multiple-choice, ordering
+// or matching answer earns when only part of it is right.
+const (
+	ScoringAllOrNothing    = "all_or_nothing"
+	ScoringProportional    = "proportional"
+	ScoringRightMinusWrong = "right_minus_wrong"
+)
+
+// options
 //
+//	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//	question_id UUID REFERENCES questions(id) ON DELETE CASCADE,
+//	text TEXT NOT NULL,
+//	is_correct BOOLEAN DEFAULT FALSE
+//	position INTEGER NOT NULL DEFAULT 0 -- display order; the correct place for ordering questions
+//	match_text TEXT -- right-hand side of a matching pair
 type Option struct {
-	ID         string `json:"id"`
-	QuestionID string `json:"question_id"`
-	Text       string `json:"text"`
-	IsCorrect  bool   `json:"is_correct"`
+	ID         string  `json:"id"`
+	QuestionID string  `json:"question_id"`
+	Text       string  `json:"text"`
+	IsCorrect  bool    `json:"is_correct"`
+	Position   int     `json:"position"`
+	MatchText  *string `json:"match_text"`
 }
 
-//quiz_attempts (
-//     id
-//     quiz_id
-//     user_id
-//     score
-//     total_questions
-//     percentage
-//     time_taken_seconds
-//     completed_at
+// quiz_attempts (
+//
+//	id
+//	quiz_id
+//	user_id
+//	score NUMERIC(8,2) -- raw points earned, negative marks included
+//	max_points NUMERIC(8,2) NOT NULL DEFAULT 0
+//	passed BOOLEAN -- NULL when the quiz has no pass mark
+//	pass_mark NUMERIC(5,2) -- the quiz's pass mark when graded; NULL without one
+//	total_questions
+//	percentage -- share of max_points earned, between 0 and 100
+//	time_taken_seconds INTEGER NOT NULL DEFAULT 0
+//	attempt_number
+//	status VARCHAR(20) NOT NULL -- in_progress | completed | expired
+//	started_at TIMESTAMP NOT NULL
+//	deadline_at TIMESTAMP -- NULL when the quiz has no time limit
+//	completed_at TIMESTAMP -- NULL while in progress
+//	quiz_version_id UUID REFERENCES quiz_versions(id) ON DELETE SET NULL
+//	shuffle_seed BIGINT -- NULL for attempts that predate shuffling
+//	question_ids UUID[] -- questions drawn for the attempt; NULL means all
+//	flagged_question_ids UUID[] NOT NULL DEFAULT '{}' -- marked for review by the learner
+//
 // );
 type QuizAttempts struct {
-	ID               string    `json:"id"`
-	QuizID           string    `json:"quiz_id"`
-	UserID           string    `json:"user_id"`
-	Score            int       `json:"score"`
-	AttemptCount     int       `json:"attempt_count"`
-	TotalQuestions   int       `json:"total_questions"`
-	Percentage       float64   `json:"percentage"`
-	TimeTakenMinutes int       `json:"time_taken_minutes"`
-	CompletedAt      time.Time `json:"completed_at"`
+	ID               string     `json:"id"`
+	QuizID           string     `json:"quiz_id"`
+	UserID           string     `json:"user_id"`
+	Score            float64    `json:"score"`
+	MaxPoints        float64    `json:"max_points"`
+	Passed           *bool      `json:"passed"`
+	PassMark         *float64   `json:"pass_mark"`
+	AttemptCount     int        `json:"attempt_count"`
+	TotalQuestions   int        `json:"total_questions"`
+	Percentage       float64    `json:"percentage"`
+	TimeTakenSeconds int        `json:"time_taken_seconds"`
+	Status           string     `json:"status"`
+	StartedAt        time.Time  `json:"started_at"`
+	DeadlineAt       *time.Time `json:"deadline_at"`
+	CompletedAt      *time.Time `json:"completed_at"`
+	QuizVersionID    *string    `json:"quiz_version_id"`
+	ShuffleSeed      *int64     `json:"shuffle_seed"`
+	QuestionIDs      []string   `json:"question_ids"`
+	FlaggedIDs       []string   `json:"flagged_question_ids"`
 }
 
+const (
+	AttemptInProgress = "in_progress"
+	AttemptCompleted  = "completed"
+	AttemptExpired    = "expired"
+)
+
 // user_answers (
 //     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 //     user_id UUID REFERENCES users(id) ON DELETE CASCADE,
 //     question_id UUID REFERENCES questions(id) ON DELETE CASCADE,
-//     option_id UUID REFERENCES options(id) ON DELETE CASCADE,
+//     option_id UUID,
+//     text_answer TEXT, -- typed answers, or the match chosen for option_id
+//     position INTEGER, -- place given to option_id in an ordering answer
 //     created_at TIMESTAMP DEFAULT NOW(),
-//     UNIQUE (user_id, question_id)
+//     UNIQUE (attempt_id, question_id, option_id)
 // );
 
 type UserAnwer struct {
 	ID         string    `json:"id"`
 	AttemptID  string    `json:"attempt_id"`
 	QuestionID string    `json:"question_id"`
-	OptionID   string    `json:"option_id"`
+	OptionID   *string   `json:"option_id"`
+	TextAnswer *string   `json:"text_answer"`
+	Position   *int      `json:"position"`
 	CreatedAt  time.Time `json:"created_at"`
 }
+
+// saved_answers (
+//     attempt_id UUID REFERENCES quiz_attempts(id) ON DELETE CASCADE,
+//     question_id UUID,
+//     answer JSONB NOT NULL, -- the answer as sent, graded on submission
+//     saved_at TIMESTAMP NOT NULL DEFAULT NOW(),
+//     PRIMARY KEY (attempt_id, question_id)
+// );
+
+type SavedAnswer struct {
+	AttemptID  string          `json:"attempt_id"`
+	QuestionID string          `json:"question_id"`
+	Answer     json.RawMessage `json:"answer"`
+	SavedAt    time.Time       `json:"saved_at"`
+}
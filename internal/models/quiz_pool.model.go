@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// quiz_pools (
+//     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//     quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+//     position INTEGER NOT NULL,
+//     tag VARCHAR(50) NOT NULL DEFAULT '',
+//     difficulty VARCHAR(10) NOT NULL DEFAULT '',
+//     draw_count INTEGER NOT NULL CHECK (draw_count > 0),
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+//     UNIQUE (quiz_id, position)
+// );
+
+// QuizPool draws DrawCount random questions from those of the quiz matching
+// Tag and Difficulty; an empty filter matches any question. Each attempt of
+// a quiz with pools gets the questions drawn by all of them.
+type QuizPool struct {
+	ID         string    `json:"id"`
+	QuizID     string    `json:"quiz_id"`
+	Position   int       `json:"position"`
+	Tag        string    `json:"tag"`
+	Difficulty string    `json:"difficulty"`
+	DrawCount  int       `json:"draw_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// quiz_versions (
+//     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//     quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+//     version INTEGER NOT NULL,
+//     snapshot JSONB NOT NULL,
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+//     UNIQUE (quiz_id, version)
+// );
+
+// QuizVersion is an immutable copy of a quiz's content. A new one is written
+// whenever the quiz, its questions or its options change, and every attempt
+// pins the version it was taken against.
+type QuizVersion struct {
+	ID        string       `json:"id"`
+	QuizID    string       `json:"quiz_id"`
+	Version   int          `json:"version"`
+	Snapshot  QuizSnapshot `json:"snapshot"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type QuizSnapshot struct {
+	Title            string             `json:"title"`
+	Description      string             `json:"description"`
+	DurationMinutes  int                `json:"duration_minutes"`
+	ShuffleQuestions bool               `json:"shuffle_questions"`
+	ShuffleOptions   bool               `json:"shuffle_options"`
+	Pools            []PoolSnapshot     `json:"pools"`
+	Questions        []QuestionSnapshot `json:"questions"`
+}
+
+type QuestionSnapshot struct {
+	ID              string           `json:"id"`
+	QuestionText    string           `json:"question_text"`
+	Explanation     string           `json:"explanation"`
+	CorrectAnswer   string           `json:"correct_answer"`
+	OrderIndex      int              `json:"order_index"`
+	QuestionType    string           `json:"question_type"`
+	ScoringStrategy string           `json:"scoring_strategy"`
+	Settings        QuestionSettings `json:"settings"`
+	Tag             string           `json:"tag"`
+	Difficulty      string           `json:"difficulty"`
+	Points          float64          `json:"points"` // 0 in versions saved before points existed
+	NegativePoints  float64          `json:"negative_points"`
+	Options         []OptionSnapshot `json:"options"`
+}
+
+type PoolSnapshot struct {
+	Tag        string `json:"tag"`
+	Difficulty string `json:"difficulty"`
+	DrawCount  int    `json:"draw_count"`
+}
+
+type OptionSnapshot struct {
+	ID        string  `json:"id"`
+	Text      string  `json:"text"`
+	IsCorrect bool    `json:"is_correct"`
+	Position  int     `json:"position"`
+	MatchText *string `json:"match_text"`
+}
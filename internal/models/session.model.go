@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// sessions (
+//     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+//     refresh_token_hash TEXT UNIQUE NOT NULL,
+//     user_agent TEXT,
+//     ip_address VARCHAR(64),
+//     expires_at TIMESTAMP NOT NULL,
+//     revoked_at TIMESTAMP,
+//     last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW()
+// );
+
+type Session struct {
+	ID               string     `json:"id"`
+	UserID           string     `json:"user_id"`
+	RefreshTokenHash string     `json:"-"`
+	UserAgent        string     `json:"user_agent"`
+	IPAddress        string     `json:"ip_address"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
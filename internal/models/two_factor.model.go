@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// user_totp (
+//     user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+//     secret_encrypted TEXT NOT NULL,
+//     confirmed_at TIMESTAMP,
+//     last_used_step BIGINT NOT NULL DEFAULT 0,
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW()
+// );
+
+// UserTOTP is a user's authenticator enrollment. Two-factor login is only
+// enforced once ConfirmedAt is set.
+type UserTOTP struct {
+	UserID          string     `json:"user_id"`
+	SecretEncrypted string     `json:"-"`
+	ConfirmedAt     *time.Time `json:"confirmed_at"`
+	LastUsedStep    int64      `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// user_recovery_codes (
+//     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+//     code_hash TEXT NOT NULL,
+//     used_at TIMESTAMP,
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+//     UNIQUE (user_id, code_hash)
+// );
@@ -6,13 +6,16 @@ import (
 )
 
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	Username     string    `json:"username"`
-	Avatar       *string   `json:"avatar"`
-	Banner       *string   `json:"banner"`
-	PasswordHash sql.NullString    `json:"-"`
-	GoogleID     string    `json:"google_id"`
-	CreatedAt    time.Time `json:"created_at"`
-	Updated_at   time.Time `json:"updated_at"`
+	ID              string         `json:"id"`
+	Email           string         `json:"email"`
+	Username        string         `json:"username"`
+	Avatar          *string        `json:"avatar"`
+	Banner          *string        `json:"banner"`
+	PasswordHash    sql.NullString `json:"-"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at"`
+	// DeletionScheduledAt is set while the account is in its deletion grace
+	// period; the row is purged once it passes.
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	Updated_at          time.Time  `json:"updated_at"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// user_tokens (
+//     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+//     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+//     purpose VARCHAR(32) NOT NULL,
+//     token_hash TEXT UNIQUE NOT NULL,
+//     expires_at TIMESTAMP NOT NULL,
+//     used_at TIMESTAMP,
+//     created_at TIMESTAMP NOT NULL DEFAULT NOW()
+// );
+
+type UserToken struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Purpose   string     `json:"purpose"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Rule allows Limit hits per Window. A zero Limit disables the rule.
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Allow records a hit for key and reports whether it is within the rule.
+// When it is not, retryAfter is the time left until the window resets.
+func (l *Limiter) Allow(ctx context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	if rule.Limit <= 0 {
+		return true, 0, nil
+	}
+	count, resetAt, err := l.store.Incr(ctx, key, rule.Window)
+	if err != nil {
+		return false, 0, err
+	}
+	if count > rule.Limit {
+		return false, time.Until(resetAt), nil
+	}
+	return true, 0, nil
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Lockout blocks a key after repeated failures. Once Threshold failures have
+// been seen within FailureWindow, every further failure locks the key for
+// twice as long as the previous one, starting at BaseDelay and capped at
+// MaxDelay. A success clears the history.
+type Lockout struct {
+	store         Store
+	Threshold     int
+	FailureWindow time.Duration
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+}
+
+func NewLockout(store Store, threshold int, failureWindow, baseDelay, maxDelay time.Duration) *Lockout {
+	return &Lockout{
+		store:         store,
+		Threshold:     threshold,
+		FailureWindow: failureWindow,
+		BaseDelay:     baseDelay,
+		MaxDelay:      maxDelay,
+	}
+}
+
+// Check returns how long key is still locked, or zero if it is not.
+func (l *Lockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	locked, until, err := l.store.Peek(ctx, lockKey(key))
+	if err != nil || locked == 0 {
+		return 0, err
+	}
+	return time.Until(until), nil
+}
+
+// Fail records a failure and returns the lock it triggered, if any.
+func (l *Lockout) Fail(ctx context.Context, key string) (time.Duration, error) {
+	if l.Threshold <= 0 {
+		return 0, nil
+	}
+	failures, _, err := l.store.Incr(ctx, failKey(key), l.FailureWindow)
+	if err != nil || failures < l.Threshold {
+		return 0, err
+	}
+
+	delay := l.BaseDelay
+	for i := l.Threshold; i < failures && delay < l.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > l.MaxDelay {
+		delay = l.MaxDelay
+	}
+
+	if err := l.store.Reset(ctx, lockKey(key)); err != nil {
+		return 0, err
+	}
+	if _, _, err := l.store.Incr(ctx, lockKey(key), delay); err != nil {
+		return 0, err
+	}
+	return delay, nil
+}
+
+func (l *Lockout) Success(ctx context.Context, key string) error {
+	if err := l.store.Reset(ctx, failKey(key)); err != nil {
+		return err
+	}
+	return l.store.Reset(ctx, lockKey(key))
+}
+
+func failKey(key string) string { return "lockout:fail:" + key }
+func lockKey(key string) string { return "lockout:lock:" + key }
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps fixed-window hit counters. MemoryStore is enough for a single
+// instance; running several instances needs a shared implementation (for
+// example Redis INCR + PEXPIRE) so they see the same counts.
+type Store interface {
+	// Incr records a hit for key and returns the hits in the current window
+	// and when that window ends. A new window of the given length starts
+	// when the key has none or its window has ended.
+	Incr(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	// Peek returns the current count without recording a hit. A missing or
+	// ended window reports zero.
+	Peek(ctx context.Context, key string) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type memoryEntry struct {
+	count   int
+	resetAt time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+// Expired entries are dropped at most this often, during an Incr.
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Incr(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, e := range s.entries {
+			if !now.Before(e.resetAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		e = &memoryEntry{resetAt: now.Add(window)}
+		s.entries[key] = e
+	}
+	e.count++
+	return e.count, e.resetAt, nil
+}
+
+func (s *MemoryStore) Peek(ctx context.Context, key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !time.Now().Before(e.resetAt) {
+		return 0, time.Time{}, nil
+	}
+	return e.count, e.resetAt, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
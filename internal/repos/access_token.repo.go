@@ -0,0 +1,130 @@
+package repos
+
+import (
+	"context"
+	"ecoquiz/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AccessTokenRepo interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error)
+	FindByUserID(ctx context.Context, userID string) ([]models.PersonalAccessToken, error)
+	Delete(ctx context.Context, userID, tokenID string) error
+	DeleteAllForUser(ctx context.Context, userID string) error
+	Touch(ctx context.Context, tokenID string) error
+}
+
+type accessTokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAccessTokenRepo(db *pgxpool.Pool) AccessTokenRepo {
+	return &accessTokenRepo{db: db}
+}
+
+func (r *accessTokenRepo) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	query := `
+		INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query,
+		token.UserID,
+		token.Name,
+		token.TokenPrefix,
+		token.TokenHash,
+		token.Scopes,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *accessTokenRepo) FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	query := `
+		SELECT id, user_id, name, token_prefix, token_hash, scopes, expires_at, last_used_at, created_at
+		FROM personal_access_tokens
+		WHERE token_hash = $1
+	`
+	var t models.PersonalAccessToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.TokenPrefix,
+		&t.TokenHash,
+		&t.Scopes,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *accessTokenRepo) FindByUserID(ctx context.Context, userID string) ([]models.PersonalAccessToken, error) {
+	query := `
+		SELECT id, user_id, name, token_prefix, token_hash, scopes, expires_at, last_used_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]models.PersonalAccessToken, 0)
+	for rows.Next() {
+		var t models.PersonalAccessToken
+		if err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.Name,
+			&t.TokenPrefix,
+			&t.TokenHash,
+			&t.Scopes,
+			&t.ExpiresAt,
+			&t.LastUsedAt,
+			&t.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *accessTokenRepo) Delete(ctx context.Context, userID, tokenID string) error {
+	query := `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`
+	cmdTag, err := r.db.Exec(ctx, query, tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *accessTokenRepo) DeleteAllForUser(ctx context.Context, userID string) error {
+	query := `DELETE FROM personal_access_tokens WHERE user_id = $1`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+// Touch records a use of the token. Writes are coalesced to one per minute so
+// a busy script does not turn every request into an UPDATE.
+func (r *accessTokenRepo) Touch(ctx context.Context, tokenID string) error {
+	query := `
+		UPDATE personal_access_tokens
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	_, err := r.db.Exec(ctx, query, tokenID)
+	return err
+}
@@ -353,7 +353,7 @@ func (r *communityRepo) CountMembers(ctx context.Context, commID string) (int, e
 	return count, err
 }
 func (r *communityRepo) CountQuizzes(ctx context.Context, commID string) (int, error) {
-	query := `SELECT COUNT(*) FROM quizzes WHERE community_id = $1 AND is_published = true`
+	query := `SELECT COUNT(*) FROM quizzes WHERE community_id = $1 AND status IN ('published', 'closed')`
 	var count int
 	err := r.db.QueryRow(ctx, query, commID).Scan(&count)
 	return count, err
@@ -369,7 +369,7 @@ func (r *communityRepo) FindUserCommunitiesWithDetails(ctx context.Context, user
 			cm.joined_at,
 			cm.role,
 			(SELECT COUNT(*) FROM community_members WHERE community_id = c.id) as member_count,
-			(SELECT COUNT(*) FROM quizzes WHERE community_id = c.id AND is_published = true) as quiz_count,
+			(SELECT COUNT(*) FROM quizzes WHERE community_id = c.id AND status IN ('published', 'closed')) as quiz_count,
 			CASE WHEN c.creator_id = $1 THEN 'CREATOR' ELSE 'MEMBER' END as member_role
 		FROM communities c
 		JOIN community_members cm ON cm.community_id = c.id
@@ -0,0 +1,108 @@
+package repos
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// exportSections maps each part of a personal data export to the query that
+// builds it. Rows are serialised by Postgres so new columns are exported
+// without code changes; secrets (password, token and TOTP hashes) are never
+// selected.
+var exportSections = []struct {
+	name  string
+	query string
+}{
+	{"profile", `
+		SELECT to_json(u) FROM (
+			SELECT id, email, username, avatar, banner, email_verified_at,
+			       deletion_scheduled_at, created_at, updated_at
+			FROM users WHERE id = $1
+		) u`},
+	{"identities", `
+		SELECT COALESCE(json_agg(i ORDER BY i.created_at), '[]') FROM (
+			SELECT provider, email, created_at FROM user_identities WHERE user_id = $1
+		) i`},
+	{"access_tokens", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT name, token_prefix, scopes, expires_at, last_used_at, created_at
+			FROM personal_access_tokens WHERE user_id = $1
+		) t`},
+	{"sessions", `
+		SELECT COALESCE(json_agg(s ORDER BY s.created_at), '[]') FROM (
+			SELECT user_agent, ip_address, expires_at, revoked_at, last_used_at, created_at
+			FROM sessions WHERE user_id = $1
+		) s`},
+	{"communities", `
+		SELECT COALESCE(json_agg(c ORDER BY c.joined_at), '[]') FROM (
+			SELECT cm.role, cm.joined_at, co.*
+			FROM community_members cm
+			JOIN communities co ON co.id = cm.community_id
+			WHERE cm.user_id = $1
+		) c`},
+	{"quizzes", `
+		SELECT COALESCE(json_agg(q ORDER BY q.created_at), '[]') FROM (
+			SELECT qz.*, (
+				SELECT COALESCE(json_agg(qs ORDER BY qs.order_index), '[]') FROM (
+					SELECT qu.*, (
+						SELECT COALESCE(json_agg(o), '[]') FROM options o WHERE o.question_id = qu.id
+					) AS options
+					FROM questions qu WHERE qu.quiz_id = qz.id
+				) qs
+			) AS questions
+			FROM quizzes qz WHERE qz.creator_id = $1
+		) q`},
+	{"attempts", `
+		SELECT COALESCE(json_agg(a ORDER BY a.quiz_id, a.attempt_number), '[]') FROM (
+			SELECT * FROM quiz_attempts WHERE user_id = $1
+		) a`},
+	{"answers", `
+		SELECT COALESCE(json_agg(ua ORDER BY ua.attempt_id, ua.created_at), '[]') FROM (
+			SELECT ua.* FROM user_answers ua
+			JOIN quiz_attempts qa ON qa.id = ua.attempt_id
+			WHERE qa.user_id = $1
+		) ua`},
+	{"comments", `
+		SELECT COALESCE(json_agg(c ORDER BY c.created_at), '[]') FROM (
+			SELECT * FROM question_comments WHERE user_id = $1
+		) c`},
+	{"likes", `
+		SELECT COALESCE(json_agg(l ORDER BY l.created_at), '[]') FROM (
+			SELECT quiz_id, created_at FROM quiz_likes WHERE user_id = $1
+		) l`},
+}
+
+type ExportRepo interface {
+	// ExportUser returns every export section keyed by name. All queries run
+	// in one read-only transaction so the sections are consistent.
+	ExportUser(ctx context.Context, userID string) (map[string]json.RawMessage, error)
+}
+
+type exportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewExportRepo(db *pgxpool.Pool) ExportRepo {
+	return &exportRepo{db: db}
+}
+
+func (r *exportRepo) ExportUser(ctx context.Context, userID string) (map[string]json.RawMessage, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	sections := make(map[string]json.RawMessage, len(exportSections))
+	for _, section := range exportSections {
+		var data []byte
+		if err := tx.QueryRow(ctx, section.query, userID).Scan(&data); err != nil {
+			return nil, err
+		}
+		sections[section.name] = data
+	}
+	return sections, nil
+}
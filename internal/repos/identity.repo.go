@@ -0,0 +1,105 @@
+package repos
+
+import (
+	"context"
+	"ecoquiz/internal/models"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdentityRepo interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	FindByUserID(ctx context.Context, userID string) ([]models.UserIdentity, error)
+	Delete(ctx context.Context, userID, provider string) error
+}
+
+type identityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepo(db *pgxpool.Pool) IdentityRepo {
+	return &identityRepo{db: db}
+}
+
+func (r *identityRepo) Create(ctx context.Context, identity *models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, provider_subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query,
+		identity.UserID,
+		identity.Provider,
+		identity.ProviderSubject,
+		identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+func (r *identityRepo) FindByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, provider_subject, COALESCE(email, ''), created_at
+		FROM user_identities
+		WHERE provider = $1 AND provider_subject = $2
+	`
+	var identity models.UserIdentity
+	err := r.db.QueryRow(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.ProviderSubject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepo) FindByUserID(ctx context.Context, userID string) ([]models.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, provider_subject, COALESCE(email, ''), created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := make([]models.UserIdentity, 0)
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.ProviderSubject,
+			&identity.Email,
+			&identity.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *identityRepo) Delete(ctx context.Context, userID, provider string) error {
+	query := `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`
+	cmdTag, err := r.db.Exec(ctx, query, userID, provider)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errors.New("identity not found")
+	}
+	return nil
+}
@@ -12,9 +12,13 @@ import (
 
 type OptionRepo interface {
 	CreateBatchTx(ctx context.Context, options []models.Option, tx pgx.Tx) error
+	GetByID(ctx context.Context, id string) (*models.Option, error)
 	GetByQuestionID(ctx context.Context, questionID string) ([]models.Option, error)
-	Update(ctx context.Context, options []models.Option) error
+	GetByQuestionIDTx(ctx context.Context, questionID string, tx pgx.Tx) ([]models.Option, error)
+	UpdateTx(ctx context.Context, options []models.Option, tx pgx.Tx) error
+	DeleteTx(ctx context.Context, id string, tx pgx.Tx) error
 	DeleteByQuestionID(ctx context.Context, questionID string) error
+	DeleteByQuestionIDTx(ctx context.Context, questionID string, tx pgx.Tx) error
 }
 
 type optionRepo struct {
@@ -34,9 +38,11 @@ func (r *optionRepo) CreateBatchTx(ctx context.Context, options []models.Option,
 		INSERT INTO options (
 			question_id,
 			text,
-			is_correct
+			is_correct,
+			position,
+			match_text
 		)
-		VALUES ($1, $2, $3)
+		VALUES ($1, $2, $3, $4, $5)
 	`
 
 	batch := &pgx.Batch{}
@@ -46,6 +52,8 @@ func (r *optionRepo) CreateBatchTx(ctx context.Context, options []models.Option,
 			opt.QuestionID,
 			opt.Text,
 			opt.IsCorrect,
+			opt.Position,
+			opt.MatchText,
 		)
 	}
 
@@ -62,15 +70,48 @@ func (r *optionRepo) CreateBatchTx(ctx context.Context, options []models.Option,
 	return nil
 }
 
+func (r *optionRepo) GetByID(ctx context.Context, id string) (*models.Option, error) {
+	query := `
+		SELECT
+			id,
+			question_id,
+			text,
+			is_correct,
+			position,
+			match_text
+		FROM options
+		WHERE id = $1
+	`
+
+	var opt models.Option
+
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&opt.ID,
+		&opt.QuestionID,
+		&opt.Text,
+		&opt.IsCorrect,
+		&opt.Position,
+		&opt.MatchText,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &opt, nil
+}
+
 func (r *optionRepo) GetByQuestionID(ctx context.Context, questionID string) ([]models.Option, error) {
 	query := `
 		SELECT
 			id,
 			question_id,
 			text,
-			is_correct
+			is_correct,
+			position,
+			match_text
 		FROM options
 		WHERE question_id = $1
+		ORDER BY position
 	`
 
 	rows, err := r.db.Query(ctx, query, questionID)
@@ -89,6 +130,55 @@ func (r *optionRepo) GetByQuestionID(ctx context.Context, questionID string) ([]
 			&opt.QuestionID,
 			&opt.Text,
 			&opt.IsCorrect,
+			&opt.Position,
+			&opt.MatchText,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, opt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+func (r *optionRepo) GetByQuestionIDTx(ctx context.Context, questionID string, tx pgx.Tx) ([]models.Option, error) {
+	query := `
+		SELECT
+			id,
+			question_id,
+			text,
+			is_correct,
+			position,
+			match_text
+		FROM options
+		WHERE question_id = $1
+		ORDER BY position
+	`
+
+	rows, err := tx.Query(ctx, query, questionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := make([]models.Option, 0)
+
+	for rows.Next() {
+		var opt models.Option
+
+		err := rows.Scan(
+			&opt.ID,
+			&opt.QuestionID,
+			&opt.Text,
+			&opt.IsCorrect,
+			&opt.Position,
+			&opt.MatchText,
 		)
 		if err != nil {
 			return nil, err
@@ -104,7 +194,7 @@ func (r *optionRepo) GetByQuestionID(ctx context.Context, questionID string) ([]
 	return options, nil
 }
 
-func (r *optionRepo) Update(ctx context.Context, options []models.Option) error {
+func (r *optionRepo) UpdateTx(ctx context.Context, options []models.Option, tx pgx.Tx) error {
 	if len(options) == 0 {
 		return nil
 	}
@@ -113,23 +203,23 @@ func (r *optionRepo) Update(ctx context.Context, options []models.Option) error
 		UPDATE options
 		SET
 			text = $1,
-			is_correct = $2
-		WHERE id = $3
+			is_correct = $2,
+			match_text = $3
+		WHERE id = $4
 	`
 
 	batch := &pgx.Batch{}
 
-	batch.Len()
-
 	for _, opt := range options {
 		batch.Queue(query,
 			opt.Text,
 			opt.IsCorrect,
+			opt.MatchText,
 			opt.ID,
 		)
 	}
 
-	br := r.db.SendBatch(ctx, batch)
+	br := tx.SendBatch(ctx, batch)
 	defer br.Close()
 
 	for range options {
@@ -146,6 +236,28 @@ func (r *optionRepo) Update(ctx context.Context, options []models.Option) error
 	return nil
 }
 
+func (r *optionRepo) DeleteTx(ctx context.Context, id string, tx pgx.Tx) error {
+	query := `DELETE FROM options WHERE id = $1`
+
+	cmdTag, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return errors.New("option not found")
+	}
+
+	return nil
+}
+
+func (r *optionRepo) DeleteByQuestionIDTx(ctx context.Context, questionID string, tx pgx.Tx) error {
+	query := `DELETE FROM options WHERE question_id = $1`
+
+	_, err := tx.Exec(ctx, query, questionID)
+	return err
+}
+
 func (r *optionRepo) DeleteByQuestionID(ctx context.Context, questionID string) error {
 	query := `DELETE FROM options WHERE question_id = $1`
 
@@ -14,9 +14,13 @@ import (
 type QuestionRepo interface {
 	CreateBatchTx(ctx context.Context, questions []models.Question, tx pgx.Tx) error
 	GetByID(ctx context.Context, id string) (*models.Question, error)
-	Update(ctx context.Context, question *models.Question) error
-	Delete(ctx context.Context, id string) error
+	UpdateTx(ctx context.Context, question *models.Question, tx pgx.Tx) error
+	DeleteTx(ctx context.Context, id string, tx pgx.Tx) error
 	FindByQuizID(ctx context.Context, quizID string) ([]*models.Question, error)
+	FindIDsByQuizIDTx(ctx context.Context, quizID string, tx pgx.Tx) ([]string, error)
+	MaxOrderIndexTx(ctx context.Context, quizID string, tx pgx.Tx) (int, error)
+	ReorderTx(ctx context.Context, quizID string, ids []string, tx pgx.Tx) error
+	ReindexTx(ctx context.Context, quizID string, tx pgx.Tx) error
 }
 
 type questionRepo struct {
@@ -38,9 +42,16 @@ func (r *questionRepo) CreateBatchTx(ctx context.Context, questions []models.Que
 			question_text,
 			explanation,
 			correct_answer,
-			order_index
+			order_index,
+			question_type,
+			scoring_strategy,
+			settings,
+			tag,
+			difficulty,
+			points,
+			negative_points
 		)
-		VALUES ($1, $2, $3, $4, $5)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -54,6 +65,13 @@ func (r *questionRepo) CreateBatchTx(ctx context.Context, questions []models.Que
 			q.Explanation,
 			q.CorrectAnswer,
 			q.OrderIndex,
+			q.QuestionType,
+			q.ScoringStrategy,
+			q.Settings,
+			q.Tag,
+			q.Difficulty,
+			q.Points,
+			q.NegativePoints,
 		)
 	}
 
@@ -80,6 +98,13 @@ func (r *questionRepo) GetByID(ctx context.Context, id string) (*models.Question
 			explanation,
 			correct_answer,
 			order_index,
+			question_type,
+			scoring_strategy,
+			settings,
+			tag,
+			difficulty,
+			points,
+			negative_points,
 			created_at,
 			updated_at
 		FROM questions
@@ -95,14 +120,17 @@ func (r *questionRepo) GetByID(ctx context.Context, id string) (*models.Question
 		&question.Explanation,
 		&question.CorrectAnswer,
 		&question.OrderIndex,
+		&question.QuestionType,
+		&question.ScoringStrategy,
+		&question.Settings,
+		&question.Tag,
+		&question.Difficulty,
+		&question.Points,
+		&question.NegativePoints,
 		&question.CreatedAt,
 		&question.UpdatedAt,
 	)
 
-	if err == pgx.ErrNoRows {
-		return nil, errors.New("question not found")
-	}
-
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +138,7 @@ func (r *questionRepo) GetByID(ctx context.Context, id string) (*models.Question
 	return &question, nil
 }
 
-func (r *questionRepo) Update(ctx context.Context, question *models.Question) error {
+func (r *questionRepo) UpdateTx(ctx context.Context, question *models.Question, tx pgx.Tx) error {
 	query := `
 		UPDATE questions
 		SET
@@ -118,15 +146,29 @@ func (r *questionRepo) Update(ctx context.Context, question *models.Question) er
 			explanation = $2,
 			correct_answer = $3,
 			order_index = $4,
-			updated_at = $5
-		WHERE id = $6
+			question_type = $5,
+			scoring_strategy = $6,
+			settings = $7,
+			tag = $8,
+			difficulty = $9,
+			points = $10,
+			negative_points = $11,
+			updated_at = $12
+		WHERE id = $13
 	`
 
-	cmdTag, err := r.db.Exec(ctx, query,
+	cmdTag, err := tx.Exec(ctx, query,
 		question.QuestionText,
 		question.Explanation,
 		question.CorrectAnswer,
 		question.OrderIndex,
+		question.QuestionType,
+		question.ScoringStrategy,
+		question.Settings,
+		question.Tag,
+		question.Difficulty,
+		question.Points,
+		question.NegativePoints,
 		time.Now(),
 		question.ID,
 	)
@@ -142,10 +184,10 @@ func (r *questionRepo) Update(ctx context.Context, question *models.Question) er
 	return nil
 }
 
-func (r *questionRepo) Delete(ctx context.Context, id string) error {
+func (r *questionRepo) DeleteTx(ctx context.Context, id string, tx pgx.Tx) error {
 	query := `DELETE FROM questions WHERE id = $1`
 
-	cmdTag, err := r.db.Exec(ctx, query, id)
+	cmdTag, err := tx.Exec(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -166,6 +208,13 @@ func (r *questionRepo) FindByQuizID(ctx context.Context, quizID string) ([]*mode
 			explanation,
 			correct_answer,
 			order_index,
+			question_type,
+			scoring_strategy,
+			settings,
+			tag,
+			difficulty,
+			points,
+			negative_points,
 			created_at,
 			updated_at
 		FROM questions
@@ -191,6 +240,13 @@ func (r *questionRepo) FindByQuizID(ctx context.Context, quizID string) ([]*mode
 			&question.Explanation,
 			&question.CorrectAnswer,
 			&question.OrderIndex,
+			&question.QuestionType,
+			&question.ScoringStrategy,
+			&question.Settings,
+			&question.Tag,
+			&question.Difficulty,
+			&question.Points,
+			&question.NegativePoints,
 			&question.CreatedAt,
 			&question.UpdatedAt,
 		)
@@ -207,3 +263,79 @@ func (r *questionRepo) FindByQuizID(ctx context.Context, quizID string) ([]*mode
 
 	return questions, nil
 }
+
+// FindIDsByQuizIDTx returns the IDs of the quiz's questions in order.
+func (r *questionRepo) FindIDsByQuizIDTx(ctx context.Context, quizID string, tx pgx.Tx) ([]string, error) {
+	query := `SELECT id FROM questions WHERE quiz_id = $1 ORDER BY order_index ASC`
+
+	rows, err := tx.Query(ctx, query, quizID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// MaxOrderIndexTx returns the order index of the quiz's last question, or 0
+// when it has none.
+func (r *questionRepo) MaxOrderIndexTx(ctx context.Context, quizID string, tx pgx.Tx) (int, error) {
+	query := `SELECT COALESCE(MAX(order_index), 0) FROM questions WHERE quiz_id = $1`
+
+	var last int
+	err := tx.QueryRow(ctx, query, quizID).Scan(&last)
+	return last, err
+}
+
+// ReorderTx numbers the quiz's questions 1, 2, ... in the order of ids. It
+// is one statement, so the deferrable order_index constraint lets questions
+// trade places.
+func (r *questionRepo) ReorderTx(ctx context.Context, quizID string, ids []string, tx pgx.Tx) error {
+	query := `
+		UPDATE questions q
+		SET
+			order_index = o.position,
+			updated_at = $3
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, position)
+		WHERE q.id = o.id
+		  AND q.quiz_id = $1
+		  AND q.order_index <> o.position
+	`
+
+	_, err := tx.Exec(ctx, query, quizID, ids, time.Now())
+	return err
+}
+
+// ReindexTx closes the gaps in the quiz's order indexes, numbering its
+// questions 1, 2, ... in their current order.
+func (r *questionRepo) ReindexTx(ctx context.Context, quizID string, tx pgx.Tx) error {
+	query := `
+		UPDATE questions q
+		SET
+			order_index = o.position,
+			updated_at = $2
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY order_index) AS position
+			FROM questions
+			WHERE quiz_id = $1
+		) o
+		WHERE q.id = o.id
+		  AND q.order_index <> o.position
+	`
+
+	_, err := tx.Exec(ctx, query, quizID, time.Now())
+	return err
+}
@@ -18,7 +18,8 @@ type QuizRepo interface {
 	CreateTx(ctx context.Context, quiz *models.Quiz, tx pgx.Tx) error
 	GetAllQuizzes(ctx context.Context) ([]models.Quiz, error)
 	FindByID(ctx context.Context, id string) (*models.Quiz, error)
-	Update(ctx context.Context, quiz *models.Quiz) error
+	UpdateTx(ctx context.Context, quiz *models.Quiz, tx pgx.Tx) error
+	LockTx(ctx context.Context, id string, tx pgx.Tx) error
 	Delete(ctx context.Context, id string) error
 	FindByCommunityID(ctx context.Context, communityID string) ([]*models.Quiz, error)
 	BeginTx(ctx context.Context) (pgx.Tx, error)
@@ -28,6 +29,12 @@ type QuizRepo interface {
 
 	UpdateAttempt(ctx context.Context, attempt *models.QuizAttempts, tx pgx.Tx) error
 	FindAttemptByUser(ctx context.Context, quizID string, userID string) ([]*models.QuizAttempts, error)
+	FindInProgressAttemptTx(ctx context.Context, quizID, userID string, tx pgx.Tx) (*models.QuizAttempts, error)
+	FindOverdueAttempts(ctx context.Context, cutoff time.Time) ([]*models.QuizAttempts, error)
+	SaveAnswerTx(ctx context.Context, answer *models.SavedAnswer, tx pgx.Tx) error
+	DeleteSavedAnswerTx(ctx context.Context, attemptID, questionID string, tx pgx.Tx) error
+	FindSavedAnswersTx(ctx context.Context, attemptID string, tx pgx.Tx) ([]models.SavedAnswer, error)
+	DeleteSavedAnswersTx(ctx context.Context, attemptID string, tx pgx.Tx) error
 	FindAttemptByQuiz(ctx context.Context, quizID string) ([]*models.QuizAttempts, error)
 
 	AddLike(ctx context.Context, quizID, userID string) error
@@ -37,9 +44,13 @@ type QuizRepo interface {
 	GetQuizLeaderboard(ctx context.Context, quizID string) ([]dto_quiz.LeaderboardEntry, error)
 	IsLike(ctx context.Context, quizID, userId string) (bool, error)
 	FindAttemptsByUserID(ctx context.Context, userID string) ([]dto_quiz.UserAttemptWithQuiz, error)
-	GetUserAnswersForAttempt(ctx context.Context, attemptID string) (map[string]string, error)
-	GetOptionStatsForQuiz(ctx context.Context, quizID string) (map[string]int, error)
+	FindOfficialResultsByUserID(ctx context.Context, userID string) ([]dto_quiz.UserQuizResult, error)
+	GetUserAnswersForAttempt(ctx context.Context, attemptID string) (map[string][]models.UserAnwer, error)
+	GetOptionStatsForQuiz(ctx context.Context, quizID string) (selections map[string]int, shown map[string]int, err error)
 	GetAttemptByID(ctx context.Context, attemptID string) (*models.QuizAttempts, error)
+	FindPools(ctx context.Context, quizID string) ([]models.QuizPool, error)
+	ReplacePoolsTx(ctx context.Context, quizID string, pools []models.QuizPool, tx pgx.Tx) error
+	ApplySchedules(ctx context.Context, publishBy, closeBy time.Time) (published int64, closed int64, err error)
 }
 
 type quizRepo struct {
@@ -60,9 +71,20 @@ func (r *quizRepo) CreateTx(ctx context.Context, quiz *models.Quiz, tx pgx.Tx) e
 			title,
 			description,
 			duration_minutes,
-			is_published)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at
+			status,
+			publish_at,
+			close_at,
+			shuffle_questions,
+			shuffle_options,
+			max_attempts,
+			cooldown_minutes,
+			scoring_rule,
+			pass_mark,
+			license,
+			forked_from_quiz_id,
+			forked_from_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id, is_published, created_at, updated_at
 	`
 
 	err := tx.QueryRow(ctx, query,
@@ -71,8 +93,19 @@ func (r *quizRepo) CreateTx(ctx context.Context, quiz *models.Quiz, tx pgx.Tx) e
 		quiz.Title,
 		quiz.Description,
 		quiz.DurationMinutes,
-		quiz.IsPublished,
-	).Scan(&quiz.ID, &quiz.CreatedAt, &quiz.UpdatedAt)
+		quiz.Status,
+		quiz.PublishAt,
+		quiz.CloseAt,
+		quiz.ShuffleQuestions,
+		quiz.ShuffleOptions,
+		quiz.MaxAttempts,
+		quiz.CooldownMinutes,
+		quiz.ScoringRule,
+		quiz.PassMark,
+		quiz.License,
+		quiz.ForkedFromQuiz,
+		quiz.ForkedFromUser,
+	).Scan(&quiz.ID, &quiz.IsPublished, &quiz.CreatedAt, &quiz.UpdatedAt)
 
 	return err
 }
@@ -87,12 +120,12 @@ func (r *quizRepo) GetAllQuizzes(ctx context.Context) ([]models.Quiz, error) {
 		"description",
 		duration_minutes,
 		likes_count,
-		(SELECT COUNT(*) FROM quiz_attempts WHERE quiz_id = quizzes.id AND attempt_number = 1) as students_count,
-		COALESCE((SELECT AVG(percentage) FROM quiz_attempts WHERE quiz_id = quizzes.id AND attempt_number = 1), 0) as average_score,
+		(SELECT COUNT(*) FROM official_attempts WHERE quiz_id = quizzes.id) as students_count,
+		COALESCE((SELECT AVG(percentage) FROM official_attempts WHERE quiz_id = quizzes.id), 0) as average_score,
 		is_published,
 		created_at
 	FROM quizzes
-	WHERE is_published = true
+	WHERE status IN ('published', 'closed')
 	ORDER BY created_at DESC
 `
 
@@ -143,9 +176,21 @@ func (r *quizRepo) FindByID(ctx context.Context, id string) (*models.Quiz, error
 			description,
 			duration_minutes,
 			likes_count,
-			(SELECT COUNT(*) FROM quiz_attempts WHERE quiz_id = $1 AND attempt_number = 1) as students_count,
-			COALESCE((SELECT AVG(percentage) FROM quiz_attempts WHERE quiz_id = $1 AND attempt_number = 1), 0) as average_score,
+			(SELECT COUNT(*) FROM official_attempts WHERE quiz_id = $1) as students_count,
+			COALESCE((SELECT AVG(percentage) FROM official_attempts WHERE quiz_id = $1), 0) as average_score,
 			is_published,
+			status,
+			publish_at,
+			close_at,
+			shuffle_questions,
+			shuffle_options,
+			max_attempts,
+			cooldown_minutes,
+			scoring_rule,
+			pass_mark,
+			license,
+			forked_from_quiz_id,
+			forked_from_user_id,
 			created_at,
 			updated_at
 		FROM quizzes
@@ -165,6 +210,18 @@ func (r *quizRepo) FindByID(ctx context.Context, id string) (*models.Quiz, error
 		&quiz.StudentsCount,
 		&quiz.AverageScore,
 		&quiz.IsPublished,
+		&quiz.Status,
+		&quiz.PublishAt,
+		&quiz.CloseAt,
+		&quiz.ShuffleQuestions,
+		&quiz.ShuffleOptions,
+		&quiz.MaxAttempts,
+		&quiz.CooldownMinutes,
+		&quiz.ScoringRule,
+		&quiz.PassMark,
+		&quiz.License,
+		&quiz.ForkedFromQuiz,
+		&quiz.ForkedFromUser,
 		&quiz.CreatedAt,
 		&quiz.UpdatedAt,
 	)
@@ -174,23 +231,48 @@ func (r *quizRepo) FindByID(ctx context.Context, id string) (*models.Quiz, error
 	return &quiz, nil
 }
 
-func (r *quizRepo) Update(ctx context.Context, quiz *models.Quiz) error {
+// LockTx locks the quiz row until tx ends, so edits to its set of questions
+// run one at a time.
+func (r *quizRepo) LockTx(ctx context.Context, id string, tx pgx.Tx) error {
+	var lockedID string
+	return tx.QueryRow(ctx, `SELECT id FROM quizzes WHERE id = $1 FOR UPDATE`, id).Scan(&lockedID)
+}
+
+func (r *quizRepo) UpdateTx(ctx context.Context, quiz *models.Quiz, tx pgx.Tx) error {
 	query := `
 		UPDATE quizzes
 		SET
 			title = $1,
 			description = $2,
 			duration_minutes = $3,
-			is_published = $4,
-			updated_at = $5
-		WHERE id = $6
+			status = $4,
+			publish_at = $5,
+			close_at = $6,
+			shuffle_questions = $7,
+			shuffle_options = $8,
+			max_attempts = $9,
+			cooldown_minutes = $10,
+			scoring_rule = $11,
+			pass_mark = $12,
+			license = $13,
+			updated_at = $14
+		WHERE id = $15
 	`
 
-	cmdTag, err := r.db.Exec(ctx, query,
+	cmdTag, err := tx.Exec(ctx, query,
 		quiz.Title,
 		quiz.Description,
 		quiz.DurationMinutes,
-		quiz.IsPublished,
+		quiz.Status,
+		quiz.PublishAt,
+		quiz.CloseAt,
+		quiz.ShuffleQuestions,
+		quiz.ShuffleOptions,
+		quiz.MaxAttempts,
+		quiz.CooldownMinutes,
+		quiz.ScoringRule,
+		quiz.PassMark,
+		quiz.License,
 		time.Now(),
 		quiz.ID,
 	)
@@ -231,8 +313,8 @@ func (r *quizRepo) FindByCommunityID(ctx context.Context, communityID string) ([
 			description,
 			duration_minutes,
 			likes_count,
-			(SELECT COUNT(*) FROM quiz_attempts WHERE quiz_id = quizzes.id AND attempt_number = 1) as students_count,
-			COALESCE((SELECT AVG(percentage) FROM quiz_attempts WHERE quiz_id = quizzes.id AND attempt_number = 1), 0) as average_score,
+			(SELECT COUNT(*) FROM official_attempts WHERE quiz_id = quizzes.id) as students_count,
+			COALESCE((SELECT AVG(percentage) FROM official_attempts WHERE quiz_id = quizzes.id), 0) as average_score,
 			is_published,
 			created_at,
 			updated_at
@@ -286,8 +368,8 @@ func (r *quizRepo) CreateBatchUserAnswer(
 ) error {
 
 	query := `
-		INSERT INTO user_answers (attempt_id, question_id, option_id)
-		VALUES ($1, $2, $3)
+		INSERT INTO user_answers (attempt_id, question_id, option_id, text_answer, position)
+		VALUES ($1, $2, $3, $4, $5)
 	`
 
 	batch := &pgx.Batch{}
@@ -298,6 +380,8 @@ func (r *quizRepo) CreateBatchUserAnswer(
 			a.AttemptID,
 			a.QuestionID,
 			a.OptionID,
+			a.TextAnswer,
+			a.Position,
 		)
 	}
 
@@ -321,9 +405,15 @@ func (r *quizRepo) CreateUserAttempt(ctx context.Context, attempt *models.QuizAt
 			score,
 			total_questions,
 			percentage,
-			time_taken_minutes,
-			attempt_number)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+			time_taken_seconds,
+			attempt_number,
+			quiz_version_id,
+			status,
+			started_at,
+			deadline_at,
+			shuffle_seed,
+			question_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id
 	`
 	err := tx.QueryRow(ctx, query,
@@ -332,8 +422,14 @@ func (r *quizRepo) CreateUserAttempt(ctx context.Context, attempt *models.QuizAt
 		attempt.Score,
 		attempt.TotalQuestions,
 		attempt.Percentage,
-		attempt.TimeTakenMinutes,
+		attempt.TimeTakenSeconds,
 		attempt.AttemptCount,
+		attempt.QuizVersionID,
+		attempt.Status,
+		attempt.StartedAt,
+		attempt.DeadlineAt,
+		attempt.ShuffleSeed,
+		attempt.QuestionIDs,
 	).Scan(&attempt.ID)
 	return err
 }
@@ -352,10 +448,17 @@ func (r *quizRepo) FindAttemptByUser(
 			score,
 			total_questions,
 			percentage,
-			time_taken_minutes,
-			completed_at
+			time_taken_seconds,
+			attempt_number,
+			status,
+			started_at,
+			deadline_at,
+			completed_at,
+			quiz_version_id,
+			shuffle_seed,
+			question_ids
 		FROM quiz_attempts
-		WHERE quiz_id = $1 AND user_id = $2 ORDER BY completed_at
+		WHERE quiz_id = $1 AND user_id = $2 ORDER BY started_at
 	`
 
 	rows, err := r.db.Query(ctx, query, quizID, userID)
@@ -376,8 +479,15 @@ func (r *quizRepo) FindAttemptByUser(
 			&attempt.Score,
 			&attempt.TotalQuestions,
 			&attempt.Percentage,
-			&attempt.TimeTakenMinutes,
+			&attempt.TimeTakenSeconds,
+			&attempt.AttemptCount,
+			&attempt.Status,
+			&attempt.StartedAt,
+			&attempt.DeadlineAt,
 			&attempt.CompletedAt,
+			&attempt.QuizVersionID,
+			&attempt.ShuffleSeed,
+			&attempt.QuestionIDs,
 		); err != nil {
 			return nil, err
 		}
@@ -392,6 +502,136 @@ func (r *quizRepo) FindAttemptByUser(
 	return quizAttempts, nil
 }
 
+// FindInProgressAttemptTx locks the user's open attempt on the quiz, if any.
+func (r *quizRepo) FindInProgressAttemptTx(ctx context.Context, quizID, userID string, tx pgx.Tx) (*models.QuizAttempts, error) {
+	query := `
+		SELECT
+			id,
+			quiz_id,
+			user_id,
+			score,
+			total_questions,
+			percentage,
+			time_taken_seconds,
+			attempt_number,
+			status,
+			started_at,
+			deadline_at,
+			completed_at,
+			quiz_version_id,
+			shuffle_seed,
+			question_ids,
+			flagged_question_ids
+		FROM quiz_attempts
+		WHERE quiz_id = $1 AND user_id = $2 AND status = 'in_progress'
+		ORDER BY started_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`
+
+	var a models.QuizAttempts
+	err := tx.QueryRow(ctx, query, quizID, userID).Scan(
+		&a.ID,
+		&a.QuizID,
+		&a.UserID,
+		&a.Score,
+		&a.TotalQuestions,
+		&a.Percentage,
+		&a.TimeTakenSeconds,
+		&a.AttemptCount,
+		&a.Status,
+		&a.StartedAt,
+		&a.DeadlineAt,
+		&a.CompletedAt,
+		&a.QuizVersionID,
+		&a.ShuffleSeed,
+		&a.QuestionIDs,
+		&a.FlaggedIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// FindOverdueAttempts lists in-progress attempts whose deadline passed
+// before cutoff.
+func (r *quizRepo) FindOverdueAttempts(ctx context.Context, cutoff time.Time) ([]*models.QuizAttempts, error) {
+	query := `
+		SELECT a.id, a.quiz_id, a.user_id
+		FROM quiz_attempts a
+		JOIN quizzes q ON q.id = a.quiz_id
+		WHERE a.status = 'in_progress'
+		  AND (a.deadline_at < $1
+		       OR (q.status = 'published' AND q.close_at < $1)
+		       OR q.status IN ('closed', 'archived'))
+	`
+	rows, err := r.db.Query(ctx, query, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*models.QuizAttempts
+	for rows.Next() {
+		var a models.QuizAttempts
+		if err := rows.Scan(&a.ID, &a.QuizID, &a.UserID); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, &a)
+	}
+	return attempts, rows.Err()
+}
+
+// SaveAnswerTx stores the answer to one question of an attempt, replacing
+// any answer saved for it before.
+func (r *quizRepo) SaveAnswerTx(ctx context.Context, answer *models.SavedAnswer, tx pgx.Tx) error {
+	query := `
+		INSERT INTO saved_answers (attempt_id, question_id, answer, saved_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (attempt_id, question_id)
+		DO UPDATE SET answer = EXCLUDED.answer, saved_at = EXCLUDED.saved_at
+	`
+	_, err := tx.Exec(ctx, query, answer.AttemptID, answer.QuestionID, answer.Answer, answer.SavedAt)
+	return err
+}
+
+func (r *quizRepo) DeleteSavedAnswerTx(ctx context.Context, attemptID, questionID string, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `DELETE FROM saved_answers WHERE attempt_id = $1 AND question_id = $2`, attemptID, questionID)
+	return err
+}
+
+func (r *quizRepo) FindSavedAnswersTx(ctx context.Context, attemptID string, tx pgx.Tx) ([]models.SavedAnswer, error) {
+	query := `
+		SELECT attempt_id, question_id, answer, saved_at
+		FROM saved_answers
+		WHERE attempt_id = $1
+		ORDER BY saved_at
+	`
+	rows, err := tx.Query(ctx, query, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var answers []models.SavedAnswer
+	for rows.Next() {
+		var a models.SavedAnswer
+		if err := rows.Scan(&a.AttemptID, &a.QuestionID, &a.Answer, &a.SavedAt); err != nil {
+			return nil, err
+		}
+		answers = append(answers, a)
+	}
+	return answers, rows.Err()
+}
+
+func (r *quizRepo) DeleteSavedAnswersTx(ctx context.Context, attemptID string, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `DELETE FROM saved_answers WHERE attempt_id = $1`, attemptID)
+	return err
+}
+
+// FindAttemptByQuiz returns each learner's official attempt on the quiz;
+// under the average rule that is their latest one.
 func (r *quizRepo) FindAttemptByQuiz(
 	ctx context.Context,
 	quizID string,
@@ -405,11 +645,14 @@ func (r *quizRepo) FindAttemptByQuiz(
 			score,
 			total_questions,
 			percentage,
-			time_taken_minutes,
+			time_taken_seconds,
 			attempt_number,
+			status,
+			started_at,
+			deadline_at,
 			completed_at
 		FROM quiz_attempts
-		WHERE quiz_id = $1 AND attempt_number = 1
+		WHERE id IN (SELECT attempt_id FROM official_attempts WHERE quiz_id = $1)
 		ORDER BY completed_at DESC
 	`
 
@@ -431,8 +674,11 @@ func (r *quizRepo) FindAttemptByQuiz(
 			&attempt.Score,
 			&attempt.TotalQuestions,
 			&attempt.Percentage,
-			&attempt.TimeTakenMinutes,
+			&attempt.TimeTakenSeconds,
 			&attempt.AttemptCount,
+			&attempt.Status,
+			&attempt.StartedAt,
+			&attempt.DeadlineAt,
 			&attempt.CompletedAt,
 		); err != nil {
 			return nil, err
@@ -455,10 +701,15 @@ func (r *quizRepo) UpdateAttempt(ctx context.Context, attempt *models.QuizAttemp
 			score = $1,
 			total_questions = $2,
 			percentage = $3,
-			time_taken_minutes = $4,
+			time_taken_seconds = $4,
 			attempt_number = $5,
-			completed_at = $6
-		WHERE id = $7
+			completed_at = $6,
+			status = $7,
+			flagged_question_ids = $8,
+			max_points = $9,
+			passed = $10,
+			pass_mark = $11
+		WHERE id = $12
 	`
 	// Changed from QueryRow to Exec because the UPDATE statement does not include a RETURNING clause.
 	// Therefore, it does not return any rows to scan, checking RowsAffected ensure the update happened.
@@ -466,9 +717,14 @@ func (r *quizRepo) UpdateAttempt(ctx context.Context, attempt *models.QuizAttemp
 		attempt.Score,
 		attempt.TotalQuestions,
 		attempt.Percentage,
-		attempt.TimeTakenMinutes,
+		attempt.TimeTakenSeconds,
 		attempt.AttemptCount,
 		attempt.CompletedAt,
+		attempt.Status,
+		attempt.FlaggedIDs,
+		attempt.MaxPoints,
+		attempt.Passed,
+		attempt.PassMark,
 		attempt.ID,
 	)
 	if err != nil {
@@ -542,8 +798,8 @@ func (r *quizRepo) FindQuizzesByCommunityIDWithCount(ctx context.Context, commun
 			q.duration_minutes,
 			q.likes_count,
 			q.created_at,
-			(SELECT COUNT(*) FROM quiz_attempts WHERE quiz_id = q.id AND attempt_number = 1) as students_count,
-			COALESCE((SELECT AVG(percentage) FROM quiz_attempts WHERE quiz_id = q.id AND attempt_number = 1), 0) as average_score,
+			(SELECT COUNT(*) FROM official_attempts WHERE quiz_id = q.id) as students_count,
+			COALESCE((SELECT AVG(percentage) FROM official_attempts WHERE quiz_id = q.id), 0) as average_score,
 			(SELECT COUNT(*) FROM questions WHERE quiz_id = q.id) as number_of_questions,
 			u.id,
 			u.username,
@@ -553,7 +809,7 @@ func (r *quizRepo) FindQuizzesByCommunityIDWithCount(ctx context.Context, commun
 		FROM quizzes q
 		JOIN users u ON q.creator_id = u.id
 		LEFT JOIN community_members cm ON cm.user_id = u.id AND cm.community_id = q.community_id
-		WHERE q.community_id = $1 AND q.is_published = true
+		WHERE q.community_id = $1 AND q.status IN ('published', 'closed')
 		ORDER BY q.created_at DESC
 	`
 
@@ -603,21 +859,25 @@ func (r *quizRepo) FindQuizzesByCommunityIDWithCount(ctx context.Context, commun
 	return quizzes, nil
 }
 
+// GetQuizLeaderboard ranks each learner's official result under the quiz's
+// scoring rule.
 func (r *quizRepo) GetQuizLeaderboard(ctx context.Context, quizID string) ([]dto_quiz.LeaderboardEntry, error) {
 	query := `
 		SELECT
-			qa.id,
-			qa.time_taken_minutes,
-			qa.score,
-			qa.completed_at,
+			oa.attempt_id,
+			oa.time_taken_seconds,
+			oa.score,
+			oa.percentage,
+			oa.attempts_count,
+			oa.completed_at,
 			u.id,
 			u.username,
 			u.email,
 			u.avatar
-		FROM quiz_attempts qa
-		JOIN users u ON qa.user_id = u.id
-		WHERE qa.quiz_id = $1 AND qa.attempt_number = 1
-		ORDER BY qa.score DESC
+		FROM official_attempts oa
+		JOIN users u ON oa.user_id = u.id
+		WHERE oa.quiz_id = $1
+		ORDER BY oa.percentage DESC, oa.time_taken_seconds ASC
 	`
 
 	rows, err := r.db.Query(ctx, query, quizID)
@@ -632,8 +892,10 @@ func (r *quizRepo) GetQuizLeaderboard(ctx context.Context, quizID string) ([]dto
 		var submittedAt time.Time
 		if err := rows.Scan(
 			&entry.AttemptID,
-			&entry.TimeTakenMinutes,
+			&entry.TimeTakenSeconds,
 			&entry.Score,
+			&entry.Percentage,
+			&entry.AttemptsCount,
 			&submittedAt,
 			&entry.User.ID,
 			&entry.User.Username,
@@ -642,6 +904,7 @@ func (r *quizRepo) GetQuizLeaderboard(ctx context.Context, quizID string) ([]dto
 		); err != nil {
 			return nil, err
 		}
+		entry.TimeTakenMinutes = entry.TimeTakenSeconds / 60
 		entry.SubmittedAt = utils.FormatTime(submittedAt)
 		leaderboard = append(leaderboard, entry)
 	}
@@ -671,7 +934,7 @@ func (r *quizRepo) FindAttemptsByUserID(ctx context.Context, userID string) ([]d
 	query := `
 		SELECT 
 			qa.score,
-			qa.time_taken_minutes,
+			qa.time_taken_seconds,
 			qa.attempt_number,
 			qa.percentage,
 			qa.completed_at,
@@ -680,7 +943,7 @@ func (r *quizRepo) FindAttemptsByUserID(ctx context.Context, userID string) ([]d
 			(SELECT COUNT(*) FROM questions WHERE quiz_id = q.id) as questions_count
 		FROM quiz_attempts qa
 		JOIN quizzes q ON qa.quiz_id = q.id
-		WHERE qa.user_id = $1
+		WHERE qa.user_id = $1 AND qa.status <> 'in_progress'
 		ORDER BY qa.completed_at DESC
 	`
 
@@ -696,7 +959,7 @@ func (r *quizRepo) FindAttemptsByUserID(ctx context.Context, userID string) ([]d
 		var completedAt time.Time
 		if err := rows.Scan(
 			&a.Score,
-			&a.TimeTakenMinutes,
+			&a.TimeTakenSeconds,
 			&a.AttemptNumber,
 			&a.Percentage,
 			&completedAt,
@@ -706,6 +969,7 @@ func (r *quizRepo) FindAttemptsByUserID(ctx context.Context, userID string) ([]d
 		); err != nil {
 			return nil, err
 		}
+		a.TimeTakenMinutes = a.TimeTakenSeconds / 60
 		a.CompletedAt = utils.FormatTime(completedAt)
 		attempts = append(attempts, a)
 	}
@@ -713,60 +977,247 @@ func (r *quizRepo) FindAttemptsByUserID(ctx context.Context, userID string) ([]d
 	return attempts, nil
 }
 
-func (r *quizRepo) GetUserAnswersForAttempt(ctx context.Context, attemptID string) (map[string]string, error) {
-	query := `SELECT question_id, option_id FROM user_answers WHERE attempt_id = $1`
+// FindOfficialResultsByUserID returns the user's official result on every
+// quiz they finished, under each quiz's scoring rule.
+func (r *quizRepo) FindOfficialResultsByUserID(ctx context.Context, userID string) ([]dto_quiz.UserQuizResult, error) {
+	query := `
+		SELECT
+			oa.attempt_id,
+			oa.scoring_rule,
+			oa.score,
+			oa.percentage,
+			oa.attempts_count,
+			oa.completed_at,
+			q.id,
+			q.title,
+			(SELECT COUNT(*) FROM questions WHERE quiz_id = q.id) as questions_count
+		FROM official_attempts oa
+		JOIN quizzes q ON oa.quiz_id = q.id
+		WHERE oa.user_id = $1
+		ORDER BY oa.completed_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []dto_quiz.UserQuizResult
+	for rows.Next() {
+		var res dto_quiz.UserQuizResult
+		var completedAt time.Time
+		if err := rows.Scan(
+			&res.AttemptID,
+			&res.ScoringRule,
+			&res.Score,
+			&res.Percentage,
+			&res.AttemptsCount,
+			&completedAt,
+			&res.Quiz.ID,
+			&res.Quiz.Title,
+			&res.Quiz.QuestionsCount,
+		); err != nil {
+			return nil, err
+		}
+		res.CompletedAt = utils.FormatTime(completedAt)
+		results = append(results, res)
+	}
+
+	return results, rows.Err()
+}
+
+// GetUserAnswersForAttempt returns the stored answer rows keyed by question.
+func (r *quizRepo) GetUserAnswersForAttempt(ctx context.Context, attemptID string) (map[string][]models.UserAnwer, error) {
+	query := `
+		SELECT id, attempt_id, question_id, option_id, text_answer, position, created_at
+		FROM user_answers
+		WHERE attempt_id = $1
+		ORDER BY created_at, position, id
+	`
 	rows, err := r.db.Query(ctx, query, attemptID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	answers := make(map[string]string)
+	answers := make(map[string][]models.UserAnwer)
 	for rows.Next() {
-		var qID, oID string
-		if err := rows.Scan(&qID, &oID); err != nil {
+		var a models.UserAnwer
+		if err := rows.Scan(&a.ID, &a.AttemptID, &a.QuestionID, &a.OptionID, &a.TextAnswer, &a.Position, &a.CreatedAt); err != nil {
 			return nil, err
 		}
-		answers[qID] = oID
+		answers[a.QuestionID] = append(answers[a.QuestionID], a)
 	}
-	return answers, nil
+	return answers, rows.Err()
 }
 
-func (r *quizRepo) GetOptionStatsForQuiz(ctx context.Context, quizID string) (map[string]int, error) {
+// GetOptionStatsForQuiz counts, over finished attempts, how often each
+// option was selected and how often each question was shown. Attempts of a
+// quiz with pools only see the questions drawn for them, so selections
+// should be compared with shown rather than with the number of attempts.
+func (r *quizRepo) GetOptionStatsForQuiz(ctx context.Context, quizID string) (map[string]int, map[string]int, error) {
 	query := `
 		SELECT ua.option_id, COUNT(*)
 		FROM user_answers ua
 		JOIN quiz_attempts qa ON ua.attempt_id = qa.id
 		WHERE qa.quiz_id = $1
+		  AND qa.status <> 'in_progress'
+		  AND ua.option_id IS NOT NULL
+		  AND ua.position IS NULL
+		  AND ua.text_answer IS NULL
 		GROUP BY ua.option_id
 	`
 	rows, err := r.db.Query(ctx, query, quizID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	stats := make(map[string]int)
+	selections := make(map[string]int)
 	for rows.Next() {
 		var oID string
 		var count int
 		if err := rows.Scan(&oID, &count); err != nil {
+			return nil, nil, err
+		}
+		selections[oID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Attempts without drawn questions were shown every question of their
+	// version.
+	shownQuery := `
+		SELECT question_id, COUNT(*)
+		FROM (
+			SELECT unnest(qa.question_ids)::text AS question_id
+			FROM quiz_attempts qa
+			WHERE qa.quiz_id = $1
+			  AND qa.status <> 'in_progress'
+			  AND qa.question_ids IS NOT NULL
+			UNION ALL
+			SELECT q->>'id'
+			FROM quiz_attempts qa
+			JOIN quiz_versions v ON v.id = qa.quiz_version_id
+			CROSS JOIN jsonb_array_elements(v.snapshot->'questions') q
+			WHERE qa.quiz_id = $1
+			  AND qa.status <> 'in_progress'
+			  AND qa.question_ids IS NULL
+		) shown
+		GROUP BY question_id
+	`
+	shownRows, err := r.db.Query(ctx, shownQuery, quizID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer shownRows.Close()
+
+	shown := make(map[string]int)
+	for shownRows.Next() {
+		var qID string
+		var count int
+		if err := shownRows.Scan(&qID, &count); err != nil {
+			return nil, nil, err
+		}
+		shown[qID] = count
+	}
+	return selections, shown, shownRows.Err()
+}
+
+// ApplySchedules publishes scheduled quizzes whose publish_at is at or before
+// publishBy and closes published ones whose close_at is at or before closeBy.
+// A quiz whose whole window went by while it was scheduled ends up closed.
+func (r *quizRepo) ApplySchedules(ctx context.Context, publishBy, closeBy time.Time) (int64, int64, error) {
+	published, err := r.db.Exec(ctx, `
+		UPDATE quizzes
+		SET status = 'published', updated_at = NOW()
+		WHERE status = 'scheduled' AND publish_at <= $1
+	`, publishBy)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	closed, err := r.db.Exec(ctx, `
+		UPDATE quizzes
+		SET status = 'closed', updated_at = NOW()
+		WHERE status = 'published' AND close_at <= $1
+	`, closeBy)
+	if err != nil {
+		return published.RowsAffected(), 0, err
+	}
+
+	return published.RowsAffected(), closed.RowsAffected(), nil
+}
+
+func (r *quizRepo) FindPools(ctx context.Context, quizID string) ([]models.QuizPool, error) {
+	query := `
+		SELECT id, quiz_id, position, tag, difficulty, draw_count, created_at
+		FROM quiz_pools
+		WHERE quiz_id = $1
+		ORDER BY position
+	`
+	rows, err := r.db.Query(ctx, query, quizID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pools := make([]models.QuizPool, 0)
+	for rows.Next() {
+		var p models.QuizPool
+		if err := rows.Scan(&p.ID, &p.QuizID, &p.Position, &p.Tag, &p.Difficulty, &p.DrawCount, &p.CreatedAt); err != nil {
 			return nil, err
 		}
-		stats[oID] = count
+		pools = append(pools, p)
+	}
+	return pools, rows.Err()
+}
+
+// ReplacePoolsTx swaps the quiz's pools for the given ones, numbered in
+// order. An empty slice removes them.
+func (r *quizRepo) ReplacePoolsTx(ctx context.Context, quizID string, pools []models.QuizPool, tx pgx.Tx) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM quiz_pools WHERE quiz_id = $1`, quizID); err != nil {
+		return err
+	}
+	if len(pools) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO quiz_pools (quiz_id, position, tag, difficulty, draw_count)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	batch := &pgx.Batch{}
+	for i, p := range pools {
+		batch.Queue(query, quizID, i, p.Tag, p.Difficulty, p.DrawCount)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for range pools {
+		if _, err := br.Exec(); err != nil {
+			return err
+		}
 	}
-	return stats, nil
+	return nil
 }
 
 func (r *quizRepo) GetAttemptByID(ctx context.Context, attemptID string) (*models.QuizAttempts, error) {
 	query := `
-		SELECT id, quiz_id, user_id, score, total_questions, percentage, time_taken_minutes, completed_at
+		SELECT id, quiz_id, user_id, score, total_questions, percentage, time_taken_seconds, attempt_number,
+			status, started_at, deadline_at, completed_at, quiz_version_id, shuffle_seed, question_ids,
+			flagged_question_ids, max_points, passed, pass_mark
 		FROM quiz_attempts
 		WHERE id = $1
 	`
 	var a models.QuizAttempts
 	err := r.db.QueryRow(ctx, query, attemptID).Scan(
-		&a.ID, &a.QuizID, &a.UserID, &a.Score, &a.TotalQuestions, &a.Percentage, &a.TimeTakenMinutes, &a.CompletedAt,
+		&a.ID, &a.QuizID, &a.UserID, &a.Score, &a.TotalQuestions, &a.Percentage, &a.TimeTakenSeconds, &a.AttemptCount,
+		&a.Status, &a.StartedAt, &a.DeadlineAt, &a.CompletedAt, &a.QuizVersionID, &a.ShuffleSeed, &a.QuestionIDs,
+		&a.FlaggedIDs, &a.MaxPoints, &a.Passed, &a.PassMark,
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,130 @@
+package repos
+
+import (
+	"context"
+
+	"ecoquiz/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type QuizVersionRepo interface {
+	CreateTx(ctx context.Context, quizID string, tx pgx.Tx) (*models.QuizVersion, error)
+	FindByID(ctx context.Context, id string) (*models.QuizVersion, error)
+	FindLatest(ctx context.Context, quizID string) (*models.QuizVersion, error)
+}
+
+type quizVersionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewQuizVersionRepo(db *pgxpool.Pool) QuizVersionRepo {
+	return &quizVersionRepo{db: db}
+}
+
+// CreateTx bumps the quiz's current_version and snapshots its questions and
+// options as seen by tx. Bumping the counter first locks the quiz row, so
+// concurrent edits get consecutive version numbers.
+func (r *quizVersionRepo) CreateTx(ctx context.Context, quizID string, tx pgx.Tx) (*models.QuizVersion, error) {
+	bump := `UPDATE quizzes SET current_version = current_version + 1 WHERE id = $1`
+	cmdTag, err := tx.Exec(ctx, bump, quizID)
+	if err != nil {
+		return nil, err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+
+	query := `
+		INSERT INTO quiz_versions (quiz_id, version, snapshot)
+		SELECT
+			q.id,
+			q.current_version,
+			jsonb_build_object(
+				'title', q.title,
+				'description', COALESCE(q.description, ''),
+				'duration_minutes', COALESCE(q.duration_minutes, 0),
+				'shuffle_questions', q.shuffle_questions,
+				'shuffle_options', q.shuffle_options,
+				'pools', COALESCE((
+					SELECT jsonb_agg(jsonb_build_object(
+						'tag', p.tag,
+						'difficulty', p.difficulty,
+						'draw_count', p.draw_count
+					) ORDER BY p.position)
+					FROM quiz_pools p
+					WHERE p.quiz_id = q.id
+				), '[]'::jsonb),
+				'questions', COALESCE((
+					SELECT jsonb_agg(jsonb_build_object(
+						'id', qs.id,
+						'question_text', qs.question_text,
+						'explanation', COALESCE(qs.explanation, ''),
+						'correct_answer', COALESCE(qs.correct_answer, ''),
+						'order_index', qs.order_index,
+						'question_type', qs.question_type,
+						'scoring_strategy', qs.scoring_strategy,
+						'settings', qs.settings,
+						'tag', qs.tag,
+						'difficulty', qs.difficulty,
+						'points', qs.points,
+						'negative_points', qs.negative_points,
+						'options', COALESCE((
+							SELECT jsonb_agg(jsonb_build_object(
+								'id', o.id,
+								'text', o.text,
+								'is_correct', o.is_correct,
+								'position', o.position,
+								'match_text', o.match_text
+							) ORDER BY o.position)
+							FROM options o
+							WHERE o.question_id = qs.id
+						), '[]'::jsonb)
+					) ORDER BY qs.order_index)
+					FROM questions qs
+					WHERE qs.quiz_id = q.id
+				), '[]'::jsonb)
+			)
+		FROM quizzes q
+		WHERE q.id = $1
+		RETURNING id, quiz_id, version, snapshot, created_at
+	`
+
+	var v models.QuizVersion
+	err = tx.QueryRow(ctx, query, quizID).Scan(&v.ID, &v.QuizID, &v.Version, &v.Snapshot, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *quizVersionRepo) FindByID(ctx context.Context, id string) (*models.QuizVersion, error) {
+	query := `
+		SELECT id, quiz_id, version, snapshot, created_at
+		FROM quiz_versions
+		WHERE id = $1
+	`
+	var v models.QuizVersion
+	err := r.db.QueryRow(ctx, query, id).Scan(&v.ID, &v.QuizID, &v.Version, &v.Snapshot, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *quizVersionRepo) FindLatest(ctx context.Context, quizID string) (*models.QuizVersion, error) {
+	query := `
+		SELECT id, quiz_id, version, snapshot, created_at
+		FROM quiz_versions
+		WHERE quiz_id = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+	var v models.QuizVersion
+	err := r.db.QueryRow(ctx, query, quizID).Scan(&v.ID, &v.QuizID, &v.Version, &v.Snapshot, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
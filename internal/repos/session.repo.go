@@ -0,0 +1,145 @@
+package repos
+
+import (
+	"context"
+	"ecoquiz/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionRepo interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, sessionID string) (*models.Session, error)
+	FindByRefreshHash(ctx context.Context, refreshHash string) (*models.Session, error)
+	Rotate(ctx context.Context, sessionID, oldHash, newHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, sessionID string) error
+	RevokeAllForUser(ctx context.Context, userID string) error
+	RevokeOthers(ctx context.Context, userID, keepSessionID string) error
+	IsActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+type sessionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepo(db *pgxpool.Pool) SessionRepo {
+	return &sessionRepo{db: db}
+}
+
+func (r *sessionRepo) Create(ctx context.Context, session *models.Session) error {
+	query := `
+		INSERT INTO sessions (user_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, last_used_at, created_at
+	`
+	return r.db.QueryRow(ctx, query,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&session.ID, &session.LastUsedAt, &session.CreatedAt)
+}
+
+func (r *sessionRepo) FindByID(ctx context.Context, sessionID string) (*models.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+		       expires_at, revoked_at, last_used_at, created_at
+		FROM sessions
+		WHERE id = $1
+	`
+	var s models.Session
+	err := r.db.QueryRow(ctx, query, sessionID).Scan(
+		&s.ID,
+		&s.UserID,
+		&s.RefreshTokenHash,
+		&s.UserAgent,
+		&s.IPAddress,
+		&s.ExpiresAt,
+		&s.RevokedAt,
+		&s.LastUsedAt,
+		&s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepo) FindByRefreshHash(ctx context.Context, refreshHash string) (*models.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+		       expires_at, revoked_at, last_used_at, created_at
+		FROM sessions
+		WHERE refresh_token_hash = $1
+	`
+	var s models.Session
+	err := r.db.QueryRow(ctx, query, refreshHash).Scan(
+		&s.ID,
+		&s.UserID,
+		&s.RefreshTokenHash,
+		&s.UserAgent,
+		&s.IPAddress,
+		&s.ExpiresAt,
+		&s.RevokedAt,
+		&s.LastUsedAt,
+		&s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Rotate swaps the refresh token of an active session. The old hash is part
+// of the WHERE clause so two concurrent refreshes with the same token cannot
+// both succeed.
+func (r *sessionRepo) Rotate(ctx context.Context, sessionID, oldHash, newHash string, expiresAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET refresh_token_hash = $1,
+		    expires_at = $2,
+		    last_used_at = NOW()
+		WHERE id = $3 AND refresh_token_hash = $4 AND revoked_at IS NULL
+	`
+	cmdTag, err := r.db.Exec(ctx, query, newHash, expiresAt, sessionID, oldHash)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *sessionRepo) Revoke(ctx context.Context, sessionID string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	_, err := r.db.Exec(ctx, query, sessionID)
+	return err
+}
+
+func (r *sessionRepo) RevokeAllForUser(ctx context.Context, userID string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+func (r *sessionRepo) RevokeOthers(ctx context.Context, userID, keepSessionID string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+	_, err := r.db.Exec(ctx, query, userID, keepSessionID)
+	return err
+}
+
+func (r *sessionRepo) IsActive(ctx context.Context, sessionID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM sessions
+			WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`
+	var active bool
+	err := r.db.QueryRow(ctx, query, sessionID).Scan(&active)
+	return active, err
+}
@@ -0,0 +1,185 @@
+package repos
+
+import (
+	"context"
+	"ecoquiz/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TwoFactorRepo interface {
+	SavePending(ctx context.Context, userID, secretEncrypted string) error
+	FindByUserID(ctx context.Context, userID string) (*models.UserTOTP, error)
+	Confirm(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	UseStep(ctx context.Context, userID string, step int64) error
+	Delete(ctx context.Context, userID string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+type twoFactorRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepo(db *pgxpool.Pool) TwoFactorRepo {
+	return &twoFactorRepo{db: db}
+}
+
+// SavePending stores a new, unconfirmed secret. A confirmed enrollment is
+// never overwritten; pgx.ErrNoRows is returned instead.
+func (r *twoFactorRepo) SavePending(ctx context.Context, userID, secretEncrypted string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret_encrypted)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted,
+		    last_used_step = 0,
+		    created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL
+	`
+	cmdTag, err := r.db.Exec(ctx, query, userID, secretEncrypted)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *twoFactorRepo) FindByUserID(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	query := `
+		SELECT user_id, secret_encrypted, confirmed_at, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+	var t models.UserTOTP
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.SecretEncrypted,
+		&t.ConfirmedAt,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Confirm enables two-factor login and stores the first set of recovery
+// codes in one transaction.
+func (r *twoFactorRepo) Confirm(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	cmdTag, err := tx.Exec(ctx, `
+		UPDATE user_totp
+		SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := replaceRecoveryCodesTx(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseStep records a successful code. The step must be newer than the last
+// accepted one, so each code can be used only once; pgx.ErrNoRows signals a
+// replay.
+func (r *twoFactorRepo) UseStep(ctx context.Context, userID string, step int64) error {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+	cmdTag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *twoFactorRepo) Delete(ctx context.Context, userID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *twoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodesTx(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodesTx(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, hash,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode burns an unused code, returning pgx.ErrNoRows if there is
+// none matching.
+func (r *twoFactorRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	cmdTag, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *twoFactorRepo) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	query := `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	return count, err
+}
@@ -3,17 +3,25 @@ package repos
 import (
 	"context"
 	"ecoquiz/internal/models"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepo interface {
 	Create(ctx context.Context, user *models.User) error
+	CreateWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error
+	UsernameExists(ctx context.Context, username string) (bool, error)
 	FindByID(ctx context.Context, userID string) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	Update(ctx context.Context, avater string, banner string, username string, userID string) error
 	UpdateAvatar(ctx context.Context, avatar string, userID string) error
 	UpdateBanner(ctx context.Context, banner string, userID string) error
+	UpdatePassword(ctx context.Context, passwordHash string, userID string) error
+	MarkEmailVerified(ctx context.Context, userID string) error
+	ScheduleDeletion(ctx context.Context, userID string, at time.Time) error
+	CancelDeletion(ctx context.Context, userID string) error
+	DeleteScheduled(ctx context.Context, before time.Time) (int64, error)
 }
 
 type userRepo struct {
@@ -27,25 +35,73 @@ func NewUserRepo(db *pgxpool.Pool) UserRepo {
 }
 
 func (r *userRepo) Create(ctx context.Context, user *models.User) error {
-	query := `INSERT INTO users(username , email , avatar , google_id , password_hash)
-	 VALUES ($1 , $2 , $3 , $4, $5) 
+	query := `INSERT INTO users(username , email , avatar , password_hash , email_verified_at)
+	 VALUES ($1 , $2 , $3 , $4 , $5) 
 	 RETURNING id , created_at , updated_at 
 	 `
-	return r.db.QueryRow(ctx, query, user.Username, user.Email, user.Avatar, user.GoogleID, user.PasswordHash).Scan(
+	return r.db.QueryRow(ctx, query, user.Username, user.Email, user.Avatar, user.PasswordHash, user.EmailVerifiedAt).Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Updated_at,
 	)
 }
 
+// CreateWithIdentity creates a user that signs in through an external
+// provider together with its identity row, so neither exists without the other.
+func (r *userRepo) CreateWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	userQuery := `INSERT INTO users(username , email , avatar , password_hash , email_verified_at)
+	 VALUES ($1 , $2 , $3 , $4 , $5)
+	 RETURNING id , created_at , updated_at
+	 `
+	if err := tx.QueryRow(ctx, userQuery, user.Username, user.Email, user.Avatar, user.PasswordHash, user.EmailVerifiedAt).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Updated_at,
+	); err != nil {
+		return err
+	}
+
+	identityQuery := `
+		INSERT INTO user_identities (user_id, provider, provider_subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	identity.UserID = user.ID
+	if err := tx.QueryRow(ctx, identityQuery,
+		identity.UserID,
+		identity.Provider,
+		identity.ProviderSubject,
+		identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *userRepo) UsernameExists(ctx context.Context, username string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)`
+	var exists bool
+	err := r.db.QueryRow(ctx, query, username).Scan(&exists)
+	return exists, err
+}
+
 func (r *userRepo) FindByID(ctx context.Context, userID string) (*models.User, error) {
 	query := `SELECT 
 	id ,
     username ,
+	password_hash,
     avatar ,
 	email ,
-	google_id ,
 	banner ,
+	email_verified_at ,
+	deletion_scheduled_at ,
 	created_at , 
 	updated_at FROM users WHERE id = $1`
 	user := models.User{}
@@ -53,10 +109,12 @@ func (r *userRepo) FindByID(ctx context.Context, userID string) (*models.User, e
 	err := r.db.QueryRow(ctx, query, userID).Scan(
 		&user.ID,
 		&user.Username,
+		&user.PasswordHash,
 		&user.Avatar,
 		&user.Email,
-		&user.GoogleID,
 		&user.Banner,
+		&user.EmailVerifiedAt,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.Updated_at)
 
@@ -75,8 +133,9 @@ func (r *userRepo) FindByEmail(ctx context.Context, email string) (*models.User,
 	password_hash,
     avatar ,
 	email ,
-	google_id ,
 	banner ,
+	email_verified_at ,
+	deletion_scheduled_at ,
 	created_at , 
 	updated_at FROM users WHERE email = $1`
 	user := models.User{}
@@ -87,8 +146,9 @@ func (r *userRepo) FindByEmail(ctx context.Context, email string) (*models.User,
 		&user.PasswordHash,
 		&user.Avatar,
 		&user.Email,
-		&user.GoogleID,
 		&user.Banner,
+		&user.EmailVerifiedAt,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.Updated_at)
 
@@ -131,3 +191,58 @@ func (r *userRepo) UpdateBanner(ctx context.Context, banner string, userID strin
 	_, err := r.db.Exec(ctx, query, banner, userID)
 	return err
 }
+
+func (r *userRepo) UpdatePassword(ctx context.Context, passwordHash string, userID string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $1,
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+	_, err := r.db.Exec(ctx, query, passwordHash, userID)
+	return err
+}
+
+func (r *userRepo) MarkEmailVerified(ctx context.Context, userID string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()),
+		    updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+func (r *userRepo) ScheduleDeletion(ctx context.Context, userID string, at time.Time) error {
+	query := `
+		UPDATE users
+		SET deletion_scheduled_at = $1,
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+	_, err := r.db.Exec(ctx, query, at, userID)
+	return err
+}
+
+func (r *userRepo) CancelDeletion(ctx context.Context, userID string) error {
+	query := `
+		UPDATE users
+		SET deletion_scheduled_at = NULL,
+		    updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+// DeleteScheduled removes every account whose grace period ended before the
+// given time. Everything the user owns goes with it through ON DELETE CASCADE.
+func (r *userRepo) DeleteScheduled(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM users WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= $1`
+	cmdTag, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
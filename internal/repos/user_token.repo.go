@@ -0,0 +1,97 @@
+package repos
+
+import (
+	"context"
+	"ecoquiz/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserTokenRepo interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	FindValid(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	Consume(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	InvalidateForUser(ctx context.Context, userID, purpose string) error
+}
+
+type userTokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTokenRepo(db *pgxpool.Pool) UserTokenRepo {
+	return &userTokenRepo{db: db}
+}
+
+func (r *userTokenRepo) Create(ctx context.Context, token *models.UserToken) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// FindValid looks up an unused, unexpired token without consuming it, so a
+// request can be validated before the token is spent.
+func (r *userTokenRepo) FindValid(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	query := `
+		SELECT id, user_id, purpose, token_hash, expires_at, used_at, created_at
+		FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	`
+	var t models.UserToken
+	err := r.db.QueryRow(ctx, query, tokenHash, purpose).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.UsedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Consume marks a valid token as used and returns it. Expired, used or
+// unknown tokens return pgx.ErrNoRows; the single UPDATE makes reuse
+// impossible even under concurrent requests.
+func (r *userTokenRepo) Consume(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+	`
+	var t models.UserToken
+	err := r.db.QueryRow(ctx, query, tokenHash, purpose).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.UsedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *userTokenRepo) InvalidateForUser(ctx context.Context, userID, purpose string) error {
+	query := `
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+	_, err := r.db.Exec(ctx, query, userID, purpose)
+	return err
+}
@@ -3,21 +3,41 @@ package routes
 import (
 	"ecoquiz/internal/handlers"
 	middleware "ecoquiz/internal/middlewares"
+	"ecoquiz/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func QuizRoutes(api *gin.RouterGroup, quizHandler *handlers.QuizHandler, jwtsecret string) {
+func QuizRoutes(api *gin.RouterGroup, quizHandler *handlers.QuizHandler, authMiddleware gin.HandlerFunc, limits RateLimits) {
+	read := middleware.RequireScope(models.ScopeQuizzesRead)
+	write := middleware.RequireScope(models.ScopeQuizzesWrite)
+
 	quizGroup := api.Group("/quizzes")
-	quizGroup.Use(middleware.JWTAuth(jwtsecret))
+	quizGroup.Use(authMiddleware)
 	{
-		quizGroup.POST("", quizHandler.CreateQuiz)
-		quizGroup.GET("/get", quizHandler.GetAllQuizzes)
-		quizGroup.GET("/:id", quizHandler.GetQuizByID)
-		quizGroup.GET("/:id/take", quizHandler.TakeQuiz)
-		quizGroup.POST("/:id/submit", quizHandler.SubmitQuiz)
-		quizGroup.POST("/:id/like", quizHandler.ToggleLike)
-		quizGroup.GET("/attempts/:id/results", quizHandler.GetQuizResult)
-		quizGroup.POST("/:id/questions", quizHandler.AddQuestion)
+		quizGroup.POST("", write, limits.CreateQuiz, quizHandler.CreateQuiz)
+		quizGroup.GET("/get", read, quizHandler.GetAllQuizzes)
+		quizGroup.POST("/import", write, limits.CreateQuiz, quizHandler.ImportQuiz)
+		quizGroup.GET("/:id", read, quizHandler.GetQuizByID)
+		quizGroup.GET("/:id/take", write, quizHandler.TakeQuiz)
+		quizGroup.POST("/:id/start", write, quizHandler.StartQuiz)
+		quizGroup.GET("/:id/resume", write, quizHandler.ResumeQuiz)
+		quizGroup.PATCH("/:id/answers", write, quizHandler.SaveAnswer)
+		quizGroup.DELETE("/:id/answers/:questionId", write, quizHandler.ClearAnswer)
+		quizGroup.POST("/:id/submit", write, quizHandler.SubmitQuiz)
+		quizGroup.POST("/:id/like", write, quizHandler.ToggleLike)
+		quizGroup.GET("/attempts/:id/results", read, quizHandler.GetQuizResult)
+		quizGroup.POST("/:id/questions", write, quizHandler.AddQuestion)
+		quizGroup.POST("/:id/questions/bulk", write, quizHandler.AddQuestions)
+		quizGroup.PUT("/:id/questions/order", write, quizHandler.ReorderQuestions)
+		quizGroup.PUT("/:id", write, quizHandler.UpdateQuiz)
+		quizGroup.DELETE("/:id", write, quizHandler.DeleteQuiz)
+		quizGroup.PUT("/:id/pools", write, quizHandler.SetPools)
+		quizGroup.GET("/:id/export", write, quizHandler.ExportQuiz)
+		quizGroup.POST("/:id/fork", write, limits.CreateQuiz, quizHandler.ForkQuiz)
+		quizGroup.PUT("/:id/questions/:questionId", write, quizHandler.UpdateQuestion)
+		quizGroup.DELETE("/:id/questions/:questionId", write, quizHandler.DeleteQuestion)
+		quizGroup.PUT("/:id/questions/:questionId/options/:optionId", write, quizHandler.UpdateOption)
+		quizGroup.DELETE("/:id/questions/:questionId/options/:optionId", write, quizHandler.DeleteOption)
 	}
 }
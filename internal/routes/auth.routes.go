@@ -2,16 +2,39 @@ package routes
 
 import (
 	"ecoquiz/internal/handlers"
+	middleware "ecoquiz/internal/middlewares"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(rg *gin.RouterGroup, authHandler *handlers.AuthHandler) {
+func AuthRoutes(rg *gin.RouterGroup, authHandler *handlers.AuthHandler, authMiddleware gin.HandlerFunc, limits RateLimits) {
+	// Personal access tokens must not be able to manage sign-in methods or
+	// sessions, so the authenticated auth routes also require a login session.
+	session := middleware.RequireSession()
+
 	auth := rg.Group("/auth")
 	{
 		auth.GET("/google", authHandler.GoogleLogin)
 		auth.GET("/google/callback", authHandler.GoogleCallback)
-		auth.POST("/register", authHandler.Register)
-		auth.POST("/login", authHandler.Login)
+		auth.GET("/google/link", authMiddleware, session, authHandler.GoogleLink)
+		auth.POST("/google/link/confirm", limits.Auth, authHandler.ConfirmGoogleLink)
+		auth.GET("/identities", authMiddleware, session, authHandler.ListIdentities)
+		auth.DELETE("/identities/:provider", authMiddleware, session, authHandler.UnlinkIdentity)
+		auth.POST("/register", limits.Register, authHandler.Register)
+		auth.POST("/login", limits.Auth, authHandler.Login)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/logout", authHandler.Logout)
+		auth.POST("/logout-all", authMiddleware, session, authHandler.LogoutAll)
+		auth.POST("/verify-email", limits.Auth, authHandler.VerifyEmail)
+		auth.POST("/verify-email/resend", authMiddleware, session, limits.Auth, authHandler.ResendVerificationEmail)
+		auth.POST("/forgot-password", limits.Auth, authHandler.ForgotPassword)
+		auth.POST("/reset-password", limits.Auth, authHandler.ResetPassword)
+		auth.POST("/change-password", authMiddleware, session, authHandler.ChangePassword)
+		auth.POST("/2fa/verify", limits.Auth, authHandler.VerifyTwoFactor)
+		auth.GET("/2fa", authMiddleware, session, authHandler.TwoFactorStatus)
+		auth.POST("/2fa/setup", authMiddleware, session, authHandler.SetupTwoFactor)
+		auth.POST("/2fa/confirm", authMiddleware, session, authHandler.ConfirmTwoFactor)
+		auth.POST("/2fa/disable", authMiddleware, session, authHandler.DisableTwoFactor)
+		auth.POST("/2fa/recovery-codes", authMiddleware, session, authHandler.RegenerateRecoveryCodes)
 	}
 }
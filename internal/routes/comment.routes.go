@@ -3,20 +3,23 @@ package routes
 import (
 	"ecoquiz/internal/handlers"
 	middleware "ecoquiz/internal/middlewares"
+	"ecoquiz/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CommentRoutes(rg *gin.RouterGroup, commentHandler *handlers.CommentHandler, secretJWT string) {
+func CommentRoutes(rg *gin.RouterGroup, commentHandler *handlers.CommentHandler, authMiddleware gin.HandlerFunc, limits RateLimits) {
+	write := middleware.RequireScope(models.ScopeCommentsWrite)
+
 	questions := rg.Group("/questions")
-	questions.Use(middleware.JWTAuth(secretJWT))
+	questions.Use(authMiddleware)
 	{
-		questions.POST("/:id/comments", commentHandler.CreateComment)
+		questions.POST("/:id/comments", write, limits.CreateComment, commentHandler.CreateComment)
 	}
 
 	comments := rg.Group("/comments")
-	comments.Use(middleware.JWTAuth(secretJWT))
+	comments.Use(authMiddleware)
 	{
-		comments.DELETE("/:id", commentHandler.DeleteComment)
+		comments.DELETE("/:id", write, commentHandler.DeleteComment)
 	}
 }
@@ -3,20 +3,24 @@ package routes
 import (
 	"ecoquiz/internal/handlers"
 	middleware "ecoquiz/internal/middlewares"
+	"ecoquiz/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CommunityRoutes(rg *gin.RouterGroup, communityHandler *handlers.CommunityHandler, secretJWT string) {
+func CommunityRoutes(rg *gin.RouterGroup, communityHandler *handlers.CommunityHandler, authMiddleware gin.HandlerFunc) {
+	read := middleware.RequireScope(models.ScopeCommunitiesRead)
+	write := middleware.RequireScope(models.ScopeCommunitiesWrite)
+
 	community := rg.Group("/communities")
-	community.Use(middleware.JWTAuth(secretJWT))
+	community.Use(authMiddleware)
 	{
-		community.GET("", communityHandler.GetAllCommunities)
-		community.POST("", communityHandler.CreateCommunity)
-		community.GET("/:id", communityHandler.GetCommunityByID)
-		community.POST("/:id/join", communityHandler.JoinCommunity)
-		community.PUT("/:id/members/:userId/promote", communityHandler.PromoteMember)
-		community.PUT("/:id/members/:userId/demote", communityHandler.DemoteMember)
-		community.POST("/upload-banner", communityHandler.UploadBanner)
+		community.GET("", read, communityHandler.GetAllCommunities)
+		community.POST("", write, communityHandler.CreateCommunity)
+		community.GET("/:id", read, communityHandler.GetCommunityByID)
+		community.POST("/:id/join", write, communityHandler.JoinCommunity)
+		community.PUT("/:id/members/:userId/promote", write, communityHandler.PromoteMember)
+		community.PUT("/:id/members/:userId/demote", write, communityHandler.DemoteMember)
+		community.POST("/upload-banner", write, communityHandler.UploadBanner)
 	}
 }
@@ -6,18 +6,31 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// RateLimits are the rate-limiting middlewares built in main from
+// configuration.
+type RateLimits struct {
+	// Auth limits unauthenticated auth endpoints per IP.
+	Auth     gin.HandlerFunc
+	Register gin.HandlerFunc
+	// CreateQuiz and CreateComment limit content creation per user.
+	CreateQuiz    gin.HandlerFunc
+	CreateComment gin.HandlerFunc
+}
+
 func SetupRoutes(router *gin.Engine,
 	authHandler *handlers.AuthHandler,
 	userHandler *handlers.UserHandler,
+	accessTokenHandler *handlers.AccessTokenHandler,
 	communityHandler *handlers.CommunityHandler,
 	quizHandler *handlers.QuizHandler,
 	commentHandler *handlers.CommentHandler,
-	jwtsecret string,
+	authMiddleware gin.HandlerFunc,
+	limits RateLimits,
 ) {
 	api := router.Group("/api")
-	AuthRoutes(api, authHandler)
-	UserRoutes(api, userHandler, jwtsecret)
-	CommunityRoutes(api, communityHandler, jwtsecret)
-	QuizRoutes(api, quizHandler, jwtsecret)
-	CommentRoutes(api, commentHandler, jwtsecret)
+	AuthRoutes(api, authHandler, authMiddleware, limits)
+	UserRoutes(api, userHandler, accessTokenHandler, authMiddleware)
+	CommunityRoutes(api, communityHandler, authMiddleware)
+	QuizRoutes(api, quizHandler, authMiddleware, limits)
+	CommentRoutes(api, commentHandler, authMiddleware, limits)
 }
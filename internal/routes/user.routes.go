@@ -3,18 +3,33 @@ package routes
 import (
 	"ecoquiz/internal/handlers"
 	middleware "ecoquiz/internal/middlewares"
+	"ecoquiz/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(rg *gin.RouterGroup, userHandler *handlers.UserHandler, jwtSecret string) {
+func UserRoutes(rg *gin.RouterGroup, userHandler *handlers.UserHandler, accessTokenHandler *handlers.AccessTokenHandler, authMiddleware gin.HandlerFunc) {
+	read := middleware.RequireScope(models.ScopeProfileRead)
+	write := middleware.RequireScope(models.ScopeProfileWrite)
+
 	users := rg.Group("/users")
-	users.Use(middleware.JWTAuth(jwtSecret))
+	users.Use(authMiddleware)
+	{
+		users.GET("/me", read, userHandler.Profile)
+		users.PUT("/me", write, userHandler.UpdateUser)
+		users.PUT("/me/avatar", write, userHandler.UpdateAvatar)
+		users.PUT("/me/banner", write, userHandler.UpdateBanner)
+		users.DELETE("/me", middleware.RequireSession(), userHandler.DeleteAccount)
+		users.DELETE("/me/deletion", middleware.RequireSession(), userHandler.CancelDeletion)
+		users.GET("/me/export", middleware.RequireSession(), userHandler.ExportData)
+		users.GET("/:userID", read, userHandler.GetUser)
+	}
+
+	tokens := users.Group("/me/tokens")
+	tokens.Use(middleware.RequireSession())
 	{
-		users.GET("/me", userHandler.Profile)
-		users.PUT("/me", userHandler.UpdateUser)
-		users.PUT("/me/avatar", userHandler.UpdateAvatar)
-		users.PUT("/me/banner", userHandler.UpdateBanner)
-		users.GET("/:userID", userHandler.GetUser)
+		tokens.GET("", accessTokenHandler.ListTokens)
+		tokens.POST("", accessTokenHandler.CreateToken)
+		tokens.DELETE("/:tokenID", accessTokenHandler.RevokeToken)
 	}
 }
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	dto_user "ecoquiz/internal/dto/user"
+	"ecoquiz/internal/models"
+	"ecoquiz/internal/repos"
+	sharedErrors "ecoquiz/internal/shared/errors"
+	"ecoquiz/internal/utils"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Characters of the plaintext token kept for display so users can tell their
+// tokens apart without the secret being stored.
+const accessTokenDisplayLength = 12
+
+type AccessTokenService struct {
+	accessTokenRepo repos.AccessTokenRepo
+}
+
+func NewAccessTokenService(accessTokenRepo repos.AccessTokenRepo) *AccessTokenService {
+	return &AccessTokenService{accessTokenRepo: accessTokenRepo}
+}
+
+func (s *AccessTokenService) CreateToken(ctx context.Context, userID string, req *dto_user.CreateAccessTokenRequest) (*dto_user.CreatedAccessToken, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidBody, "token name is required")
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.AccessTokenScopes, scope) {
+			return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidScope, "unknown scope: "+scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	plain := utils.PersonalAccessTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: plain[:accessTokenDisplayLength],
+		TokenHash:   utils.HashToken(plain),
+		Scopes:      scopes,
+	}
+	if req.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.accessTokenRepo.Create(ctx, &token); err != nil {
+		if isUniqueViolation(err) {
+			return nil, sharedErrors.Conflict(sharedErrors.ErrAccessTokenNameTaken, "a token with this name already exists")
+		}
+		return nil, errors.New("failed to create token")
+	}
+
+	return &dto_user.CreatedAccessToken{PersonalAccessToken: token, Token: plain}, nil
+}
+
+func (s *AccessTokenService) ListTokens(ctx context.Context, userID string) ([]models.PersonalAccessToken, error) {
+	tokens, err := s.accessTokenRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to get tokens")
+	}
+	return tokens, nil
+}
+
+func (s *AccessTokenService) RevokeToken(ctx context.Context, userID, tokenID string) error {
+	err := s.accessTokenRepo.Delete(ctx, userID, tokenID)
+	if err == pgx.ErrNoRows {
+		return sharedErrors.NotFound(sharedErrors.ErrAccessTokenNotFound, "token not found")
+	}
+	if err != nil {
+		return errors.New("failed to revoke token")
+	}
+	return nil
+}
+
+// Authenticate implements middleware.AccessTokenAuthenticator.
+func (s *AccessTokenService) Authenticate(ctx context.Context, plain string) (string, []string, error) {
+	token, err := s.accessTokenRepo.FindByHash(ctx, utils.HashToken(plain))
+	if err == pgx.ErrNoRows {
+		return "", nil, sharedErrors.Unauthorized(sharedErrors.ErrInvalidToken, "invalid token")
+	}
+	if err != nil {
+		return "", nil, errors.New("failed to check token")
+	}
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		return "", nil, sharedErrors.Unauthorized(sharedErrors.ErrInvalidToken, "token expired")
+	}
+
+	if err := s.accessTokenRepo.Touch(ctx, token.ID); err != nil {
+		log.Printf("failed to record use of access token %s: %v", token.ID, err)
+	}
+	return token.UserID, token.Scopes, nil
+}
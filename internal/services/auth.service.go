@@ -4,81 +4,789 @@ import (
 	"context"
 	"database/sql"
 
+	"ecoquiz/internal/mailer"
 	"ecoquiz/internal/models"
+	"ecoquiz/internal/ratelimit"
 	"ecoquiz/internal/repos"
+	sharedErrors "ecoquiz/internal/shared/errors"
 	"ecoquiz/internal/utils"
 	"errors"
+	"fmt"
+	"log"
 	"math/rand"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const refreshTokenBytes = 32
+
+// reauthWindow is how recent a sign-in must be to stand in for a password
+// on accounts that do not have one.
+const reauthWindow = 10 * time.Minute
+
+const (
+	googleLinkPurpose       = "google_link"
+	googleLinkIntentPurpose = "google_link_intent"
+	linkTokenTTL            = 10 * time.Minute
 )
 
 type AuthService struct {
-	userRepo  repos.UserRepo
-	jwtSecret string
+	userRepo      repos.UserRepo
+	sessionRepo   repos.SessionRepo
+	identityRepo  repos.IdentityRepo
+	userTokenRepo repos.UserTokenRepo
+	twoFactorRepo repos.TwoFactorRepo
+	mailer        mailer.Mailer
+	hasher        *utils.PasswordHasher
+	secretBox     *utils.SecretBox
+	lockout       *ratelimit.Lockout
+	settings      AuthSettings
+}
+
+// AuthSettings groups the secrets and lifetimes the auth flows depend on.
+type AuthSettings struct {
+	JwtSecret        string
+	AccessTTL        time.Duration
+	RefreshTTL       time.Duration
+	EmailVerifyTTL   time.Duration
+	PasswordResetTTL time.Duration
+	// ClientURL is used to build the links sent by email.
+	ClientURL string
+	// TOTPIssuer is the account label shown in authenticator apps.
+	TOTPIssuer string
+	// PasswordPolicy applies to every new password: register, reset and change.
+	PasswordPolicy utils.PasswordPolicy
+}
+
+// AuthTokens is the pair handed to the client after any successful sign-in.
+type AuthTokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
 }
 
-func NewAuthService(userRepo repos.UserRepo, jwtSecret string) *AuthService {
+// SessionMeta describes the client a session is created for.
+type SessionMeta struct {
+	UserAgent string
+	IPAddress string
+}
+
+// GoogleSignInResult holds either a sign-in result or, when the Google email
+// belongs to an existing password account, a LinkToken the user must confirm
+// with their password before the Google identity is attached.
+type GoogleSignInResult struct {
+	SignInResult
+	LinkToken string
+}
+
+func NewAuthService(
+	userRepo repos.UserRepo,
+	sessionRepo repos.SessionRepo,
+	identityRepo repos.IdentityRepo,
+	userTokenRepo repos.UserTokenRepo,
+	twoFactorRepo repos.TwoFactorRepo,
+	mailer mailer.Mailer,
+	hasher *utils.PasswordHasher,
+	secretBox *utils.SecretBox,
+	lockout *ratelimit.Lockout,
+	settings AuthSettings,
+) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		identityRepo:  identityRepo,
+		userTokenRepo: userTokenRepo,
+		twoFactorRepo: twoFactorRepo,
+		mailer:        mailer,
+		hasher:        hasher,
+		secretBox:     secretBox,
+		lockout:       lockout,
+		settings:      settings,
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, username string, email string, password string) (string, error) {
+func (s *AuthService) Register(ctx context.Context, username string, email string, password string, meta SessionMeta) (*AuthTokens, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrEmailRequired, "email is required")
+	}
+	if err := s.checkPasswordPolicy(password, username, email); err != nil {
+		return nil, err
+	}
 	if _, err := s.userRepo.FindByEmail(ctx, email); err == nil {
-		return "", errors.New("user already exists")
+		return nil, sharedErrors.Conflict(sharedErrors.ErrEmailExists, "user already exists")
+	}
+	passwordHash, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
 	}
-	rand.Seed(time.Now().UnixNano())
-	GoogleID := strconv.Itoa(rand.Int())
 
 	var user = &models.User{
 		Username:     username,
 		Email:        email,
-		PasswordHash: sql.NullString{String: password, Valid: true},
-		GoogleID:     GoogleID,
+		PasswordHash: sql.NullString{String: passwordHash, Valid: true},
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
-		return "", errors.New("Failed to Register" + err.Error())
+		return nil, errors.New("Failed to Register" + err.Error())
 	}
-	return utils.GenerateToken(user.Email, user.ID, s.jwtSecret)
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("failed to send verification email to user %s: %v", user.ID, err)
+	}
+	return s.startSession(ctx, user, meta)
 }
 
-func (s *AuthService) Login(ctx context.Context, email string, password string) (string, error) {
+func (s *AuthService) Login(ctx context.Context, email string, password string, meta SessionMeta) (*SignInResult, error) {
+	// Failures are counted per email whether or not the account exists, so
+	// the lockout does not reveal which emails are registered.
+	key := "login:" + strings.ToLower(email)
+	if err := s.checkLockout(ctx, key); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err == nil && s.checkPassword(ctx, user, password) {
+		s.clearFailures(ctx, key)
+		return s.beginSignIn(ctx, user, meta)
+	}
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, errors.New("failed to get user")
+	}
+	if lockErr := s.recordFailure(ctx, key); lockErr != nil {
+		return nil, lockErr
+	}
+	return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "invalid email or password")
+}
+
+// ChangePassword replaces the password of a signed-in user after checking the
+// current one, and signs out every other session.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return errors.New("failed to get user")
+	}
+	if !user.PasswordHash.Valid {
+		return sharedErrors.BadRequest(sharedErrors.ErrInvalidBody, "this account has no password, use forgot password to set one")
+	}
+
+	key := "login:" + strings.ToLower(user.Email)
+	if err := s.checkLockout(ctx, key); err != nil {
+		return err
+	}
+	if !s.checkPassword(ctx, user, currentPassword) {
+		if lockErr := s.recordFailure(ctx, key); lockErr != nil {
+			return lockErr
+		}
+		return sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "current password is incorrect")
+	}
+	s.clearFailures(ctx, key)
+
+	if err := s.checkPasswordPolicy(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+	passwordHash, err := s.hasher.Hash(newPassword)
 	if err != nil {
-		return "", errors.New("invalid email or password" + err.Error())
+		return errors.New("failed to hash password")
 	}
-	if password != user.PasswordHash.String {
-		return "", errors.New("invalid email or password")
+	if err := s.userRepo.UpdatePassword(ctx, passwordHash, userID); err != nil {
+		return errors.New("failed to change password")
 	}
-	return utils.GenerateToken(user.Email, user.ID, s.jwtSecret)
+	if err := s.sessionRepo.RevokeOthers(ctx, userID, sessionID); err != nil {
+		return errors.New("failed to revoke other sessions")
+	}
+	return nil
 }
 
-func (s *AuthService) GoogleHandle(ctx context.Context, claims jwt.MapClaims) (string, error) {
-	email := claims["email"].(string)
+func (s *AuthService) checkPasswordPolicy(password, username, email string) error {
+	if err := s.settings.PasswordPolicy.Check(password, username, email); err != nil {
+		return sharedErrors.BadRequest(sharedErrors.ErrWeakPassword, err.Error())
+	}
+	return nil
+}
 
-	user, err := s.userRepo.FindByEmail(ctx, email)
+// Reauthenticate confirms that the caller is the account owner before a
+// destructive action. Password accounts must enter their password; accounts
+// without one must have signed in within reauthWindow. A two-factor code is
+// required on top when two-factor authentication is enabled.
+func (s *AuthService) Reauthenticate(ctx context.Context, userID, sessionID, password, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			user = &models.User{
-				Username: claims["name"].(string),
-				Email:    email,
-				GoogleID: claims["sub"].(string),
-				PasswordHash: sql.NullString{String:  "" , Valid: false},
+		return errors.New("failed to get user")
+	}
+
+	if user.PasswordHash.Valid {
+		key := "login:" + strings.ToLower(user.Email)
+		if err := s.checkLockout(ctx, key); err != nil {
+			return err
+		}
+		if !s.checkPassword(ctx, user, password) {
+			if lockErr := s.recordFailure(ctx, key); lockErr != nil {
+				return lockErr
 			}
+			return sharedErrors.Unauthorized(sharedErrors.ErrReauthRequired, "invalid password")
+		}
+		s.clearFailures(ctx, key)
+	} else {
+		session, err := s.sessionRepo.FindByID(ctx, sessionID)
+		if err != nil || time.Since(session.CreatedAt) > reauthWindow {
+			return sharedErrors.Unauthorized(sharedErrors.ErrReauthRequired, "sign in again to confirm this action")
+		}
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+	if code == "" {
+		return sharedErrors.Unauthorized(sharedErrors.ErrReauthRequired, "two-factor code is required")
+	}
+	totp, err := s.confirmedTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.checkSecondFactor(ctx, totp, code)
+}
+
+// checkLockout returns an error while key is locked out. Store errors are
+// logged and ignored so an unavailable store does not block sign-in.
+func (s *AuthService) checkLockout(ctx context.Context, key string) error {
+	wait, err := s.lockout.Check(ctx, key)
+	if err != nil {
+		log.Printf("lockout check failed for %s: %v", key, err)
+		return nil
+	}
+	if wait > 0 {
+		return sharedErrors.TooManyRequests(sharedErrors.ErrAccountLocked, "too many failed attempts, try again later", wait)
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt and returns an error if it triggered
+// a lockout.
+func (s *AuthService) recordFailure(ctx context.Context, key string) error {
+	wait, err := s.lockout.Fail(ctx, key)
+	if err != nil {
+		log.Printf("lockout update failed for %s: %v", key, err)
+		return nil
+	}
+	if wait > 0 {
+		return sharedErrors.TooManyRequests(sharedErrors.ErrAccountLocked, "too many failed attempts, try again later", wait)
+	}
+	return nil
+}
 
-			err := s.userRepo.Create(ctx, user)
-			if err != nil {
-				return "", err
+func (s *AuthService) clearFailures(ctx context.Context, key string) {
+	if err := s.lockout.Success(ctx, key); err != nil {
+		log.Printf("lockout reset failed for %s: %v", key, err)
+	}
+}
+
+// checkPassword verifies password against the user's stored hash and
+// transparently upgrades hashes written with older settings.
+func (s *AuthService) checkPassword(ctx context.Context, user *models.User, password string) bool {
+	if !user.PasswordHash.Valid {
+		return false
+	}
+	match, needsRehash := s.hasher.Verify(password, user.PasswordHash.String)
+	if !match {
+		return false
+	}
+	if needsRehash {
+		// The login already succeeded, so a failed upgrade is retried next time.
+		if hashed, err := s.hasher.Hash(password); err == nil {
+			if err := s.userRepo.UpdatePassword(ctx, hashed, user.ID); err != nil {
+				log.Printf("failed to rehash password for user %s: %v", user.ID, err)
 			}
-		} else {
-			return "", err
 		}
 	}
-	return utils.GenerateToken(user.Email, user.ID, s.jwtSecret)
+	return true
+}
+
+// GoogleHandle signs in the owner of a verified Google id_token. A known
+// Google identity logs straight in; an unknown one either creates a new
+// account or, if the email is already taken, asks for proof of ownership.
+func (s *AuthService) GoogleHandle(ctx context.Context, claims *utils.GoogleClaims, meta SessionMeta) (*GoogleSignInResult, error) {
+	identity, err := s.identityRepo.FindByProviderSubject(ctx, models.ProviderGoogle, claims.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, errors.New("failed to get user")
+		}
+		result, err := s.beginSignIn(ctx, user, meta)
+		if err != nil {
+			return nil, err
+		}
+		return &GoogleSignInResult{SignInResult: *result}, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, errors.New("failed to get identity")
+	}
+
+	if !claims.EmailVerified {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "google email is not verified")
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, claims.Email)
+	if err == nil {
+		if !user.PasswordHash.Valid {
+			return nil, sharedErrors.Conflict(sharedErrors.ErrEmailExists, "an account with this email already exists, sign in and link Google from your settings")
+		}
+		linkToken, err := utils.GeneratePurposeToken(googleLinkPurpose, user.ID, map[string]string{
+			"gsub": claims.Subject,
+			"gem":  claims.Email,
+		}, s.settings.JwtSecret, linkTokenTTL)
+		if err != nil {
+			return nil, errors.New("failed to generate link token")
+		}
+		return &GoogleSignInResult{LinkToken: linkToken}, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, errors.New("failed to get user")
+	}
+
+	username, err := s.availableUsername(ctx, claims.Name, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	verifiedAt := time.Now()
+	user = &models.User{
+		Username:        username,
+		Email:           claims.Email,
+		PasswordHash:    sql.NullString{Valid: false},
+		EmailVerifiedAt: &verifiedAt,
+	}
+	identity = &models.UserIdentity{
+		Provider:        models.ProviderGoogle,
+		ProviderSubject: claims.Subject,
+		Email:           claims.Email,
+	}
+	if err := s.userRepo.CreateWithIdentity(ctx, user, identity); err != nil {
+		return nil, errors.New("failed to create user: " + err.Error())
+	}
+	tokens, err := s.startSession(ctx, user, meta)
+	if err != nil {
+		return nil, err
+	}
+	return &GoogleSignInResult{SignInResult: SignInResult{Tokens: tokens}}, nil
+}
+
+// ConfirmGoogleLink attaches the Google identity carried by linkToken once the
+// user proves they own the existing account, then signs them in.
+func (s *AuthService) ConfirmGoogleLink(ctx context.Context, linkToken, password string, meta SessionMeta) (*SignInResult, error) {
+	claims, err := utils.ParsePurposeToken(linkToken, googleLinkPurpose, s.settings.JwtSecret)
+	if err != nil {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "link request expired, sign in with Google again")
+	}
+	subject, _ := claims["gsub"].(string)
+	email, _ := claims["gem"].(string)
+
+	user, err := s.userRepo.FindByID(ctx, claims["sub"].(string))
+	if err != nil {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "link request expired, sign in with Google again")
+	}
+	key := "login:" + strings.ToLower(user.Email)
+	if err := s.checkLockout(ctx, key); err != nil {
+		return nil, err
+	}
+	if !s.checkPassword(ctx, user, password) {
+		if lockErr := s.recordFailure(ctx, key); lockErr != nil {
+			return nil, lockErr
+		}
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "invalid password")
+	}
+	s.clearFailures(ctx, key)
+
+	if err := s.attachIdentity(ctx, user.ID, models.ProviderGoogle, subject, email); err != nil {
+		return nil, err
+	}
+	// Google has verified the same address the account was registered with.
+	if user.EmailVerifiedAt == nil && strings.EqualFold(email, user.Email) {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			log.Printf("failed to mark email verified for user %s: %v", user.ID, err)
+		}
+	}
+	return s.beginSignIn(ctx, user, meta)
+}
+
+// GoogleLinkIntent returns a token remembering which signed-in user started
+// the Google OAuth flow to attach an identity.
+func (s *AuthService) GoogleLinkIntent(userID string) (string, error) {
+	token, err := utils.GeneratePurposeToken(googleLinkIntentPurpose, userID, nil, s.settings.JwtSecret, linkTokenTTL)
+	if err != nil {
+		return "", errors.New("failed to generate link token")
+	}
+	return token, nil
+}
+
+func (s *AuthService) LinkGoogle(ctx context.Context, intentToken string, claims *utils.GoogleClaims) error {
+	intent, err := utils.ParsePurposeToken(intentToken, googleLinkIntentPurpose, s.settings.JwtSecret)
+	if err != nil {
+		return sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "link request expired, try again")
+	}
+	return s.attachIdentity(ctx, intent["sub"].(string), models.ProviderGoogle, claims.Subject, claims.Email)
+}
+
+func (s *AuthService) attachIdentity(ctx context.Context, userID, provider, subject, email string) error {
+	existing, err := s.identityRepo.FindByProviderSubject(ctx, provider, subject)
+	if err == nil {
+		if existing.UserID == userID {
+			return nil
+		}
+		return sharedErrors.Conflict(sharedErrors.ErrIdentityLinked, "this account is already linked to another user")
+	}
+	if err != pgx.ErrNoRows {
+		return errors.New("failed to get identity")
+	}
+
+	identity := &models.UserIdentity{
+		UserID:          userID,
+		Provider:        provider,
+		ProviderSubject: subject,
+		Email:           email,
+	}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		if isUniqueViolation(err) {
+			return sharedErrors.Conflict(sharedErrors.ErrIdentityLinked, "a "+provider+" account is already linked")
+		}
+		return errors.New("failed to link identity")
+	}
+	return nil
+}
+
+func (s *AuthService) ListIdentities(ctx context.Context, userID string) ([]models.UserIdentity, error) {
+	identities, err := s.identityRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to get identities")
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity detaches a provider as long as the user keeps another way
+// to sign in.
+func (s *AuthService) UnlinkIdentity(ctx context.Context, userID, provider string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return sharedErrors.NotFound(sharedErrors.ErrUserNotFound, "user not found")
+	}
+	identities, err := s.identityRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return errors.New("failed to get identities")
+	}
+
+	linked := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			linked = true
+		}
+	}
+	if !linked {
+		return sharedErrors.NotFound(sharedErrors.ErrIdentityNotFound, provider+" is not linked")
+	}
+	if !user.PasswordHash.Valid && len(identities) == 1 {
+		return sharedErrors.BadRequest(sharedErrors.ErrLastSignInMethod, "set a password before unlinking your only sign-in method")
+	}
+
+	if err := s.identityRepo.Delete(ctx, userID, provider); err != nil {
+		return errors.New("failed to unlink identity")
+	}
+	return nil
+}
+
+// ResendVerificationEmail sends a fresh verification link; earlier links stop
+// working.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, userID string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return sharedErrors.NotFound(sharedErrors.ErrUserNotFound, "user not found")
+	}
+	if user.EmailVerifiedAt != nil {
+		return sharedErrors.BadRequest(sharedErrors.ErrEmailAlreadyVerified, "email is already verified")
+	}
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		return errors.New("failed to send verification email")
+	}
+	return nil
+}
+
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	userToken, err := s.userTokenRepo.Consume(ctx, models.TokenPurposeVerifyEmail, utils.HashToken(token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return sharedErrors.BadRequest(sharedErrors.ErrInvalidToken, "verification link is invalid or expired")
+		}
+		return errors.New("failed to verify email")
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, userToken.UserID); err != nil {
+		return errors.New("failed to verify email")
+	}
+	return nil
+}
+
+// ForgotPassword emails a reset link when the address belongs to an account.
+// It reports success either way so it cannot be used to discover accounts.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			log.Printf("failed to look up user for password reset: %v", err)
+		}
+		return nil
+	}
+
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposeResetPassword, s.settings.PasswordResetTTL)
+	if err != nil {
+		log.Printf("failed to create password reset token for user %s: %v", user.ID, err)
+		return nil
+	}
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your EcoQuiz password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Username, s.settings.PasswordResetTTL, s.settings.ClientURL, url.QueryEscape(token),
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("failed to send password reset email to user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password from a reset link and signs the user out
+// everywhere, since the old password may have been compromised.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	tokenHash := utils.HashToken(token)
+
+	// Validate the new password before spending the token so a rejected
+	// password can be retried with the same link.
+	pending, err := s.userTokenRepo.FindValid(ctx, models.TokenPurposeResetPassword, tokenHash)
+	if err == pgx.ErrNoRows {
+		return sharedErrors.BadRequest(sharedErrors.ErrInvalidToken, "reset link is invalid or expired")
+	}
+	if err != nil {
+		return errors.New("failed to reset password")
+	}
+	user, err := s.userRepo.FindByID(ctx, pending.UserID)
+	if err != nil {
+		return errors.New("failed to get user")
+	}
+	if err := s.checkPasswordPolicy(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	userToken, err := s.userTokenRepo.Consume(ctx, models.TokenPurposeResetPassword, tokenHash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return sharedErrors.BadRequest(sharedErrors.ErrInvalidToken, "reset link is invalid or expired")
+		}
+		return errors.New("failed to reset password")
+	}
+
+	passwordHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	if err := s.userRepo.UpdatePassword(ctx, passwordHash, userToken.UserID); err != nil {
+		return errors.New("failed to reset password")
+	}
+	if err := s.sessionRepo.RevokeAllForUser(ctx, userToken.UserID); err != nil {
+		return errors.New("failed to revoke sessions")
+	}
+	// Receiving the reset email proves the address works.
+	if err := s.userRepo.MarkEmailVerified(ctx, userToken.UserID); err != nil {
+		log.Printf("failed to mark email verified for user %s: %v", userToken.UserID, err)
+	}
+	return nil
+}
+
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposeVerifyEmail, s.settings.EmailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your EcoQuiz email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email address by opening the link below. It expires in %s.\n\n%s/verify-email?token=%s\n",
+			user.Username, s.settings.EmailVerifyTTL, s.settings.ClientURL, url.QueryEscape(token),
+		),
+	})
+}
+
+// issueUserToken invalidates earlier tokens of the same purpose and stores
+// the hash of a new single-use token, returning the raw value for the link.
+func (s *AuthService) issueUserToken(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	if err := s.userTokenRepo.InvalidateForUser(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	userToken := &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.userTokenRepo.Create(ctx, userToken); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// availableUsername derives a unique username from the provider profile name,
+// falling back to the email local part.
+func (s *AuthService) availableUsername(ctx context.Context, name, email string) (string, error) {
+	base := sanitizeUsername(name)
+	if base == "" {
+		base = sanitizeUsername(strings.SplitN(email, "@", 2)[0])
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		exists, err := s.userRepo.UsernameExists(ctx, candidate)
+		if err != nil {
+			return "", errors.New("failed to check username")
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = base + strconv.Itoa(1000+rand.Intn(9000))
+	}
+	return "", errors.New("failed to pick a username")
+}
+
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.':
+			b.WriteRune(r)
+		case r == ' ' || r == '-':
+			b.WriteRune('_')
+		}
+	}
+	username := b.String()
+	if len(username) > 40 {
+		username = username[:40]
+	}
+	return username
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// Refresh exchanges a refresh token for a new token pair. The refresh token
+// is rotated on every use, so a leaked token stops working as soon as the
+// legitimate client refreshes.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, meta SessionMeta) (*AuthTokens, error) {
+	if refreshToken == "" {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "refresh token not found")
+	}
+	oldHash := utils.HashToken(refreshToken)
+	session, err := s.sessionRepo.FindByRefreshHash(ctx, oldHash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "invalid refresh token")
+		}
+		return nil, errors.New("failed to get session")
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "session expired")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, session.UserID)
+	if err != nil {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "invalid refresh token")
+	}
+
+	newRefresh, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+	refreshExpiresAt := time.Now().Add(s.settings.RefreshTTL)
+	if err := s.sessionRepo.Rotate(ctx, session.ID, oldHash, utils.HashToken(newRefresh), refreshExpiresAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sharedErrors.Unauthorized(sharedErrors.ErrUnauthorized, "invalid refresh token")
+		}
+		return nil, errors.New("failed to rotate session")
+	}
+
+	return s.issueTokens(user, session.ID, newRefresh, refreshExpiresAt)
+}
+
+// Logout revokes the session the refresh token belongs to. Unknown tokens are
+// ignored so logging out is always safe to call.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string, sessionID string) error {
+	if refreshToken != "" {
+		session, err := s.sessionRepo.FindByRefreshHash(ctx, utils.HashToken(refreshToken))
+		if err == nil {
+			sessionID = session.ID
+		} else if err != pgx.ErrNoRows {
+			return errors.New("failed to get session")
+		}
+	}
+	if sessionID == "" {
+		return nil
+	}
+	if err := s.sessionRepo.Revoke(ctx, sessionID); err != nil {
+		return errors.New("failed to revoke session")
+	}
+	return nil
+}
+
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) error {
+	if err := s.sessionRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return errors.New("failed to revoke sessions")
+	}
+	return nil
+}
+
+func (s *AuthService) startSession(ctx context.Context, user *models.User, meta SessionMeta) (*AuthTokens, error) {
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		UserAgent:        meta.UserAgent,
+		IPAddress:        meta.IPAddress,
+		ExpiresAt:        time.Now().Add(s.settings.RefreshTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	return s.issueTokens(user, session.ID, refreshToken, session.ExpiresAt)
+}
+
+func (s *AuthService) issueTokens(user *models.User, sessionID, refreshToken string, refreshExpiresAt time.Time) (*AuthTokens, error) {
+	accessToken, err := utils.GenerateToken(user.Email, user.ID, sessionID, s.settings.JwtSecret, s.settings.AccessTTL)
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+	return &AuthTokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  time.Now().Add(s.settings.AccessTTL),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
 }
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+)
+
+// CSV has one question per row under a header row. Columns may come in any
+// order and only question is required; every column named option (option_1,
+// option 2, ...) holds one option:
+//
+//   - single_choice, multiple_choice: options, correct ones starting with *
+//   - short_answer: answer, with other accepted answers as options
+//   - ordering: options in the correct order
+//   - matching: options written as "item => match"
+//
+// points and negative_points may be left empty for 1 and 0. Quiz settings,
+// pools and numeric unit conversions are not carried.
+type csvFormat struct{}
+
+var csvColumns = []string{"type", "question", "answer", "explanation", "tag", "difficulty", "scoring", "tolerance", "unit", "max_edit_distance", "points", "negative_points"}
+
+const csvMatchSeparator = " => "
+
+func (csvFormat) contentType() string { return "text/csv" }
+func (csvFormat) extension() string   { return "csv" }
+
+func (csvFormat) encode(quiz *dto_quiz.ExportedQuiz) ([]byte, error) {
+	maxOptions := 0
+	for _, q := range quiz.Questions {
+		maxOptions = max(maxOptions, len(q.Options), len(q.Settings.AcceptedAnswers))
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := append([]string(nil), csvColumns...)
+	for i := 1; i <= maxOptions; i++ {
+		header = append(header, "option_"+strconv.Itoa(i))
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, q := range quiz.Questions {
+		row := []string{
+			q.QuestionType,
+			q.QuestionText,
+			q.CorrectAnswer,
+			q.Explanation,
+			q.Tag,
+			q.Difficulty,
+			q.ScoringStrategy,
+			formatFloat(q.Settings.Tolerance),
+			q.Settings.Unit,
+			"",
+			formatFloat(q.Points),
+			formatFloat(q.NegativePoints),
+		}
+		if q.Settings.MaxEditDistance > 0 {
+			row[9] = strconv.Itoa(q.Settings.MaxEditDistance)
+		}
+
+		switch q.QuestionType {
+		case models.QuestionShortAnswer:
+			row = append(row, q.Settings.AcceptedAnswers...)
+		case models.QuestionMatching:
+			for _, o := range q.Options {
+				match := ""
+				if o.MatchText != nil {
+					match = *o.MatchText
+				}
+				row = append(row, o.Text+csvMatchSeparator+match)
+			}
+		case models.QuestionOrdering:
+			for _, o := range q.Options {
+				row = append(row, o.Text)
+			}
+		default:
+			for _, o := range q.Options {
+				text := o.Text
+				if strings.HasPrefix(text, "*") || strings.HasPrefix(text, `\`) {
+					text = `\` + text
+				}
+				if o.IsCorrect {
+					text = "*" + text
+				}
+				row = append(row, text)
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func (csvFormat) decode(data []byte) parsedQuiz {
+	var parsed parsedQuiz
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		parsed.fail(csvErrorSource(err), "cannot read the header row: "+err.Error())
+		return parsed
+	}
+	columns := make([]string, len(header))
+	hasQuestion := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch {
+		case strings.HasPrefix(name, "option"):
+			name = "option"
+		case name == "question":
+			hasQuestion = true
+		case !isCSVColumn(name):
+			parsed.fail(source{line: 1}, "unknown column "+strconv.Quote(header[i]))
+		}
+		columns[i] = name
+	}
+	if !hasQuestion {
+		parsed.fail(source{line: 1}, "the header row needs a question column")
+		return parsed
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			parsed.fail(csvErrorSource(err), err.Error())
+			return parsed
+		}
+		line, _ := r.FieldPos(0)
+		at := source{line: line}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		var q dto_quiz.Question
+		var cells []string
+		for i, value := range record {
+			if i >= len(columns) {
+				parsed.fail(at, "row has more cells than the header has columns")
+				break
+			}
+			switch columns[i] {
+			case "type":
+				q.QuestionType = strings.TrimSpace(value)
+			case "question":
+				q.QuestionText = value
+			case "answer":
+				q.CorrectAnswer = value
+			case "explanation":
+				q.Explanation = value
+			case "tag":
+				q.Tag = strings.TrimSpace(value)
+			case "difficulty":
+				q.Difficulty = strings.TrimSpace(value)
+			case "scoring":
+				q.ScoringStrategy = strings.TrimSpace(value)
+			case "tolerance":
+				if value = strings.TrimSpace(value); value != "" {
+					tolerance, err := strconv.ParseFloat(value, 64)
+					if err != nil {
+						parsed.fail(at, "tolerance "+strconv.Quote(value)+" is not a number")
+					}
+					q.Settings.Tolerance = tolerance
+				}
+			case "unit":
+				q.Settings.Unit = strings.TrimSpace(value)
+			case "points", "negative_points":
+				if value = strings.TrimSpace(value); value != "" {
+					points, err := strconv.ParseFloat(value, 64)
+					if err != nil {
+						parsed.fail(at, columns[i]+" "+strconv.Quote(value)+" is not a number")
+					}
+					if columns[i] == "points" {
+						q.Points = points
+					} else {
+						q.NegativePoints = points
+					}
+				}
+			case "max_edit_distance":
+				if value = strings.TrimSpace(value); value != "" {
+					distance, err := strconv.Atoi(value)
+					if err != nil {
+						parsed.fail(at, "max_edit_distance "+strconv.Quote(value)+" is not a whole number")
+					}
+					q.Settings.MaxEditDistance = distance
+				}
+			case "option":
+				if value != "" {
+					cells = append(cells, value)
+				}
+			}
+		}
+
+		switch q.QuestionType {
+		case models.QuestionShortAnswer:
+			q.Settings.AcceptedAnswers = cells
+		case models.QuestionMatching:
+			for _, cell := range cells {
+				text, match, ok := strings.Cut(cell, csvMatchSeparator)
+				if !ok {
+					parsed.fail(at, "matching option "+strconv.Quote(cell)+" needs the form \"item => match\"")
+					continue
+				}
+				match = strings.TrimSpace(match)
+				q.Options = append(q.Options, dto_quiz.Option{Text: strings.TrimSpace(text), MatchText: &match})
+			}
+		case models.QuestionOrdering:
+			for _, cell := range cells {
+				q.Options = append(q.Options, dto_quiz.Option{Text: cell})
+			}
+		default:
+			for _, cell := range cells {
+				text := strings.TrimSpace(cell)
+				var option dto_quiz.Option
+				if strings.HasPrefix(text, "*") {
+					option.IsCorrect = true
+					text = strings.TrimSpace(text[1:])
+				}
+				option.Text = strings.TrimPrefix(text, `\`)
+				q.Options = append(q.Options, option)
+			}
+		}
+		parsed.addQuestion(q, at)
+	}
+	return parsed
+}
+
+func isCSVColumn(name string) bool {
+	for _, c := range csvColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func csvErrorSource(err error) source {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return source{line: parseErr.Line}
+	}
+	return source{line: 1}
+}
+
+func formatFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
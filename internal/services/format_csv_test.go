@@ -0,0 +1,103 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+)
+
+func TestCSVFormatRoundTrip(t *testing.T) {
+	quiz := sampleQuiz()
+	parsed := roundTrip(t, csvFormat{}, quiz)
+
+	if parsed.quiz.Title != "" || parsed.quiz.Pools != nil {
+		t.Fatalf("decode() carried quiz settings: %+v", parsed.quiz)
+	}
+	// Every question is carried but for numeric unit conversions.
+	checkQuestions(t, parsed.quiz.Questions, quiz.Questions, func(q dto_quiz.Question) dto_quiz.Question {
+		q.Settings.UnitFactors = nil
+		return q
+	})
+	// One row per question under the header.
+	for i, at := range parsed.sources {
+		if at.line != i+2 {
+			t.Errorf("question %d read from line %d, want %d", i+1, at.line, i+2)
+		}
+	}
+}
+
+func TestCSVFormatDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantLines []int
+		wantMsg   string
+		wantRead  int
+	}{
+		{
+			name:      "unknown column",
+			data:      "question,colour\nWhat?,red\n",
+			wantLines: []int{1},
+			wantMsg:   `unknown column "colour"`,
+			wantRead:  1,
+		},
+		{
+			name:      "no question column",
+			data:      "type,answer\ntrue_false,true\n",
+			wantLines: []int{1},
+			wantMsg:   "needs a question column",
+		},
+		{
+			name:      "lines counted across quoted line breaks",
+			data:      "type,question,tolerance\nnumeric,\"Line one\nline two\",0.5\nnumeric,Third,abc\n",
+			wantLines: []int{4},
+			wantMsg:   `tolerance "abc" is not a number`,
+			wantRead:  2,
+		},
+		{
+			name:      "blank rows still count",
+			data:      "question,points\nFirst,1\n,\n\nFourth,many\n",
+			wantLines: []int{5},
+			wantMsg:   `points "many" is not a number`,
+			wantRead:  2,
+		},
+		{
+			name:      "more cells than columns",
+			data:      "question,answer\nFirst,a\nSecond,b,extra\n",
+			wantLines: []int{3},
+			wantMsg:   "more cells than the header",
+			wantRead:  2,
+		},
+		{
+			name:      "matching option without a match",
+			data:      "type,question,option_1,option_2\nmatching,Pair them,Sun => Solar,Wind\n",
+			wantLines: []int{2},
+			wantMsg:   `matching option "Wind" needs the form`,
+			wantRead:  1,
+		},
+		{
+			name:      "unclosed quote",
+			data:      "question,answer\nFirst,a\n\"Second,b\nThird,c\n",
+			wantLines: []int{4},
+			wantMsg:   "record on line 3",
+			wantRead:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := csvFormat{}.decode([]byte(tt.data))
+			if got := issueLines(parsed.issues); !reflect.DeepEqual(got, tt.wantLines) {
+				t.Fatalf("decode() issues = %+v, want lines %v", parsed.issues, tt.wantLines)
+			}
+			if !strings.Contains(parsed.issues[0].Message, tt.wantMsg) {
+				t.Fatalf("decode() issue = %q, want %q", parsed.issues[0].Message, tt.wantMsg)
+			}
+			if len(parsed.quiz.Questions) != tt.wantRead {
+				t.Fatalf("decode() read %d questions, want %d", len(parsed.quiz.Questions), tt.wantRead)
+			}
+		})
+	}
+}
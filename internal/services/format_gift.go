@@ -0,0 +1,462 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+)
+
+// Moodle GIFT. Questions are separated by blank lines and $CATEGORY lines
+// carry the tag. GIFT has no ordering questions, so they are exported as
+// matching questions pairing each item with its position; difficulty,
+// numeric units and typo allowances are not carried. Essay and description
+// questions cannot be imported.
+type giftFormat struct{}
+
+func (giftFormat) contentType() string { return "text/plain; charset=utf-8" }
+func (giftFormat) extension() string   { return "gift" }
+
+func (giftFormat) encode(quiz *dto_quiz.ExportedQuiz) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("// " + giftComment(quiz.Title) + "\n")
+	if quiz.Description != "" {
+		b.WriteString("// " + giftComment(quiz.Description) + "\n")
+	}
+
+	category := ""
+	for i, q := range quiz.Questions {
+		b.WriteString("\n")
+		if q.Tag != category {
+			category = q.Tag
+			b.WriteString("$CATEGORY: " + category + "\n\n")
+		}
+		b.WriteString("::Q" + strconv.Itoa(i+1) + ":: " + giftEscape(q.QuestionText) + " {\n")
+
+		switch q.QuestionType {
+		case models.QuestionTrueFalse:
+			if q.CorrectAnswer == "true" {
+				b.WriteString("\tTRUE\n")
+			} else {
+				b.WriteString("\tFALSE\n")
+			}
+		case models.QuestionShortAnswer:
+			b.WriteString("\t=" + giftEscape(q.CorrectAnswer) + "\n")
+			for _, a := range q.Settings.AcceptedAnswers {
+				b.WriteString("\t=" + giftEscape(a) + "\n")
+			}
+		case models.QuestionNumeric:
+			answer := giftEscape(q.CorrectAnswer)
+			if q.Settings.Tolerance > 0 {
+				answer += ":" + formatFloat(q.Settings.Tolerance)
+			}
+			b.WriteString("\t#" + answer + "\n")
+		case models.QuestionMatching:
+			for _, o := range q.Options {
+				match := ""
+				if o.MatchText != nil {
+					match = *o.MatchText
+				}
+				b.WriteString("\t=" + giftEscape(o.Text) + " -> " + giftEscape(match) + "\n")
+			}
+		case models.QuestionOrdering:
+			for j, o := range q.Options {
+				b.WriteString("\t=" + giftEscape(o.Text) + " -> " + strconv.Itoa(j+1) + "\n")
+			}
+		case models.QuestionMultipleChoice:
+			correct := 0
+			for _, o := range q.Options {
+				if o.IsCorrect {
+					correct++
+				}
+			}
+			wrong := len(q.Options) - correct
+			for _, o := range q.Options {
+				if o.IsCorrect {
+					b.WriteString("\t~%" + giftWeight(100/float64(correct)) + "%" + giftEscape(o.Text) + "\n")
+				} else {
+					b.WriteString("\t~%-" + giftWeight(100/float64(wrong)) + "%" + giftEscape(o.Text) + "\n")
+				}
+			}
+		default:
+			for _, o := range q.Options {
+				marker := "~"
+				if o.IsCorrect {
+					marker = "="
+				}
+				b.WriteString("\t" + marker + giftEscape(o.Text) + "\n")
+			}
+		}
+
+		if q.Explanation != "" {
+			b.WriteString("\t####" + giftEscape(q.Explanation) + "\n")
+		}
+		b.WriteString("}\n")
+	}
+	return []byte(b.String()), nil
+}
+
+func (giftFormat) decode(data []byte) parsedQuiz {
+	var parsed parsedQuiz
+	category := ""
+
+	var block []string
+	blockStart := 0
+	flush := func() {
+		if len(block) > 0 {
+			q, err := giftQuestion(strings.Join(block, "\n"))
+			at := source{line: blockStart}
+			if err != "" {
+				parsed.fail(at, err)
+			} else {
+				q.Tag = category
+				parsed.addQuestion(q, at)
+			}
+		}
+		block = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxImportBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case strings.HasPrefix(trimmed, "//"):
+			continue
+		case trimmed == "":
+			flush()
+			continue
+		case len(block) == 0 && strings.HasPrefix(trimmed, "$CATEGORY:"):
+			category = giftCategory(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+			continue
+		}
+		if len(block) == 0 {
+			blockStart = line
+		}
+		block = append(block, text)
+	}
+	if err := scanner.Err(); err != nil {
+		parsed.fail(source{line: line + 1}, "cannot read the file: "+err.Error())
+	}
+	flush()
+	return parsed
+}
+
+// giftQuestion reads one question. A non-empty string is the reason it
+// could not be read.
+func giftQuestion(raw string) (dto_quiz.Question, string) {
+	var q dto_quiz.Question
+	t := newGiftText(raw)
+
+	open := t.index("{", 0)
+	if open < 0 {
+		return q, "no answer block: GIFT questions need their answers in { }"
+	}
+	end := t.index("}", open)
+	if end < 0 {
+		return q, "the answer block opened with { is never closed"
+	}
+
+	before := t.slice(0, open)
+	if title := before.trimmedStart(); before.hasPrefixAt("::", title) {
+		if close := before.index("::", title+2); close >= 0 {
+			before = before.slice(close+2, len(before.r))
+		}
+	}
+	text := stripGiftFormat(strings.TrimSpace(before.String()))
+	if after := strings.TrimSpace(t.slice(end+1, len(t.r)).String()); after != "" {
+		text += " _____ " + after
+	}
+	q.QuestionText = text
+
+	body := t.slice(open+1, end)
+	if feedback := body.index("####", 0); feedback >= 0 {
+		q.Explanation = strings.TrimSpace(body.slice(feedback+4, len(body.r)).String())
+		body = body.slice(0, feedback)
+	}
+
+	plain := strings.TrimSpace(body.String())
+	switch {
+	case plain == "":
+		return q, "essay questions are not supported"
+	case strings.HasPrefix(plain, "#"):
+		return giftNumeric(q, body)
+	}
+	if verdict, _, _ := strings.Cut(plain, "#"); isGiftBool(verdict) {
+		q.QuestionType = models.QuestionTrueFalse
+		q.CorrectAnswer = strconv.FormatBool(strings.HasPrefix(strings.ToUpper(strings.TrimSpace(verdict)), "T"))
+		return q, ""
+	}
+
+	answers := giftAnswers(body)
+	if len(answers) == 0 {
+		return q, "the answer block has no answers starting with = or ~"
+	}
+
+	allRight, matching, weighted := true, true, false
+	for _, a := range answers {
+		allRight = allRight && a.marker == '='
+		matching = matching && a.marker == '=' && strings.Contains(a.text, "->")
+		weighted = weighted || a.weight != nil
+	}
+
+	switch {
+	case matching:
+		q.QuestionType = models.QuestionMatching
+		for _, a := range answers {
+			item, match, _ := strings.Cut(a.text, "->")
+			match = strings.TrimSpace(match)
+			q.Options = append(q.Options, dto_quiz.Option{Text: strings.TrimSpace(item), MatchText: &match})
+		}
+	case allRight && !weighted:
+		q.QuestionType = models.QuestionShortAnswer
+		q.CorrectAnswer = answers[0].text
+		for _, a := range answers[1:] {
+			q.Settings.AcceptedAnswers = append(q.Settings.AcceptedAnswers, a.text)
+		}
+	default:
+		// Multiple-answer questions mark right answers with ~ and a positive
+		// weight instead of =.
+		correct, multiple := 0, false
+		for _, a := range answers {
+			right := a.marker == '=' || (a.weight != nil && *a.weight > 0)
+			if right {
+				correct++
+			}
+			if a.marker == '~' && right {
+				multiple = true
+			}
+			q.Options = append(q.Options, dto_quiz.Option{Text: a.text, IsCorrect: right})
+		}
+		q.QuestionType = models.QuestionSingleChoice
+		if correct > 1 || multiple {
+			q.QuestionType = models.QuestionMultipleChoice
+			q.ScoringStrategy = models.ScoringProportional
+		}
+	}
+	return q, ""
+}
+
+// giftNumeric reads {#value}, {#value:tolerance}, {#min..max}, or a list of
+// =value alternatives, of which the first worth full marks is used.
+func giftNumeric(q dto_quiz.Question, body giftText) (dto_quiz.Question, string) {
+	spec := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(body.String()), "#"))
+	if strings.HasPrefix(spec, "=") || strings.HasPrefix(spec, "~") {
+		spec = ""
+		for _, a := range giftAnswers(body.slice(body.index("#", 0)+1, len(body.r))) {
+			if a.marker == '=' && (a.weight == nil || *a.weight == 100) {
+				spec = a.text
+				break
+			}
+		}
+		if spec == "" {
+			return q, "numeric question has no answer worth full marks"
+		}
+	}
+	spec, _, _ = strings.Cut(spec, "#")
+	spec = strings.TrimSpace(spec)
+
+	q.QuestionType = models.QuestionNumeric
+	if low, high, ok := strings.Cut(spec, ".."); ok {
+		lo, errLo := strconv.ParseFloat(strings.TrimSpace(low), 64)
+		hi, errHi := strconv.ParseFloat(strings.TrimSpace(high), 64)
+		if errLo != nil || errHi != nil || hi < lo {
+			return q, "numeric range " + strconv.Quote(spec) + " is not min..max"
+		}
+		q.CorrectAnswer = formatFloat((lo + hi) / 2)
+		if q.CorrectAnswer == "" {
+			q.CorrectAnswer = "0"
+		}
+		q.Settings.Tolerance = (hi - lo) / 2
+		return q, ""
+	}
+
+	value, tolerance, _ := strings.Cut(spec, ":")
+	if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+		return q, "numeric answer " + strconv.Quote(value) + " is not a number"
+	}
+	q.CorrectAnswer = strings.TrimSpace(value)
+	if tolerance = strings.TrimSpace(tolerance); tolerance != "" {
+		t, err := strconv.ParseFloat(tolerance, 64)
+		if err != nil {
+			return q, "numeric tolerance " + strconv.Quote(tolerance) + " is not a number"
+		}
+		q.Settings.Tolerance = t
+	}
+	return q, ""
+}
+
+type giftAnswer struct {
+	marker rune
+	weight *float64
+	text   string
+}
+
+// giftAnswers splits an answer block at each unescaped = or ~, dropping
+// per-answer feedback.
+func giftAnswers(body giftText) []giftAnswer {
+	var answers []giftAnswer
+	start := -1
+	emit := func(end int) {
+		if start < 0 {
+			return
+		}
+		part := body.slice(start+1, end)
+		if feedback := part.index("#", 0); feedback >= 0 {
+			part = part.slice(0, feedback)
+		}
+		a := giftAnswer{marker: body.r[start], text: strings.TrimSpace(part.String())}
+		if strings.HasPrefix(a.text, "%") {
+			if w, rest, ok := strings.Cut(a.text[1:], "%"); ok {
+				if weight, err := strconv.ParseFloat(w, 64); err == nil {
+					a.weight = &weight
+					a.text = strings.TrimSpace(rest)
+				}
+			}
+		}
+		answers = append(answers, a)
+	}
+	for i, r := range body.r {
+		if !body.esc[i] && (r == '=' || r == '~') {
+			emit(i)
+			start = i
+		}
+	}
+	emit(len(body.r))
+	return answers
+}
+
+func isGiftBool(s string) bool {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "T", "TRUE", "F", "FALSE":
+		return true
+	}
+	return false
+}
+
+// giftCategory keeps the last part of a category path, which is what a tag
+// is here.
+func giftCategory(path string) string {
+	path = strings.TrimSpace(path)
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[i+1:]
+	}
+	if strings.HasPrefix(path, "$") {
+		return ""
+	}
+	return strings.TrimSpace(path)
+}
+
+// stripGiftFormat drops a leading [html], [plain], [markdown] or [moodle].
+func stripGiftFormat(text string) string {
+	for _, f := range []string{"[html]", "[plain]", "[markdown]", "[moodle]"} {
+		if strings.HasPrefix(strings.ToLower(text), f) {
+			return strings.TrimSpace(text[len(f):])
+		}
+	}
+	return text
+}
+
+func giftEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '~', '=', '#', '{', '}', ':':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func giftComment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func giftWeight(w float64) string {
+	return strconv.FormatFloat(float64(int(w*100000))/100000, 'f', -1, 64)
+}
+
+// giftText is GIFT source with backslash escapes resolved; esc marks the
+// characters that were escaped and so carry no meaning.
+type giftText struct {
+	r   []rune
+	esc []bool
+}
+
+func newGiftText(s string) giftText {
+	var t giftText
+	src := []rune(s)
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\\' && i+1 < len(src) {
+			i++
+			if src[i] == 'n' {
+				t.r = append(t.r, '\n')
+			} else {
+				t.r = append(t.r, src[i])
+			}
+			t.esc = append(t.esc, true)
+			continue
+		}
+		t.r = append(t.r, src[i])
+		t.esc = append(t.esc, false)
+	}
+	return t
+}
+
+// index finds sub made only of unescaped characters, starting at from.
+func (t giftText) index(sub string, from int) int {
+	want := []rune(sub)
+	for i := from; i+len(want) <= len(t.r); i++ {
+		if t.hasPrefixAt(sub, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t giftText) hasPrefixAt(sub string, at int) bool {
+	want := []rune(sub)
+	if at < 0 || at+len(want) > len(t.r) {
+		return false
+	}
+	for j, r := range want {
+		if t.r[at+j] != r || t.esc[at+j] {
+			return false
+		}
+	}
+	return true
+}
+
+// trimmedStart is the index of the first non-space character.
+func (t giftText) trimmedStart() int {
+	for i, r := range t.r {
+		if r != ' ' && r != '\t' && r != '\n' {
+			return i
+		}
+	}
+	return len(t.r)
+}
+
+func (t giftText) slice(i, j int) giftText {
+	return giftText{r: t.r[i:j], esc: t.esc[i:j]}
+}
+
+func (t giftText) String() string {
+	return string(t.r)
+}
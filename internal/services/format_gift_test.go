@@ -0,0 +1,91 @@
+package services
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+)
+
+func TestGIFTFormatRoundTrip(t *testing.T) {
+	quiz := sampleQuiz()
+	parsed := roundTrip(t, giftFormat{}, quiz)
+
+	if parsed.quiz.Title != "" {
+		t.Fatalf("decode() title = %q, want none", parsed.quiz.Title)
+	}
+	// GIFT keeps the text, answers, explanation and tag. Ordering questions
+	// come back as matching ones pairing each item with its position.
+	checkQuestions(t, parsed.quiz.Questions, quiz.Questions, func(q dto_quiz.Question) dto_quiz.Question {
+		q.Difficulty = ""
+		q.Points = 0
+		q.NegativePoints = 0
+		q.Settings.Unit = ""
+		q.Settings.UnitFactors = nil
+		q.Settings.MaxEditDistance = 0
+		switch q.QuestionType {
+		case models.QuestionMatching:
+			q.ScoringStrategy = ""
+		case models.QuestionOrdering:
+			q.QuestionType = models.QuestionMatching
+			options := make([]dto_quiz.Option, len(q.Options))
+			for i, o := range q.Options {
+				options[i] = dto_quiz.Option{Text: o.Text, MatchText: strPtr(strconv.Itoa(i + 1))}
+			}
+			q.Options = options
+		}
+		return q
+	})
+}
+
+func TestGIFTFormatDecodeErrors(t *testing.T) {
+	data := strings.Join([]string{
+		"// Exported by hand",                  // 1
+		"$CATEGORY: $course$/Energy",           // 2
+		"",                                     // 3
+		"::Q1:: No answers here",               // 4
+		"",                                     // 5
+		"// a comment between questions",       // 6
+		"::Q2:: Never closed {",                // 7
+		"\t=yes",                               // 8
+		"",                                     // 9
+		"What is pi? {#three}",                 // 10
+		"",                                     // 11
+		"::Q4:: The sun is a star.",            // 12
+		"{TRUE}",                               // 13
+		"",                                     // 14
+		"::Q5:: Describe your day. {}",         // 15
+		"",                                     // 16
+		"::Q6:: Pick a range {#5..1}",          // 17
+		"::Q7:: Still the same block {=a ~b}",  // 18
+		"",                                     // 19
+		"::Q8:: Without answers { only text }", // 20
+	}, "\n")
+
+	parsed := giftFormat{}.decode([]byte(data))
+
+	want := []dto_quiz.ImportIssue{
+		{Line: 4, Message: "no answer block: GIFT questions need their answers in { }"},
+		{Line: 7, Message: "the answer block opened with { is never closed"},
+		{Line: 10, Message: `numeric answer "three" is not a number`},
+		{Line: 15, Message: "essay questions are not supported"},
+		{Line: 17, Message: `numeric range "5..1" is not min..max`},
+		{Line: 20, Message: "the answer block has no answers starting with = or ~"},
+	}
+	if !reflect.DeepEqual(parsed.issues, want) {
+		t.Fatalf("decode() issues =\n%+v\nwant\n%+v", parsed.issues, want)
+	}
+
+	// The question spread over lines 12 and 13 is still read, from where it
+	// starts and under the category above it.
+	if len(parsed.quiz.Questions) != 1 || !reflect.DeepEqual(parsed.sources, []source{{line: 12}}) {
+		t.Fatalf("decode() questions = %+v at %+v, want one at line 12", parsed.quiz.Questions, parsed.sources)
+	}
+	q := parsed.quiz.Questions[0]
+	if q.QuestionType != models.QuestionTrueFalse || q.CorrectAnswer != "true" || q.Tag != "Energy" {
+		t.Fatalf("decode() question = %+v", q)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+)
+
+// Versioned JSON. Anything written by this version, or an older one, can be
+// imported back as it was.
+const (
+	quizDocumentFormat  = "ecoquiz.quiz"
+	quizDocumentVersion = 1
+)
+
+type jsonFormat struct{}
+
+func (jsonFormat) contentType() string { return "application/json" }
+func (jsonFormat) extension() string   { return "json" }
+
+func (jsonFormat) encode(quiz *dto_quiz.ExportedQuiz) ([]byte, error) {
+	return json.MarshalIndent(dto_quiz.QuizDocument{
+		Format:  quizDocumentFormat,
+		Version: quizDocumentVersion,
+		Quiz:    *quiz,
+	}, "", "  ")
+}
+
+func (jsonFormat) decode(data []byte) parsedQuiz {
+	var parsed parsedQuiz
+	var doc dto_quiz.QuizDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		parsed.fail(source{line: jsonErrorLine(data, err)}, "invalid JSON: "+err.Error())
+		return parsed
+	}
+
+	if doc.Format != quizDocumentFormat {
+		parsed.fail(source{line: 1}, "not an EcoQuiz export: format must be "+strconv.Quote(quizDocumentFormat))
+		return parsed
+	}
+	if doc.Version < 1 || doc.Version > quizDocumentVersion {
+		parsed.fail(source{line: 1}, "unsupported version "+strconv.Itoa(doc.Version)+", this server reads up to version "+strconv.Itoa(quizDocumentVersion))
+		return parsed
+	}
+
+	lines := jsonArrayLines(data, "quiz", "questions")
+	parsed.quiz = doc.Quiz
+	parsed.quiz.Questions = nil
+	for i, q := range doc.Quiz.Questions {
+		at := source{line: 1}
+		if i < len(lines) {
+			at.line = lines[i]
+		}
+		parsed.addQuestion(q, at)
+	}
+	return parsed
+}
+
+// jsonErrorLine finds the line a decoding error points at, or 1 when it
+// does not say.
+func jsonErrorLine(data []byte, err error) int {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return lineAt(data, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return lineAt(data, typeErr.Offset)
+	}
+	return 1
+}
+
+// jsonArrayLines returns the line each element of the array found by
+// following keys from the top-level object starts on. It is only called on
+// documents that already decoded, so it gives up quietly on anything odd.
+func jsonArrayLines(data []byte, keys ...string) []int {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for _, key := range keys {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil
+		}
+		for {
+			tok, err := dec.Token()
+			if err != nil || tok == json.Delim('}') {
+				return nil
+			}
+			if tok == key {
+				break
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil
+			}
+		}
+	}
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil
+	}
+	var lines []int
+	for dec.More() {
+		lines = append(lines, lineAt(data, skipJSONSpace(data, dec.InputOffset())))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return lines
+		}
+	}
+	return lines
+}
+
+// skipJSONSpace moves past the whitespace and comma between array elements.
+func skipJSONSpace(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineAt is the 1-based line of a byte offset.
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte{'\n'}) + 1
+}
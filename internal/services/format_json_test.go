@@ -0,0 +1,86 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONFormatRoundTrip(t *testing.T) {
+	quiz := sampleQuiz()
+	parsed := roundTrip(t, jsonFormat{}, quiz)
+
+	// JSON carries everything, quiz settings and pools included.
+	if !reflect.DeepEqual(parsed.quiz, quiz) {
+		t.Fatalf("decode() quiz =\n%+v\nwant\n%+v", parsed.quiz, quiz)
+	}
+}
+
+func TestJSONFormatDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantLine int
+		wantMsg  string
+	}{
+		{
+			name: "syntax error",
+			data: `{
+  "format": "ecoquiz.quiz",
+  "version": 1,
+  "quiz": {"title": "T",}
+}`,
+			wantLine: 4,
+			wantMsg:  "invalid JSON",
+		},
+		{
+			name: "wrong type",
+			data: `{
+  "format": "ecoquiz.quiz",
+  "version": 1,
+  "quiz": {
+    "questions": [
+      {"question_text": "a"},
+      {"question_text": "b", "points": "two"}
+    ]
+  }
+}`,
+			wantLine: 7,
+			wantMsg:  "invalid JSON",
+		},
+		{
+			name: "cut short",
+			data: `{
+  "format": "ecoquiz.quiz",
+  "version": 1,
+  "quiz": {`,
+			wantLine: 4,
+			wantMsg:  "invalid JSON",
+		},
+		{
+			name:     "not an export",
+			data:     `{"format": "other", "version": 1}`,
+			wantLine: 1,
+			wantMsg:  "not an EcoQuiz export",
+		},
+		{
+			name:     "newer version",
+			data:     "\n\n" + `{"format": "ecoquiz.quiz", "version": 2}`,
+			wantLine: 1,
+			wantMsg:  "unsupported version 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := jsonFormat{}.decode([]byte(tt.data))
+			if len(parsed.issues) != 1 {
+				t.Fatalf("decode() issues = %+v, want one", parsed.issues)
+			}
+			issue := parsed.issues[0]
+			if issue.Line != tt.wantLine || !strings.Contains(issue.Message, tt.wantMsg) {
+				t.Fatalf("decode() issue = %+v, want line %d and %q", issue, tt.wantLine, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,686 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+)
+
+// IMS QTI 2.1. Exports are content packages: a zip with imsmanifest.xml, an
+// assessmentTest and one assessmentItem file per question. Imports take such
+// a package or a single XML file of items. Choice, order, match and text
+// entry interactions are understood; numeric tolerances and units, pools
+// and quiz settings are not carried. The explanation travels as
+// modalFeedback.
+type qtiFormat struct{}
+
+const (
+	qtiNamespace   = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	qtiTemplateURL = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/"
+)
+
+func (qtiFormat) contentType() string { return "application/zip" }
+func (qtiFormat) extension() string   { return "zip" }
+
+func (qtiFormat) encode(quiz *dto_quiz.ExportedQuiz) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, content string) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, content)
+		return err
+	}
+
+	hrefs := make([]string, 0, len(quiz.Questions))
+	for i := range quiz.Questions {
+		href := fmt.Sprintf("items/q%03d.xml", i+1)
+		if err := write(href, qtiItemXML(&quiz.Questions[i], fmt.Sprintf("q%03d", i+1))); err != nil {
+			return nil, err
+		}
+		hrefs = append(hrefs, href)
+	}
+
+	var test strings.Builder
+	test.WriteString(xml.Header)
+	test.WriteString(`<assessmentTest xmlns="` + qtiNamespace + `" identifier="test" title="` + xmlEscape(quiz.Title) + `">` + "\n")
+	test.WriteString("  <testPart identifier=\"part\" navigationMode=\"linear\" submissionMode=\"simultaneous\">\n")
+	test.WriteString("    <assessmentSection identifier=\"section\" title=\"" + xmlEscape(quiz.Title) + "\" visible=\"true\">\n")
+	for i, href := range hrefs {
+		test.WriteString(fmt.Sprintf("      <assessmentItemRef identifier=\"q%03d\" href=\"%s\"/>\n", i+1, href))
+	}
+	test.WriteString("    </assessmentSection>\n  </testPart>\n</assessmentTest>\n")
+	if err := write("test.xml", test.String()); err != nil {
+		return nil, err
+	}
+
+	var manifest strings.Builder
+	manifest.WriteString(xml.Header)
+	manifest.WriteString(`<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1" identifier="manifest">` + "\n")
+	manifest.WriteString("  <organizations/>\n  <resources>\n")
+	manifest.WriteString("    <resource identifier=\"test\" type=\"imsqti_test_xmlv2p1\" href=\"test.xml\">\n      <file href=\"test.xml\"/>\n")
+	for i := range hrefs {
+		manifest.WriteString(fmt.Sprintf("      <dependency identifierref=\"q%03d\"/>\n", i+1))
+	}
+	manifest.WriteString("    </resource>\n")
+	for i, href := range hrefs {
+		manifest.WriteString(fmt.Sprintf("    <resource identifier=\"q%03d\" type=\"imsqti_item_xmlv2p1\" href=\"%s\">\n      <file href=\"%s\"/>\n    </resource>\n", i+1, href, href))
+	}
+	manifest.WriteString("  </resources>\n</manifest>\n")
+	if err := write("imsmanifest.xml", manifest.String()); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// qtiItemXML writes one question as an assessmentItem.
+func qtiItemXML(q *dto_quiz.Question, identifier string) string {
+	var decl, body strings.Builder
+	template := "match_correct"
+	prompt := "      <prompt>" + xmlEscape(q.QuestionText) + "</prompt>\n"
+	choice := func(i int) string { return "choice_" + strconv.Itoa(i+1) }
+
+	switch q.QuestionType {
+	case models.QuestionTrueFalse:
+		decl.WriteString(`  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">` + "\n")
+		decl.WriteString("    <correctResponse><value>" + xmlEscape(q.CorrectAnswer) + "</value></correctResponse>\n")
+		body.WriteString(`    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">` + "\n" + prompt)
+		body.WriteString("      <simpleChoice identifier=\"true\">True</simpleChoice>\n      <simpleChoice identifier=\"false\">False</simpleChoice>\n")
+		body.WriteString("    </choiceInteraction>\n")
+
+	case models.QuestionShortAnswer, models.QuestionNumeric:
+		baseType := "string"
+		if q.QuestionType == models.QuestionNumeric {
+			baseType = "float"
+		}
+		decl.WriteString(`  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="` + baseType + `">` + "\n")
+		decl.WriteString("    <correctResponse><value>" + xmlEscape(q.CorrectAnswer) + "</value></correctResponse>\n")
+		if q.QuestionType == models.QuestionShortAnswer {
+			template = "map_response"
+			decl.WriteString("    <mapping defaultValue=\"0\">\n")
+			for _, answer := range append([]string{q.CorrectAnswer}, q.Settings.AcceptedAnswers...) {
+				decl.WriteString(`      <mapEntry mapKey="` + xmlEscape(answer) + `" mappedValue="1" caseSensitive="false"/>` + "\n")
+			}
+			decl.WriteString("    </mapping>\n")
+		}
+		body.WriteString("    <p>" + xmlEscape(q.QuestionText) + "</p>\n")
+		body.WriteString("    <p><textEntryInteraction responseIdentifier=\"RESPONSE\" expectedLength=\"20\"/></p>\n")
+
+	case models.QuestionOrdering:
+		decl.WriteString(`  <responseDeclaration identifier="RESPONSE" cardinality="ordered" baseType="identifier">` + "\n")
+		decl.WriteString("    <correctResponse>\n")
+		for i := range q.Options {
+			decl.WriteString("      <value>" + choice(i) + "</value>\n")
+		}
+		decl.WriteString("    </correctResponse>\n")
+		body.WriteString(`    <orderInteraction responseIdentifier="RESPONSE" shuffle="true">` + "\n" + prompt)
+		for i, o := range q.Options {
+			body.WriteString(`      <simpleChoice identifier="` + choice(i) + `">` + xmlEscape(o.Text) + "</simpleChoice>\n")
+		}
+		body.WriteString("    </orderInteraction>\n")
+
+	case models.QuestionMatching:
+		targets := matchTargets(q.Options)
+		decl.WriteString(`  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="directedPair">` + "\n")
+		decl.WriteString("    <correctResponse>\n")
+		for i, o := range q.Options {
+			if o.MatchText != nil {
+				decl.WriteString("      <value>" + choice(i) + " match_" + strconv.Itoa(targets[*o.MatchText]+1) + "</value>\n")
+			}
+		}
+		decl.WriteString("    </correctResponse>\n")
+		body.WriteString(`    <matchInteraction responseIdentifier="RESPONSE" shuffle="true" maxAssociations="` + strconv.Itoa(len(q.Options)) + `">` + "\n" + prompt)
+		body.WriteString("      <simpleMatchSet>\n")
+		for i, o := range q.Options {
+			body.WriteString(`        <simpleAssociableChoice identifier="` + choice(i) + `" matchMax="1">` + xmlEscape(o.Text) + "</simpleAssociableChoice>\n")
+		}
+		body.WriteString("      </simpleMatchSet>\n      <simpleMatchSet>\n")
+		matches := make([]string, len(targets))
+		for text, i := range targets {
+			matches[i] = text
+		}
+		for i, text := range matches {
+			body.WriteString(`        <simpleAssociableChoice identifier="match_` + strconv.Itoa(i+1) + `" matchMax="` + strconv.Itoa(len(q.Options)) + `">` + xmlEscape(text) + "</simpleAssociableChoice>\n")
+		}
+		body.WriteString("      </simpleMatchSet>\n    </matchInteraction>\n")
+
+	default:
+		cardinality, maxChoices := "single", "1"
+		if q.QuestionType == models.QuestionMultipleChoice {
+			cardinality, maxChoices = "multiple", "0"
+		}
+		decl.WriteString(`  <responseDeclaration identifier="RESPONSE" cardinality="` + cardinality + `" baseType="identifier">` + "\n")
+		decl.WriteString("    <correctResponse>\n")
+		for i, o := range q.Options {
+			if o.IsCorrect {
+				decl.WriteString("      <value>" + choice(i) + "</value>\n")
+			}
+		}
+		decl.WriteString("    </correctResponse>\n")
+		body.WriteString(`    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="` + maxChoices + `">` + "\n" + prompt)
+		for i, o := range q.Options {
+			body.WriteString(`      <simpleChoice identifier="` + choice(i) + `">` + xmlEscape(o.Text) + "</simpleChoice>\n")
+		}
+		body.WriteString("    </choiceInteraction>\n")
+	}
+	decl.WriteString("  </responseDeclaration>\n")
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<assessmentItem xmlns="` + qtiNamespace + `" identifier="` + identifier + `" title="` + xmlEscape(truncate(q.QuestionText, 80)) + `" adaptive="false" timeDependent="false">` + "\n")
+	b.WriteString(decl.String())
+	b.WriteString(`  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float"/>` + "\n")
+	if q.Explanation != "" {
+		b.WriteString(`  <outcomeDeclaration identifier="FEEDBACK" cardinality="single" baseType="identifier"/>` + "\n")
+	}
+	b.WriteString("  <itemBody>\n" + body.String() + "  </itemBody>\n")
+	b.WriteString(`  <responseProcessing template="` + qtiTemplateURL + template + `"/>` + "\n")
+	if q.Explanation != "" {
+		b.WriteString(`  <modalFeedback outcomeIdentifier="FEEDBACK" identifier="EXPLANATION" showHide="show">` + xmlEscape(q.Explanation) + "</modalFeedback>\n")
+	}
+	b.WriteString("</assessmentItem>\n")
+	return b.String()
+}
+
+// matchTargets numbers the distinct match texts in the order they appear.
+func matchTargets(options []dto_quiz.Option) map[string]int {
+	targets := make(map[string]int, len(options))
+	for _, o := range options {
+		if o.MatchText == nil {
+			continue
+		}
+		if _, ok := targets[*o.MatchText]; !ok {
+			targets[*o.MatchText] = len(targets)
+		}
+	}
+	return targets
+}
+
+func (qtiFormat) decode(data []byte) parsedQuiz {
+	var parsed parsedQuiz
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		decodeQTIItems(&parsed, "", data)
+		if len(parsed.quiz.Questions) == 0 && len(parsed.issues) == 0 {
+			parsed.fail(source{line: 1}, "no assessmentItem found; upload the whole content package as a zip")
+		}
+		return parsed
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		parsed.fail(source{}, "not a valid zip file: "+err.Error())
+		return parsed
+	}
+	if len(zr.File) > maxQTIFiles {
+		parsed.fail(source{}, "the package has more than "+strconv.Itoa(maxQTIFiles)+" files")
+		return parsed
+	}
+	pkg := &qtiPackage{
+		files:  make(map[string]*zip.File, len(zr.File)),
+		opened: make(map[string]bool),
+		budget: maxQTIBytes,
+	}
+	for _, f := range zr.File {
+		pkg.files[path.Clean(f.Name)] = f
+	}
+
+	items, testHref := pkg.manifest()
+	if items == nil {
+		// Without a manifest every XML file is tried, in name order.
+		for name := range pkg.files {
+			if strings.HasSuffix(strings.ToLower(name), ".xml") {
+				items = append(items, name)
+			}
+		}
+		sort.Strings(items)
+	}
+	if testHref != "" {
+		if content, err := pkg.read(testHref); err == nil {
+			parsed.quiz.Title = qtiTestTitle(content)
+		}
+	}
+
+	for _, name := range items {
+		if _, ok := pkg.files[name]; !ok {
+			parsed.fail(source{file: "imsmanifest.xml"}, "the manifest lists "+name+" but the package does not contain it")
+			continue
+		}
+		content, err := pkg.read(name)
+		if err == errQTITooLarge {
+			parsed.fail(source{file: name}, err.Error())
+			break
+		}
+		if err != nil {
+			parsed.fail(source{file: name}, "cannot read file: "+err.Error())
+			continue
+		}
+		decodeQTIItems(&parsed, name, content)
+	}
+	if len(parsed.quiz.Questions) == 0 && len(parsed.issues) == 0 {
+		parsed.fail(source{}, "the package contains no assessmentItem")
+	}
+	return parsed
+}
+
+// Limits on what one content package may cost to unpack, however well it
+// compresses.
+const (
+	maxQTIFiles = 1000
+	maxQTIBytes = 4 * maxImportBytes
+)
+
+var errQTITooLarge = errors.New("the package is larger than " + strconv.Itoa(maxQTIBytes>>20) + " MB once unpacked")
+
+// qtiPackage reads the files of an uploaded content package, each at most
+// once and all within a budget of decompressed bytes.
+type qtiPackage struct {
+	files  map[string]*zip.File
+	opened map[string]bool
+	budget int64
+}
+
+// manifest lists the item files of the package in manifest order, each
+// once, and the test file if there is one. It returns nil items when there
+// is no readable manifest.
+func (p *qtiPackage) manifest() ([]string, string) {
+	content, err := p.read("imsmanifest.xml")
+	if err != nil {
+		return nil, ""
+	}
+	var manifest struct {
+		Resources []struct {
+			Type string `xml:"type,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"resources>resource"`
+	}
+	if err := xml.Unmarshal(content, &manifest); err != nil {
+		return nil, ""
+	}
+
+	items := []string{}
+	listed := make(map[string]bool)
+	test := ""
+	for _, r := range manifest.Resources {
+		switch {
+		case strings.HasPrefix(r.Type, "imsqti_item"):
+			href := path.Clean(r.Href)
+			if !listed[href] {
+				listed[href] = true
+				items = append(items, href)
+			}
+		case strings.HasPrefix(r.Type, "imsqti_test") && test == "":
+			test = path.Clean(r.Href)
+		}
+	}
+	return items, test
+}
+
+func qtiTestTitle(content []byte) string {
+	var test struct {
+		Title string `xml:"title,attr"`
+	}
+	if err := xml.Unmarshal(content, &test); err != nil {
+		return ""
+	}
+	return test.Title
+}
+
+// read unpacks the named file. A file is read only once; asking for it again
+// is an error, so a manifest cannot make the same file count many times.
+func (p *qtiPackage) read(name string) ([]byte, error) {
+	f := p.files[name]
+	if f == nil {
+		return nil, errors.New("file not found")
+	}
+	if p.opened[name] {
+		return nil, errors.New("file already read")
+	}
+	p.opened[name] = true
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	limit := min(int64(maxImportBytes), p.budget)
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		if limit < maxImportBytes {
+			return nil, errQTITooLarge
+		}
+		return nil, errors.New("the file is larger than " + strconv.Itoa(maxImportBytes>>20) + " MB")
+	}
+	p.budget -= int64(len(content))
+	return content, nil
+}
+
+type qtiItem struct {
+	Responses []qtiResponse `xml:"responseDeclaration"`
+	Body      qtiInner      `xml:"itemBody"`
+	Feedback  []qtiInner    `xml:"modalFeedback"`
+}
+
+type qtiResponse struct {
+	Identifier  string   `xml:"identifier,attr"`
+	Cardinality string   `xml:"cardinality,attr"`
+	BaseType    string   `xml:"baseType,attr"`
+	Correct     []string `xml:"correctResponse>value"`
+	Mapping     []struct {
+		Key   string  `xml:"mapKey,attr"`
+		Value float64 `xml:"mappedValue,attr"`
+	} `xml:"mapping>mapEntry"`
+}
+
+type qtiInteraction struct {
+	XMLName            xml.Name
+	ResponseIdentifier string      `xml:"responseIdentifier,attr"`
+	Prompt             qtiInner    `xml:"prompt"`
+	Choices            []qtiChoice `xml:"simpleChoice"`
+	MatchSets          []struct {
+		Choices []qtiChoice `xml:"simpleAssociableChoice"`
+	} `xml:"simpleMatchSet"`
+}
+
+type qtiChoice struct {
+	Identifier string `xml:"identifier,attr"`
+	Inner      string `xml:",innerxml"`
+}
+
+type qtiInner struct {
+	Inner string `xml:",innerxml"`
+}
+
+// decodeQTIItems reads every assessmentItem in an XML file.
+func decodeQTIItems(parsed *parsedQuiz, file string, content []byte) {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			line, _ := dec.InputPos()
+			parsed.fail(source{file: file, line: line}, "invalid XML: "+xmlErrorMessage(err))
+			return
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "assessmentItem" {
+			continue
+		}
+
+		line, _ := dec.InputPos()
+		at := source{file: file, line: line}
+		var item qtiItem
+		if err := dec.DecodeElement(&item, &start); err != nil {
+			line, _ := dec.InputPos()
+			parsed.fail(source{file: file, line: line}, "invalid XML: "+xmlErrorMessage(err))
+			return
+		}
+		q, msg := qtiQuestion(&item)
+		if msg != "" {
+			parsed.fail(at, msg)
+			continue
+		}
+		parsed.addQuestion(q, at)
+	}
+}
+
+// qtiQuestion turns an item into a question. A non-empty string is the
+// reason it could not be.
+func qtiQuestion(item *qtiItem) (dto_quiz.Question, string) {
+	var q dto_quiz.Question
+	text, interaction, msg := qtiBody(item.Body.Inner)
+	if msg != "" {
+		return q, msg
+	}
+	if interaction == nil {
+		return q, "the item has no interaction"
+	}
+	if prompt := xmlText(interaction.Prompt.Inner); prompt != "" {
+		text = strings.TrimSpace(text + "\n" + prompt)
+	}
+	q.QuestionText = text
+	for _, f := range item.Feedback {
+		if feedback := xmlText(f.Inner); feedback != "" {
+			q.Explanation = feedback
+			break
+		}
+	}
+
+	var response *qtiResponse
+	for i := range item.Responses {
+		if item.Responses[i].Identifier == interaction.ResponseIdentifier || response == nil {
+			response = &item.Responses[i]
+		}
+	}
+	if response == nil {
+		return q, "the item has no responseDeclaration"
+	}
+	correct := response.Correct
+	if len(correct) == 0 {
+		for _, m := range response.Mapping {
+			if m.Value > 0 {
+				correct = append(correct, m.Key)
+			}
+		}
+	}
+	if len(correct) == 0 {
+		return q, "the item has no correct response"
+	}
+
+	switch interaction.XMLName.Local {
+	case "choiceInteraction":
+		ids := make(map[string]bool, len(interaction.Choices))
+		for _, c := range interaction.Choices {
+			ids[c.Identifier] = true
+		}
+		right := make(map[string]bool, len(correct))
+		for _, id := range correct {
+			if !ids[id] {
+				return q, "correct response " + strconv.Quote(id) + " is not one of the choices"
+			}
+			right[id] = true
+		}
+
+		if len(interaction.Choices) == 2 && ids["true"] && ids["false"] && len(correct) == 1 {
+			q.QuestionType = models.QuestionTrueFalse
+			q.CorrectAnswer = correct[0]
+			return q, ""
+		}
+		q.QuestionType = models.QuestionSingleChoice
+		if response.Cardinality == "multiple" {
+			q.QuestionType = models.QuestionMultipleChoice
+		}
+		for _, c := range interaction.Choices {
+			q.Options = append(q.Options, dto_quiz.Option{Text: xmlText(c.Inner), IsCorrect: right[c.Identifier]})
+		}
+
+	case "orderInteraction":
+		texts := make(map[string]string, len(interaction.Choices))
+		for _, c := range interaction.Choices {
+			texts[c.Identifier] = xmlText(c.Inner)
+		}
+		if len(correct) != len(texts) {
+			return q, "the correct order must list every choice once"
+		}
+		q.QuestionType = models.QuestionOrdering
+		for _, id := range correct {
+			text, ok := texts[id]
+			if !ok {
+				return q, "correct response " + strconv.Quote(id) + " is not one of the choices"
+			}
+			q.Options = append(q.Options, dto_quiz.Option{Text: text})
+		}
+
+	case "matchInteraction":
+		if len(interaction.MatchSets) != 2 {
+			return q, "matchInteraction needs two simpleMatchSets"
+		}
+		targets := make(map[string]string, len(interaction.MatchSets[1].Choices))
+		for _, c := range interaction.MatchSets[1].Choices {
+			targets[c.Identifier] = xmlText(c.Inner)
+		}
+		pairs := make(map[string]string, len(correct))
+		for _, value := range correct {
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				return q, "correct response " + strconv.Quote(value) + " is not a pair of identifiers"
+			}
+			match, ok := targets[fields[1]]
+			if !ok {
+				return q, "correct response " + strconv.Quote(value) + " pairs with an unknown choice"
+			}
+			pairs[fields[0]] = match
+		}
+		q.QuestionType = models.QuestionMatching
+		for _, c := range interaction.MatchSets[0].Choices {
+			match, ok := pairs[c.Identifier]
+			if !ok {
+				return q, "choice " + strconv.Quote(c.Identifier) + " has no correct match"
+			}
+			q.Options = append(q.Options, dto_quiz.Option{Text: xmlText(c.Inner), MatchText: &match})
+		}
+
+	case "textEntryInteraction":
+		if response.BaseType == "float" || response.BaseType == "integer" {
+			q.QuestionType = models.QuestionNumeric
+			q.CorrectAnswer = strings.TrimSpace(correct[0])
+			return q, ""
+		}
+		q.QuestionType = models.QuestionShortAnswer
+		q.CorrectAnswer = correct[0]
+		for _, m := range response.Mapping {
+			if m.Value > 0 && normalizeText(m.Key) != normalizeText(q.CorrectAnswer) {
+				q.Settings.AcceptedAnswers = append(q.Settings.AcceptedAnswers, m.Key)
+			}
+		}
+
+	default:
+		return q, interaction.XMLName.Local + " is not supported"
+	}
+	return q, ""
+}
+
+var qtiInteractions = map[string]bool{
+	"choiceInteraction":          true,
+	"orderInteraction":           true,
+	"matchInteraction":           true,
+	"textEntryInteraction":       true,
+	"associateInteraction":       true,
+	"extendedTextInteraction":    true,
+	"gapMatchInteraction":        true,
+	"inlineChoiceInteraction":    true,
+	"hotspotInteraction":         true,
+	"hottextInteraction":         true,
+	"sliderInteraction":          true,
+	"uploadInteraction":          true,
+	"graphicOrderInteraction":    true,
+	"selectPointInteraction":     true,
+	"positionObjectStage":        true,
+	"graphicGapMatchInteraction": true,
+}
+
+// qtiBody splits an itemBody into the text around the interaction and the
+// interaction itself. Only items with a single interaction can be imported.
+func qtiBody(inner string) (string, *qtiInteraction, string) {
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	var text strings.Builder
+	var interaction *qtiInteraction
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, "invalid itemBody: " + xmlErrorMessage(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !qtiInteractions[t.Name.Local] {
+				continue
+			}
+			if interaction != nil {
+				return "", nil, "items with more than one interaction are not supported"
+			}
+			interaction = &qtiInteraction{}
+			if err := dec.DecodeElement(interaction, &t); err != nil {
+				return "", nil, "invalid " + t.Name.Local + ": " + xmlErrorMessage(err)
+			}
+		case xml.EndElement:
+			text.WriteString("\n")
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	return collapseLines(text.String()), interaction, ""
+}
+
+// xmlText is the text content of an XML fragment, without its markup.
+func xmlText(inner string) string {
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if data, ok := tok.(xml.CharData); ok {
+			text.Write(data)
+		}
+	}
+	return collapseLines(text.String())
+}
+
+// collapseLines trims every line, squeezes runs of spaces and drops empty
+// lines.
+func collapseLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func xmlErrorMessage(err error) string {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Msg
+	}
+	return err.Error()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
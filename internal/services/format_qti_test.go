@@ -0,0 +1,159 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+)
+
+func TestQTIFormatRoundTrip(t *testing.T) {
+	quiz := sampleQuiz()
+	parsed := roundTrip(t, qtiFormat{}, quiz)
+
+	if parsed.quiz.Title != quiz.Title {
+		t.Fatalf("decode() title = %q, want %q", parsed.quiz.Title, quiz.Title)
+	}
+	// QTI keeps the text, answers and explanation; tags, points, scoring
+	// strategies and numeric settings stay behind.
+	checkQuestions(t, parsed.quiz.Questions, quiz.Questions, func(q dto_quiz.Question) dto_quiz.Question {
+		q.Tag = ""
+		q.Difficulty = ""
+		q.Points = 0
+		q.NegativePoints = 0
+		q.ScoringStrategy = ""
+		q.Settings = dto_quiz.QuestionSettings{AcceptedAnswers: q.Settings.AcceptedAnswers}
+		return q
+	})
+	// Each question is read from its own item file, where the item starts
+	// below the XML declaration.
+	for i, at := range parsed.sources {
+		want := source{file: fmt.Sprintf("items/q%03d.xml", i+1), line: 2}
+		if at != want {
+			t.Errorf("question %d read from %+v, want %+v", i+1, at, want)
+		}
+	}
+}
+
+const qtiItems = `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItems>
+<assessmentItem identifier="a">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+    <correctResponse><value>true</value></correctResponse>
+  </responseDeclaration>
+  <itemBody><choiceInteraction responseIdentifier="RESPONSE" maxChoices="1"><prompt>The sky is blue.</prompt><simpleChoice identifier="true">True</simpleChoice><simpleChoice identifier="false">False</simpleChoice></choiceInteraction></itemBody>
+</assessmentItem>
+<assessmentItem identifier="b">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier"/>
+  <itemBody><choiceInteraction responseIdentifier="RESPONSE"><simpleChoice identifier="x">X</simpleChoice></choiceInteraction></itemBody>
+</assessmentItem>
+<assessmentItem identifier="c">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string"/>
+  <itemBody><p>Broken
+  </itemBody>
+</assessmentItem>
+</assessmentItems>
+`
+
+func TestQTIFormatDecodeItems(t *testing.T) {
+	parsed := qtiFormat{}.decode([]byte(qtiItems))
+
+	want := []dto_quiz.ImportIssue{
+		{Line: 9, Message: "the item has no correct response"},
+		{Line: 16, Message: "invalid XML: element <p> closed by </itemBody>"},
+	}
+	if !reflect.DeepEqual(parsed.issues, want) {
+		t.Fatalf("decode() issues =\n%+v\nwant\n%+v", parsed.issues, want)
+	}
+	if !reflect.DeepEqual(parsed.sources, []source{{line: 3}}) {
+		t.Fatalf("decode() sources = %+v, want the item at line 3", parsed.sources)
+	}
+	if q := parsed.quiz.Questions[0]; q.QuestionType != models.QuestionTrueFalse || q.QuestionText != "The sky is blue." {
+		t.Fatalf("decode() question = %+v", q)
+	}
+}
+
+// zipFiles packs the named files, in order, into a zip.
+func zipFiles(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f[0])
+		if err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		if _, err := w.Write([]byte(f[1])); err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestQTIFormatDecodePackage(t *testing.T) {
+	manifest := `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+  <resources>
+    <resource type="imsqti_item_xmlv2p1" href="items/one.xml"/>
+    <resource type="imsqti_item_xmlv2p1" href="items/missing.xml"/>
+    <resource type="imsqti_item_xmlv2p1" href="items/one.xml"/>
+    <resource type="imsqti_item_xmlv2p1" href="./items/two.xml"/>
+  </resources>
+</manifest>
+`
+	data := zipFiles(t,
+		[2]string{"imsmanifest.xml", manifest},
+		[2]string{"items/one.xml", qtiItems},
+		[2]string{"items/two.xml", "<?xml version=\"1.0\"?>\n\n<assessmentItem>\n  <itemBody>\n</assessmentItem>\n"},
+	)
+
+	parsed := qtiFormat{}.decode(data)
+
+	want := []dto_quiz.ImportIssue{
+		{File: "items/one.xml", Line: 9, Message: "the item has no correct response"},
+		{File: "items/one.xml", Line: 16, Message: "invalid XML: element <p> closed by </itemBody>"},
+		{File: "imsmanifest.xml", Message: "the manifest lists items/missing.xml but the package does not contain it"},
+		{File: "items/two.xml", Line: 5, Message: "invalid XML: element <itemBody> closed by </assessmentItem>"},
+	}
+	if !reflect.DeepEqual(parsed.issues, want) {
+		t.Fatalf("decode() issues =\n%+v\nwant\n%+v", parsed.issues, want)
+	}
+	if !reflect.DeepEqual(parsed.sources, []source{{file: "items/one.xml", line: 3}}) {
+		t.Fatalf("decode() sources = %+v, want the one item of items/one.xml", parsed.sources)
+	}
+}
+
+func TestQTIFormatDecodeNotQTI(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want dto_quiz.ImportIssue
+	}{
+		{
+			name: "XML without items",
+			data: []byte("<?xml version=\"1.0\"?>\n<questions/>\n"),
+			want: dto_quiz.ImportIssue{Line: 1, Message: "no assessmentItem found; upload the whole content package as a zip"},
+		},
+		{
+			name: "package without items",
+			data: zipFiles(t, [2]string{"readme.txt", "hello"}),
+			want: dto_quiz.ImportIssue{Message: "the package contains no assessmentItem"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := qtiFormat{}.decode(tt.data)
+			if !reflect.DeepEqual(parsed.issues, []dto_quiz.ImportIssue{tt.want}) {
+				t.Fatalf("decode() issues = %+v, want %+v", parsed.issues, tt.want)
+			}
+		})
+	}
+}
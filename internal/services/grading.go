@@ -0,0 +1,390 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+)
+
+// maxQuestionPoints is what graders give a fully correct answer. Their
+// scores are shares of a question, weighted by its points when totalled.
+const maxQuestionPoints = 1.0
+
+// questionGrader is implemented once per question type. SubmitQuiz, TakeQuiz
+// and GetQuizResult only go through this interface, so adding a type means
+// adding a grader to questionGraders.
+type questionGrader interface {
+	// validate checks a question definition before it is saved.
+	validate(question *models.QuestionSnapshot) error
+	// present fills in what a learner sees, without revealing the answer.
+	present(question *models.QuestionSnapshot, take *dto_quiz.QuestionTake)
+	// parse reads the part of an answer this type uses and rejects anything
+	// that does not fit the question.
+	parse(question *models.QuestionSnapshot, answer dto_quiz.Answer) (response, error)
+	// score returns the share of the question earned, between zero and
+	// maxQuestionPoints.
+	score(question *models.QuestionSnapshot, r response) float64
+}
+
+var questionGraders = map[string]questionGrader{
+	models.QuestionSingleChoice:   choiceGrader{},
+	models.QuestionMultipleChoice: choiceGrader{},
+	models.QuestionTrueFalse:      trueFalseGrader{},
+	models.QuestionShortAnswer:    shortAnswerGrader{},
+	models.QuestionNumeric:        numericGrader{},
+	models.QuestionOrdering:       orderingGrader{},
+	models.QuestionMatching:       matchingGrader{},
+}
+
+func graderFor(question *models.QuestionSnapshot) questionGrader {
+	if g, ok := questionGraders[questionType(question)]; ok {
+		return g
+	}
+	return choiceGrader{}
+}
+
+// response is a parsed answer in the form it is graded and stored in. Only
+// the field matching the question type is set.
+type response struct {
+	OptionIDs []string          // choice: selected options
+	Text      *string           // true_false, short_answer, numeric: typed answer
+	Order     []string          // ordering: option IDs in the chosen order
+	Matches   map[string]string // matching: option ID to chosen match
+}
+
+type gradedAnswer struct {
+	QuestionID string
+	Response   response
+	Points     float64 // weighted, negative marks included
+}
+
+// gradeAnswers matches answers to questions by question ID and scores each
+// one with its type's grader. Every answer must name a question of the
+// attempt at most once and fit that question; anything else is treated as a
+// tampered submission. Questions left out, or answered blank, are left
+// unanswered and earn nothing.
+func gradeAnswers(questions []models.QuestionSnapshot, answers []dto_quiz.Answer) ([]gradedAnswer, float64, error) {
+	byID := make(map[string]*models.QuestionSnapshot, len(questions))
+	for i := range questions {
+		byID[questions[i].ID] = &questions[i]
+	}
+
+	graded := make([]gradedAnswer, 0, len(answers))
+	seen := make(map[string]bool, len(answers))
+	score := 0.0
+
+	for _, answer := range answers {
+		question, ok := byID[answer.QuestionID]
+		if !ok {
+			return nil, 0, sharedErrors.BadRequest(sharedErrors.ErrUnknownQuestion, "answer refers to a question that is not part of this attempt")
+		}
+		if seen[answer.QuestionID] {
+			return nil, 0, sharedErrors.BadRequest(sharedErrors.ErrDuplicateAnswer, "question answered more than once")
+		}
+		seen[answer.QuestionID] = true
+		if answerBlank(answer) {
+			continue
+		}
+
+		grader := graderFor(question)
+		r, err := grader.parse(question, answer)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		points := earnedPoints(question, grader.score(question, r))
+		score += points
+		graded = append(graded, gradedAnswer{
+			QuestionID: question.ID,
+			Response:   r,
+			Points:     points,
+		})
+	}
+
+	return graded, roundPoints(score), nil
+}
+
+// answerBlank reports whether an answer picks nothing at all.
+func answerBlank(answer dto_quiz.Answer) bool {
+	return answer.OptionID == "" &&
+		len(answer.OptionIDs) == 0 &&
+		answer.Value == nil &&
+		strings.TrimSpace(answer.Text) == "" &&
+		len(answer.Order) == 0 &&
+		len(answer.Matches) == 0
+}
+
+// flaggedQuestions lists the questions marked for review, in answer order.
+func flaggedQuestions(answers []dto_quiz.Answer) []string {
+	flagged := make([]string, 0)
+	for _, a := range answers {
+		if a.Flagged {
+			flagged = append(flagged, a.QuestionID)
+		}
+	}
+	return flagged
+}
+
+// answerResult says how a question was answered from the share of it
+// earned: correct for all of it, partial for some, wrong for none and
+// unanswered when nothing was given.
+func answerResult(answered bool, share float64) string {
+	switch {
+	case !answered:
+		return "unanswered"
+	case share == maxQuestionPoints:
+		return "correct"
+	case share > 0:
+		return "partial"
+	}
+	return "wrong"
+}
+
+// questionPoints is what a question is worth. Versions saved before points
+// existed count every question as one.
+func questionPoints(question *models.QuestionSnapshot) float64 {
+	if question.Points > 0 {
+		return question.Points
+	}
+	return maxQuestionPoints
+}
+
+// maxPointsPerQuestion bounds points and negative points, which are stored
+// as NUMERIC(6,2).
+const maxPointsPerQuestion = 1000
+
+// pointsOrDefault makes questions sent without points worth one.
+func pointsOrDefault(points float64) float64 {
+	if points == 0 {
+		return maxQuestionPoints
+	}
+	return roundPoints(points)
+}
+
+func validPoints(points, negative float64) error {
+	if points <= 0 || points > maxPointsPerQuestion {
+		return errors.New("points must be above 0 and at most " + strconv.Itoa(maxPointsPerQuestion))
+	}
+	if negative < 0 || negative > maxPointsPerQuestion {
+		return errors.New("negative_points must be between 0 and " + strconv.Itoa(maxPointsPerQuestion))
+	}
+	return nil
+}
+
+// earnedPoints weights the share of a question earned by its points. An
+// answer earning nothing costs the question's negative points instead.
+func earnedPoints(question *models.QuestionSnapshot, share float64) float64 {
+	if share == 0 {
+		return -question.NegativePoints
+	}
+	return roundPoints(share / maxQuestionPoints * questionPoints(question))
+}
+
+// maxPoints is what the questions are worth together.
+func maxPoints(questions []models.QuestionSnapshot) float64 {
+	total := 0.0
+	for i := range questions {
+		total += questionPoints(&questions[i])
+	}
+	return roundPoints(total)
+}
+
+// percentageOf is the share of max points earned. Negative marks can take
+// the points below zero, but never the percentage.
+func percentageOf(points, max float64) float64 {
+	if max <= 0 || points <= 0 {
+		return 0
+	}
+	return roundPoints(points / max * 100)
+}
+
+// answerRows flattens graded answers into user_answers rows: one per
+// selected, ordered or matched option, or a single text row.
+func answerRows(attemptID string, graded []gradedAnswer) []*models.UserAnwer {
+	rows := make([]*models.UserAnwer, 0, len(graded))
+	for _, g := range graded {
+		r := g.Response
+		switch {
+		case r.Text != nil:
+			rows = append(rows, &models.UserAnwer{AttemptID: attemptID, QuestionID: g.QuestionID, TextAnswer: r.Text})
+		case r.Order != nil:
+			for i, id := range r.Order {
+				rows = append(rows, &models.UserAnwer{AttemptID: attemptID, QuestionID: g.QuestionID, OptionID: &id, Position: &i})
+			}
+		case r.Matches != nil:
+			ids := make([]string, 0, len(r.Matches))
+			for id := range r.Matches {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			for _, id := range ids {
+				match := r.Matches[id]
+				rows = append(rows, &models.UserAnwer{AttemptID: attemptID, QuestionID: g.QuestionID, OptionID: &id, TextAnswer: &match})
+			}
+		default:
+			for _, id := range r.OptionIDs {
+				rows = append(rows, &models.UserAnwer{AttemptID: attemptID, QuestionID: g.QuestionID, OptionID: &id})
+			}
+		}
+	}
+	return rows
+}
+
+// responseFromRows is the inverse of answerRows for one question.
+func responseFromRows(rows []models.UserAnwer) response {
+	var r response
+	for _, row := range rows {
+		switch {
+		case row.OptionID == nil && row.TextAnswer != nil:
+			r.Text = row.TextAnswer
+		case row.OptionID != nil && row.Position != nil:
+			r.Order = append(r.Order, *row.OptionID)
+		case row.OptionID != nil && row.TextAnswer != nil:
+			if r.Matches == nil {
+				r.Matches = make(map[string]string, len(rows))
+			}
+			r.Matches[*row.OptionID] = *row.TextAnswer
+		case row.OptionID != nil:
+			r.OptionIDs = append(r.OptionIDs, *row.OptionID)
+		}
+	}
+	if r.Order != nil {
+		sort.SliceStable(r.Order, func(i, j int) bool {
+			return positionOf(rows, r.Order[i]) < positionOf(rows, r.Order[j])
+		})
+	}
+	return r
+}
+
+func positionOf(rows []models.UserAnwer, optionID string) int {
+	for _, row := range rows {
+		if row.OptionID != nil && *row.OptionID == optionID && row.Position != nil {
+			return *row.Position
+		}
+	}
+	return 0
+}
+
+// validateQuestion checks that a question about to be saved, together with
+// its options, can be graded as its type.
+func validateQuestion(question *models.Question, options []models.Option) error {
+	snapshot := models.QuestionSnapshot{
+		QuestionText:    question.QuestionText,
+		CorrectAnswer:   question.CorrectAnswer,
+		QuestionType:    question.QuestionType,
+		ScoringStrategy: question.ScoringStrategy,
+		Settings:        question.Settings,
+		Options:         make([]models.OptionSnapshot, 0, len(options)),
+	}
+	for _, o := range options {
+		snapshot.Options = append(snapshot.Options, models.OptionSnapshot{
+			ID:        o.ID,
+			Text:      o.Text,
+			IsCorrect: o.IsCorrect,
+			Position:  o.Position,
+			MatchText: o.MatchText,
+		})
+	}
+	sort.SliceStable(snapshot.Options, func(i, j int) bool {
+		return snapshot.Options[i].Position < snapshot.Options[j].Position
+	})
+	return graderFor(&snapshot).validate(&snapshot)
+}
+
+func questionSettings(req dto_quiz.QuestionSettings) models.QuestionSettings {
+	return models.QuestionSettings{
+		AcceptedAnswers: req.AcceptedAnswers,
+		MaxEditDistance: req.MaxEditDistance,
+		Tolerance:       req.Tolerance,
+		Unit:            req.Unit,
+		UnitFactors:     req.UnitFactors,
+	}
+}
+
+// questionKind validates a requested question type and scoring strategy,
+// filling in the defaults for whichever is left empty.
+func questionKind(qType, strategy string) (string, string, error) {
+	if qType == "" {
+		qType = models.QuestionSingleChoice
+	}
+	if _, ok := questionGraders[qType]; !ok {
+		return "", "", sharedErrors.BadRequest(sharedErrors.ErrInvalidQuestionType, "unknown question type: "+qType)
+	}
+
+	switch strategy {
+	case "":
+		strategy = models.ScoringAllOrNothing
+	case models.ScoringAllOrNothing, models.ScoringProportional, models.ScoringRightMinusWrong:
+	default:
+		return "", "", sharedErrors.BadRequest(sharedErrors.ErrInvalidQuestionType, "unknown scoring strategy: "+strategy)
+	}
+
+	return qType, strategy, nil
+}
+
+// questionType defaults snapshots taken before question types existed.
+func questionType(question *models.QuestionSnapshot) string {
+	if question.QuestionType == "" {
+		return models.QuestionSingleChoice
+	}
+	return question.QuestionType
+}
+
+func scoringStrategy(question *models.QuestionSnapshot) string {
+	if question.ScoringStrategy == "" {
+		return models.ScoringAllOrNothing
+	}
+	return question.ScoringStrategy
+}
+
+// partialCredit applies the question's scoring strategy to an answer made of
+// total parts, of which correct are right. It is used by ordering and
+// matching questions, where every item is either in the right place or not.
+//
+//   - all_or_nothing: full points only when every part is right.
+//   - proportional: the share of parts that are right.
+//   - right_minus_wrong: right parts minus wrong ones, over the total, never
+//     below zero.
+func partialCredit(question *models.QuestionSnapshot, correct, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	switch scoringStrategy(question) {
+	case models.ScoringProportional:
+		return roundPoints(maxQuestionPoints * float64(correct) / float64(total))
+	case models.ScoringRightMinusWrong:
+		wrong := total - correct
+		return roundPoints(math.Max(0, maxQuestionPoints*float64(correct-wrong)/float64(total)))
+	default:
+		if correct == total {
+			return maxQuestionPoints
+		}
+		return 0
+	}
+}
+
+func findOption(options []models.OptionSnapshot, optionID string) *models.OptionSnapshot {
+	for i := range options {
+		if options[i].ID == optionID {
+			return &options[i]
+		}
+	}
+	return nil
+}
+
+// normalizeText makes typed answers comparable: case and runs of whitespace
+// are ignored.
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// roundPoints keeps scores at the two decimals the database stores.
+func roundPoints(points float64) float64 {
+	return math.Round(points*100) / 100
+}
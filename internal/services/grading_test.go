@@ -0,0 +1,209 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+)
+
+func gradingQuestions() []models.QuestionSnapshot {
+	return []models.QuestionSnapshot{
+		{
+			ID:           "q1",
+			QuestionType: models.QuestionTrueFalse,
+			// Versions saved before points existed count the question as one.
+			CorrectAnswer: "true",
+		},
+		{
+			ID:             "q2",
+			QuestionType:   models.QuestionShortAnswer,
+			CorrectAnswer:  "Paris",
+			Points:         2,
+			NegativePoints: 0.5,
+		},
+		{
+			ID:              "q3",
+			QuestionType:    models.QuestionMultipleChoice,
+			ScoringStrategy: models.ScoringRightMinusWrong,
+			Points:          3,
+			Options: []models.OptionSnapshot{
+				{ID: "a", IsCorrect: true},
+				{ID: "b", IsCorrect: true},
+				{ID: "c"},
+			},
+		},
+	}
+}
+
+func TestGradeAnswers(t *testing.T) {
+	tests := []struct {
+		name      string
+		answers   []dto_quiz.Answer
+		wantScore float64
+		wantPts   map[string]float64
+		wantCode  string
+	}{
+		{
+			name: "all right",
+			answers: []dto_quiz.Answer{
+				{QuestionID: "q1", Value: boolPtr(true)},
+				{QuestionID: "q2", Text: "paris"},
+				{QuestionID: "q3", OptionIDs: []string{"a", "b"}},
+			},
+			wantScore: 6,
+			wantPts:   map[string]float64{"q1": 1, "q2": 2, "q3": 3},
+		},
+		{
+			name: "negative points and partial credit",
+			answers: []dto_quiz.Answer{
+				{QuestionID: "q1", Value: boolPtr(false)},
+				{QuestionID: "q2", Text: "London"},
+				{QuestionID: "q3", OptionIDs: []string{"a"}},
+			},
+			wantScore: 1,
+			wantPts:   map[string]float64{"q1": 0, "q2": -0.5, "q3": 1.5},
+		},
+		{
+			name: "skipped and blank questions cost nothing",
+			answers: []dto_quiz.Answer{
+				{QuestionID: "q2", Text: "   "},
+				{QuestionID: "q3", OptionIDs: []string{"a", "b"}},
+			},
+			wantScore: 3,
+			wantPts:   map[string]float64{"q3": 3},
+		},
+		{
+			name: "score below zero",
+			answers: []dto_quiz.Answer{
+				{QuestionID: "q2", Text: "Rome"},
+			},
+			wantScore: -0.5,
+			wantPts:   map[string]float64{"q2": -0.5},
+		},
+		{
+			name:     "question outside the attempt",
+			answers:  []dto_quiz.Answer{{QuestionID: "q9", Value: boolPtr(true)}},
+			wantCode: sharedErrors.ErrUnknownQuestion,
+		},
+		{
+			name: "question answered twice",
+			answers: []dto_quiz.Answer{
+				{QuestionID: "q1", Value: boolPtr(true)},
+				{QuestionID: "q1", Value: boolPtr(false)},
+			},
+			wantCode: sharedErrors.ErrDuplicateAnswer,
+		},
+		{
+			name:     "answer that does not fit the question",
+			answers:  []dto_quiz.Answer{{QuestionID: "q3", OptionIDs: []string{"z"}}},
+			wantCode: sharedErrors.ErrOptionNotInQuestion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graded, score, err := gradeAnswers(gradingQuestions(), tt.answers)
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("gradeAnswers() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("gradeAnswers() error = %v", err)
+			}
+			if score != tt.wantScore {
+				t.Fatalf("gradeAnswers() score = %v, want %v", score, tt.wantScore)
+			}
+			points := make(map[string]float64, len(graded))
+			for _, g := range graded {
+				points[g.QuestionID] = g.Points
+			}
+			if !reflect.DeepEqual(points, tt.wantPts) {
+				t.Fatalf("gradeAnswers() points = %v, want %v", points, tt.wantPts)
+			}
+		})
+	}
+}
+
+func TestPartialCredit(t *testing.T) {
+	tests := []struct {
+		strategy       string
+		correct, total int
+		want           float64
+	}{
+		{models.ScoringAllOrNothing, 3, 3, 1},
+		{models.ScoringAllOrNothing, 2, 3, 0},
+		{"", 2, 3, 0},
+		{models.ScoringProportional, 2, 3, 0.67},
+		{models.ScoringProportional, 0, 3, 0},
+		{models.ScoringRightMinusWrong, 3, 4, 0.5},
+		{models.ScoringRightMinusWrong, 1, 4, 0},
+		{models.ScoringProportional, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		question := &models.QuestionSnapshot{ScoringStrategy: tt.strategy}
+		if got := partialCredit(question, tt.correct, tt.total); got != tt.want {
+			t.Errorf("partialCredit(%q, %d, %d) = %v, want %v", tt.strategy, tt.correct, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestPointsAndPercentage(t *testing.T) {
+	questions := gradingQuestions()
+	if got := maxPoints(questions); got != 6 {
+		t.Fatalf("maxPoints() = %v, want 6", got)
+	}
+
+	tests := []struct {
+		points, max, want float64
+	}{
+		{3, 6, 50},
+		{2, 3, 66.67},
+		{-1, 6, 0},
+		{5, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := percentageOf(tt.points, tt.max); got != tt.want {
+			t.Errorf("percentageOf(%v, %v) = %v, want %v", tt.points, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestAnswerRowsRoundTrip(t *testing.T) {
+	text := "Paris"
+	tests := []struct {
+		name string
+		r    response
+	}{
+		{"options", response{OptionIDs: []string{"a", "b"}}},
+		{"text", response{Text: &text}},
+		{"order", response{Order: []string{"o3", "o1", "o2"}}},
+		{"matches", response{Matches: map[string]string{"m2": "CO2", "m1": "H2O"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := answerRows("attempt-1", []gradedAnswer{{QuestionID: "q1", Response: tt.r}})
+			stored := make([]models.UserAnwer, 0, len(rows))
+			// Rows come back from the database in no particular order.
+			for i := len(rows) - 1; i >= 0; i-- {
+				stored = append(stored, *rows[i])
+			}
+
+			got := responseFromRows(stored)
+			if tt.r.OptionIDs != nil {
+				if len(got.OptionIDs) != len(tt.r.OptionIDs) {
+					t.Fatalf("responseFromRows() = %+v, want %+v", got, tt.r)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.r) {
+				t.Fatalf("responseFromRows() = %+v, want %+v", got, tt.r)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+)
+
+func invalidQuestion(msg string) error {
+	return sharedErrors.BadRequest(sharedErrors.ErrInvalidQuestion, msg)
+}
+
+func invalidAnswer(msg string) error {
+	return sharedErrors.BadRequest(sharedErrors.ErrInvalidAnswer, msg)
+}
+
+// noOptions is shared by the types that are graded against CorrectAnswer.
+func noOptions(question *models.QuestionSnapshot) error {
+	if len(question.Options) > 0 {
+		return invalidQuestion(questionType(question) + " questions take no options")
+	}
+	return nil
+}
+
+// choiceGrader handles single_choice and multiple_choice questions.
+type choiceGrader struct{}
+
+func (choiceGrader) validate(question *models.QuestionSnapshot) error {
+	if len(question.Options) < 2 {
+		return invalidQuestion(questionType(question) + " questions need at least two options")
+	}
+	correct := 0
+	for _, o := range question.Options {
+		if o.IsCorrect {
+			correct++
+		}
+	}
+	if correct == 0 {
+		return invalidQuestion(questionType(question) + " questions need at least one correct option")
+	}
+	if questionType(question) == models.QuestionSingleChoice && correct > 1 {
+		return invalidQuestion("single_choice questions take exactly one correct option")
+	}
+	return nil
+}
+
+func (choiceGrader) present(question *models.QuestionSnapshot, take *dto_quiz.QuestionTake) {
+	for _, o := range question.Options {
+		take.Options = append(take.Options, dto_quiz.OptionTake{OptionID: o.ID, Text: o.Text})
+	}
+}
+
+// parse merges option_id and option_ids and checks that each one belongs to
+// the question, appears once, and that single-choice questions get exactly
+// one.
+func (choiceGrader) parse(question *models.QuestionSnapshot, answer dto_quiz.Answer) (response, error) {
+	ids := answer.OptionIDs
+	if answer.OptionID != "" {
+		ids = append([]string{answer.OptionID}, ids...)
+	}
+	if len(ids) == 0 {
+		return response{}, sharedErrors.BadRequest(sharedErrors.ErrInvalidSelection, "select at least one option")
+	}
+	if questionType(question) == models.QuestionSingleChoice && len(ids) > 1 {
+		return response{}, sharedErrors.BadRequest(sharedErrors.ErrInvalidSelection, "single-choice questions take exactly one option")
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return response{}, sharedErrors.BadRequest(sharedErrors.ErrDuplicateOption, "option selected more than once")
+		}
+		seen[id] = true
+		if findOption(question.Options, id) == nil {
+			return response{}, sharedErrors.BadRequest(sharedErrors.ErrOptionNotInQuestion, "selected option does not belong to the question")
+		}
+	}
+	return response{OptionIDs: ids}, nil
+}
+
+// score grades the selected options from their IsCorrect flags.
+//
+//   - all_or_nothing: full points only for exactly the correct set.
+//   - proportional: the share of options classified correctly, i.e. correct
+//     ones picked plus incorrect ones left alone.
+//   - right_minus_wrong: correct picks minus incorrect picks, over the number
+//     of correct options, never below zero.
+//
+// Single-choice questions earn full points when the one pick is correct.
+func (choiceGrader) score(question *models.QuestionSnapshot, r response) float64 {
+	picked := make(map[string]bool, len(r.OptionIDs))
+	for _, id := range r.OptionIDs {
+		picked[id] = true
+	}
+
+	correctTotal, right, wrong := 0, 0, 0
+	for _, o := range question.Options {
+		switch {
+		case o.IsCorrect:
+			correctTotal++
+			if picked[o.ID] {
+				right++
+			}
+		case picked[o.ID]:
+			wrong++
+		}
+	}
+
+	if questionType(question) == models.QuestionSingleChoice {
+		if right == 1 && wrong == 0 {
+			return maxQuestionPoints
+		}
+		return 0
+	}
+
+	allOrNothing := 0.0
+	if right == correctTotal && wrong == 0 && len(r.OptionIDs) > 0 {
+		allOrNothing = maxQuestionPoints
+	}
+
+	switch scoringStrategy(question) {
+	case models.ScoringProportional:
+		if len(question.Options) == 0 {
+			return 0
+		}
+		incorrectTotal := len(question.Options) - correctTotal
+		classified := right + (incorrectTotal - wrong)
+		return roundPoints(maxQuestionPoints * float64(classified) / float64(len(question.Options)))
+	case models.ScoringRightMinusWrong:
+		if correctTotal == 0 {
+			return 0
+		}
+		return roundPoints(math.Max(0, maxQuestionPoints*float64(right-wrong)/float64(correctTotal)))
+	default:
+		return allOrNothing
+	}
+}
+
+// trueFalseGrader compares a boolean answer with CorrectAnswer, which holds
+// "true" or "false".
+type trueFalseGrader struct{}
+
+func (trueFalseGrader) validate(question *models.QuestionSnapshot) error {
+	if _, err := strconv.ParseBool(strings.TrimSpace(question.CorrectAnswer)); err != nil {
+		return invalidQuestion("true_false questions need correct_answer \"true\" or \"false\"")
+	}
+	return noOptions(question)
+}
+
+func (trueFalseGrader) present(question *models.QuestionSnapshot, take *dto_quiz.QuestionTake) {}
+
+func (trueFalseGrader) parse(question *models.QuestionSnapshot, answer dto_quiz.Answer) (response, error) {
+	if answer.Value == nil {
+		return response{}, invalidAnswer("true_false questions take a boolean value")
+	}
+	text := strconv.FormatBool(*answer.Value)
+	return response{Text: &text}, nil
+}
+
+func (trueFalseGrader) score(question *models.QuestionSnapshot, r response) float64 {
+	if r.Text == nil {
+		return 0
+	}
+	want, err := strconv.ParseBool(strings.TrimSpace(question.CorrectAnswer))
+	got, gotErr := strconv.ParseBool(*r.Text)
+	if err != nil || gotErr != nil || want != got {
+		return 0
+	}
+	return maxQuestionPoints
+}
+
+// shortAnswerGrader accepts CorrectAnswer or any of the accepted answers,
+// ignoring case and spacing and forgiving up to MaxEditDistance typos.
+type shortAnswerGrader struct{}
+
+func (shortAnswerGrader) validate(question *models.QuestionSnapshot) error {
+	if normalizeText(question.CorrectAnswer) == "" {
+		return invalidQuestion("short_answer questions need a correct_answer")
+	}
+	if question.Settings.MaxEditDistance < 0 {
+		return invalidQuestion("max_edit_distance cannot be negative")
+	}
+	return noOptions(question)
+}
+
+func (shortAnswerGrader) present(question *models.QuestionSnapshot, take *dto_quiz.QuestionTake) {}
+
+func (shortAnswerGrader) parse(question *models.QuestionSnapshot, answer dto_quiz.Answer) (response, error) {
+	if normalizeText(answer.Text) == "" {
+		return response{}, invalidAnswer("short_answer questions take a text answer")
+	}
+	text := answer.Text
+	return response{Text: &text}, nil
+}
+
+func (shortAnswerGrader) score(question *models.QuestionSnapshot, r response) float64 {
+	if r.Text == nil {
+		return 0
+	}
+	got := normalizeText(*r.Text)
+	accepted := append([]string{question.CorrectAnswer}, question.Settings.AcceptedAnswers...)
+	for _, a := range accepted {
+		want := normalizeText(a)
+		if want == "" {
+			continue
+		}
+		if got == want || editDistance(got, want) <= question.Settings.MaxEditDistance {
+			return maxQuestionPoints
+		}
+	}
+	return 0
+}
+
+// editDistance is the Levenshtein distance between a and b, in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// numericGrader accepts a number within Tolerance of CorrectAnswer. The
+// answer may carry a unit: none or Unit is taken as is, a key of UnitFactors
+// is converted to Unit first, and any other unit is wrong.
+type numericGrader struct{}
+
+var numericAnswer = regexp.MustCompile(`^([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)\s*(.*)$`)
+
+func (numericGrader) validate(question *models.QuestionSnapshot) error {
+	if _, err := strconv.ParseFloat(strings.TrimSpace(question.CorrectAnswer), 64); err != nil {
+		return invalidQuestion("numeric questions need a number as correct_answer")
+	}
+	if question.Settings.Tolerance < 0 {
+		return invalidQuestion("tolerance cannot be negative")
+	}
+	for unit, factor := range question.Settings.UnitFactors {
+		if factor <= 0 {
+			return invalidQuestion("unit factor for " + unit + " must be positive")
+		}
+	}
+	return noOptions(question)
+}
+
+func (numericGrader) present(question *models.QuestionSnapshot, take *dto_quiz.QuestionTake) {
+	take.Unit = question.Settings.Unit
+}
+
+func (numericGrader) parse(question *models.QuestionSnapshot, answer dto_quiz.Answer) (response, error) {
+	if _, _, ok := parseNumber(answer.Text); !ok {
+		return response{}, invalidAnswer("numeric questions take a number, optionally followed by a unit")
+	}
+	text := strings.TrimSpace(answer.Text)
+	return response{Text: &text}, nil
+}
+
+func (numericGrader) score(question *models.QuestionSnapshot, r response) float64 {
+	if r.Text == nil {
+		return 0
+	}
+	want, err := strconv.ParseFloat(strings.TrimSpace(question.CorrectAnswer), 64)
+	if err != nil {
+		return 0
+	}
+	value, unit, ok := parseNumber(*r.Text)
+	if !ok {
+		return 0
+	}
+
+	factor := 1.0
+	if unit != "" && !strings.EqualFold(unit, question.Settings.Unit) {
+		f, known := unitFactor(question.Settings.UnitFactors, unit)
+		if !known {
+			return 0
+		}
+		factor = f
+	}
+
+	if math.Abs(value*factor-want) <= question.Settings.Tolerance+1e-9 {
+		return maxQuestionPoints
+	}
+	return 0
+}
+
+func parseNumber(s string) (float64, string, bool) {
+	m := numericAnswer.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, "", false
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return value, strings.TrimSpace(m[2]), true
+}
+
+func unitFactor(factors map[string]float64, unit string) (float64, bool) {
+	for name, factor := range factors {
+		if strings.EqualFold(name, unit) {
+			return factor, true
+		}
+	}
+	return 0, false
+}
+
+// orderingGrader asks for the options in their correct order, which is their
+// position, i.e. the order they were created in.
+type orderingGrader struct{}
+
+func (orderingGrader) validate(question *models.QuestionSnapshot) error {
+	if len(question.Options) < 2 {
+		return invalidQuestion("ordering questions need at least two options")
+	}
+	return nil
+}
+
+// present lists the items as attemptQuestions scrambled them.
+func (orderingGrader) present(question *models.QuestionSnapshot, take *dto_quiz.QuestionTake) {
+	for _, o := range question.Options {
+		take.Options = append(take.Options, dto_quiz.OptionTake{OptionID: o.ID, Text: o.Text})
+	}
+}
+
+func (orderingGrader) parse(question *models.QuestionSnapshot, answer dto_quiz.Answer) (response, error) {
+	if len(answer.Order) != len(question.Options) {
+		return response{}, invalidAnswer("order must list every option of the question once")
+	}
+	seen := make(map[string]bool, len(answer.Order))
+	for _, id := range answer.Order {
+		if seen[id] {
+			return response{}, sharedErrors.BadRequest(sharedErrors.ErrDuplicateOption, "option listed more than once")
+		}
+		seen[id] = true
+		if findOption(question.Options, id) == nil {
+			return response{}, sharedErrors.BadRequest(sharedErrors.ErrOptionNotInQuestion, "listed option does not belong to the question")
+		}
+	}
+	return response{Order: answer.Order}, nil
+}
+
+// score counts the items placed where they belong.
+func (orderingGrader) score(question *models.QuestionSnapshot, r response) float64 {
+	correct := 0
+	for i, o := range correctOrder(question) {
+		if i < len(r.Order) && r.Order[i] == o.ID {
+			correct++
+		}
+	}
+	return partialCredit(question, correct, len(question.Options))
+}
+
+func correctOrder(question *models.QuestionSnapshot) []models.OptionSnapshot {
+	options := append([]models.OptionSnapshot(nil), question.Options...)
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Position < options[j].Position })
+	return options
+}
+
+// matchingGrader pairs every option with its MatchText. Learners pick from
+// the sorted match texts rather than option IDs, so the pairs stay hidden.
+type matchingGrader struct{}
+
+func (matchingGrader) validate(question *models.QuestionSnapshot) error {
+	if len(question.Options) < 2 {
+		return invalidQuestion("matching questions need at least two options")
+	}
+	for _, o := range question.Options {
+		if o.MatchText == nil || normalizeText(*o.MatchText) == "" {
+			return invalidQuestion("every option of a matching question needs a match_text")
+		}
+	}
+	return nil
+}
+
+func (matchingGrader) present(question *models.QuestionSnapshot, take *dto_quiz.QuestionTake) {
+	seen := make(map[string]bool, len(question.Options))
+	for _, o := range question.Options {
+		take.Options = append(take.Options, dto_quiz.OptionTake{OptionID: o.ID, Text: o.Text})
+		if o.MatchText != nil && !seen[*o.MatchText] {
+			seen[*o.MatchText] = true
+			take.MatchChoices = append(take.MatchChoices, *o.MatchText)
+		}
+	}
+	sort.Strings(take.MatchChoices)
+}
+
+func (matchingGrader) parse(question *models.QuestionSnapshot, answer dto_quiz.Answer) (response, error) {
+	if len(answer.Matches) != len(question.Options) {
+		return response{}, invalidAnswer("matches must pair every option of the question once")
+	}
+	choices := make(map[string]bool, len(question.Options))
+	for _, o := range question.Options {
+		if o.MatchText != nil {
+			choices[normalizeText(*o.MatchText)] = true
+		}
+	}
+
+	matches := make(map[string]string, len(answer.Matches))
+	for _, m := range answer.Matches {
+		if _, dup := matches[m.OptionID]; dup {
+			return response{}, sharedErrors.BadRequest(sharedErrors.ErrDuplicateOption, "option matched more than once")
+		}
+		if findOption(question.Options, m.OptionID) == nil {
+			return response{}, sharedErrors.BadRequest(sharedErrors.ErrOptionNotInQuestion, "matched option does not belong to the question")
+		}
+		if !choices[normalizeText(m.Match)] {
+			return response{}, invalidAnswer("match is not one of the question's match_choices")
+		}
+		matches[m.OptionID] = m.Match
+	}
+	return response{Matches: matches}, nil
+}
+
+func (matchingGrader) score(question *models.QuestionSnapshot, r response) float64 {
+	correct := 0
+	for _, o := range question.Options {
+		if matchCorrect(&o, r.Matches) {
+			correct++
+		}
+	}
+	return partialCredit(question, correct, len(question.Options))
+}
+
+func matchCorrect(option *models.OptionSnapshot, matches map[string]string) bool {
+	match, ok := matches[option.ID]
+	return ok && option.MatchText != nil && normalizeText(match) == normalizeText(*option.MatchText)
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+)
+
+// errCode is the AppError code of err, or "" when there is none.
+func errCode(err error) string {
+	var appErr *sharedErrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
+
+func strPtr(s string) *string { return &s }
+
+func boolPtr(b bool) *bool { return &b }
+
+func choiceQuestion(qType, strategy string) *models.QuestionSnapshot {
+	return &models.QuestionSnapshot{
+		ID:              "q-choice",
+		QuestionType:    qType,
+		ScoringStrategy: strategy,
+		Options: []models.OptionSnapshot{
+			{ID: "a", IsCorrect: true, Position: 0},
+			{ID: "b", IsCorrect: true, Position: 1},
+			{ID: "c", Position: 2},
+			{ID: "d", Position: 3},
+		},
+	}
+}
+
+func TestChoiceGraderParse(t *testing.T) {
+	single := &models.QuestionSnapshot{
+		QuestionType: models.QuestionSingleChoice,
+		Options: []models.OptionSnapshot{
+			{ID: "x", IsCorrect: true},
+			{ID: "y"},
+		},
+	}
+	multiple := choiceQuestion(models.QuestionMultipleChoice, models.ScoringAllOrNothing)
+
+	tests := []struct {
+		name     string
+		question *models.QuestionSnapshot
+		answer   dto_quiz.Answer
+		want     []string
+		wantCode string
+	}{
+		{
+			name:     "single option",
+			question: single,
+			answer:   dto_quiz.Answer{OptionID: "x"},
+			want:     []string{"x"},
+		},
+		{
+			name:     "option_id and option_ids are merged",
+			question: multiple,
+			answer:   dto_quiz.Answer{OptionID: "a", OptionIDs: []string{"c"}},
+			want:     []string{"a", "c"},
+		},
+		{
+			name:     "nothing selected",
+			question: multiple,
+			answer:   dto_quiz.Answer{},
+			wantCode: sharedErrors.ErrInvalidSelection,
+		},
+		{
+			name:     "two options for a single-choice question",
+			question: single,
+			answer:   dto_quiz.Answer{OptionIDs: []string{"x", "y"}},
+			wantCode: sharedErrors.ErrInvalidSelection,
+		},
+		{
+			name:     "option selected twice",
+			question: multiple,
+			answer:   dto_quiz.Answer{OptionID: "a", OptionIDs: []string{"a"}},
+			wantCode: sharedErrors.ErrDuplicateOption,
+		},
+		{
+			name:     "option of another question",
+			question: multiple,
+			answer:   dto_quiz.Answer{OptionIDs: []string{"a", "z"}},
+			wantCode: sharedErrors.ErrOptionNotInQuestion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := choiceGrader{}.parse(tt.question, tt.answer)
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("parse() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.OptionIDs, tt.want) {
+				t.Fatalf("parse() = %v, want %v", got.OptionIDs, tt.want)
+			}
+		})
+	}
+}
+
+func TestChoiceGraderScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		qType    string
+		strategy string
+		picked   []string
+		want     float64
+	}{
+		{"single choice, right", models.QuestionSingleChoice, "", []string{"a"}, 1},
+		{"single choice, wrong", models.QuestionSingleChoice, "", []string{"c"}, 0},
+		{"snapshot without a type is single choice", "", "", []string{"a"}, 1},
+
+		{"all or nothing, exact set", models.QuestionMultipleChoice, models.ScoringAllOrNothing, []string{"a", "b"}, 1},
+		{"all or nothing, one missing", models.QuestionMultipleChoice, models.ScoringAllOrNothing, []string{"a"}, 0},
+		{"all or nothing, one extra", models.QuestionMultipleChoice, models.ScoringAllOrNothing, []string{"a", "b", "c"}, 0},
+		{"default strategy is all or nothing", models.QuestionMultipleChoice, "", []string{"a"}, 0},
+
+		{"proportional, exact set", models.QuestionMultipleChoice, models.ScoringProportional, []string{"a", "b"}, 1},
+		{"proportional, one missing", models.QuestionMultipleChoice, models.ScoringProportional, []string{"a"}, 0.75},
+		{"proportional, one right one wrong", models.QuestionMultipleChoice, models.ScoringProportional, []string{"a", "c"}, 0.5},
+		{"proportional, all wrong", models.QuestionMultipleChoice, models.ScoringProportional, []string{"c", "d"}, 0},
+
+		{"right minus wrong, exact set", models.QuestionMultipleChoice, models.ScoringRightMinusWrong, []string{"a", "b"}, 1},
+		{"right minus wrong, one missing", models.QuestionMultipleChoice, models.ScoringRightMinusWrong, []string{"a"}, 0.5},
+		{"right minus wrong, one extra", models.QuestionMultipleChoice, models.ScoringRightMinusWrong, []string{"a", "b", "c"}, 0.5},
+		{"right minus wrong, cancelled out", models.QuestionMultipleChoice, models.ScoringRightMinusWrong, []string{"a", "c"}, 0},
+		{"right minus wrong, never below zero", models.QuestionMultipleChoice, models.ScoringRightMinusWrong, []string{"c", "d"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := choiceQuestion(tt.qType, tt.strategy)
+			if tt.qType != models.QuestionMultipleChoice {
+				question.Options[1].IsCorrect = false
+			}
+			got := choiceGrader{}.score(question, response{OptionIDs: tt.picked})
+			if got != tt.want {
+				t.Fatalf("score(%v) = %v, want %v", tt.picked, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrueFalseGrader(t *testing.T) {
+	question := &models.QuestionSnapshot{QuestionType: models.QuestionTrueFalse, CorrectAnswer: " False "}
+
+	tests := []struct {
+		name     string
+		value    *bool
+		want     float64
+		wantCode string
+	}{
+		{name: "right", value: boolPtr(false), want: 1},
+		{name: "wrong", value: boolPtr(true), want: 0},
+		{name: "no value", wantCode: sharedErrors.ErrInvalidAnswer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := trueFalseGrader{}.parse(question, dto_quiz.Answer{Value: tt.value})
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("parse() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got := (trueFalseGrader{}).score(question, r); got != tt.want {
+				t.Fatalf("score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortAnswerGrader(t *testing.T) {
+	question := &models.QuestionSnapshot{
+		QuestionType:  models.QuestionShortAnswer,
+		CorrectAnswer: "Photosynthesis",
+		Settings: models.QuestionSettings{
+			AcceptedAnswers: []string{"light synthesis", "  "},
+			MaxEditDistance: 2,
+		},
+	}
+	strict := &models.QuestionSnapshot{
+		QuestionType:  models.QuestionShortAnswer,
+		CorrectAnswer: "Paris",
+	}
+
+	tests := []struct {
+		name     string
+		question *models.QuestionSnapshot
+		text     string
+		want     float64
+		wantCode string
+	}{
+		{name: "exact", question: question, text: "Photosynthesis", want: 1},
+		{name: "case and spacing ignored", question: question, text: "  PHOTOSYNTHESIS ", want: 1},
+		{name: "accepted answer", question: question, text: "Light   Synthesis", want: 1},
+		{name: "typos within the distance", question: question, text: "fotosynthesis", want: 1},
+		{name: "split word counts as one typo", question: question, text: "photo synthesis", want: 1},
+		{name: "too many typos", question: question, text: "fotosintesis", want: 0},
+		{name: "blank accepted answers never match", question: question, text: "x", want: 0},
+		{name: "no typos allowed", question: strict, text: "Pariss", want: 0},
+		{name: "no typos allowed, exact", question: strict, text: "paris", want: 1},
+		{name: "blank answer", question: question, text: "   ", wantCode: sharedErrors.ErrInvalidAnswer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := shortAnswerGrader{}.parse(tt.question, dto_quiz.Answer{Text: tt.text})
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("parse() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got := (shortAnswerGrader{}).score(tt.question, r); got != tt.want {
+				t.Fatalf("score(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNumericGrader(t *testing.T) {
+	question := &models.QuestionSnapshot{
+		QuestionType:  models.QuestionNumeric,
+		CorrectAnswer: "1500",
+		Settings: models.QuestionSettings{
+			Tolerance:   5,
+			Unit:        "m",
+			UnitFactors: map[string]float64{"km": 1000, "cm": 0.01},
+		},
+	}
+	exact := &models.QuestionSnapshot{
+		QuestionType:  models.QuestionNumeric,
+		CorrectAnswer: "0.3",
+	}
+
+	tests := []struct {
+		name     string
+		question *models.QuestionSnapshot
+		text     string
+		want     float64
+		wantCode string
+	}{
+		{name: "exact, no unit", question: question, text: "1500", want: 1},
+		{name: "within tolerance", question: question, text: "1504.9", want: 1},
+		{name: "at the tolerance", question: question, text: "1495", want: 1},
+		{name: "outside tolerance", question: question, text: "1506", want: 0},
+		{name: "question's unit", question: question, text: "1500 m", want: 1},
+		{name: "question's unit in another case", question: question, text: "1500M", want: 1},
+		{name: "converted unit", question: question, text: "1.5 km", want: 1},
+		{name: "converted unit in another case", question: question, text: "1.5KM", want: 1},
+		{name: "converted unit outside tolerance", question: question, text: "1.51 km", want: 0},
+		{name: "small converted unit", question: question, text: "150000 cm", want: 1},
+		{name: "unknown unit", question: question, text: "1500 ft", want: 0},
+		{name: "exponent", question: question, text: "1.5e3", want: 1},
+		{name: "signed", question: question, text: "+1500", want: 1},
+		{name: "no tolerance, float rounding", question: exact, text: "0.30000000000000004", want: 1},
+		{name: "no tolerance, leading dot", question: exact, text: ".3", want: 1},
+		{name: "no tolerance, off", question: exact, text: "0.31", want: 0},
+		{name: "not a number", question: question, text: "fifteen hundred", wantCode: sharedErrors.ErrInvalidAnswer},
+		{name: "unit only", question: question, text: "km", wantCode: sharedErrors.ErrInvalidAnswer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := numericGrader{}.parse(tt.question, dto_quiz.Answer{Text: tt.text})
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("parse() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got := (numericGrader{}).score(tt.question, r); got != tt.want {
+				t.Fatalf("score(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// orderingQuestion lists its options out of order; their positions give the
+// correct order o1, o2, o3, o4.
+func orderingQuestion(strategy string) *models.QuestionSnapshot {
+	return &models.QuestionSnapshot{
+		QuestionType:    models.QuestionOrdering,
+		ScoringStrategy: strategy,
+		Options: []models.OptionSnapshot{
+			{ID: "o3", Position: 2},
+			{ID: "o1", Position: 0},
+			{ID: "o4", Position: 3},
+			{ID: "o2", Position: 1},
+		},
+	}
+}
+
+func TestOrderingGraderParse(t *testing.T) {
+	question := orderingQuestion("")
+
+	tests := []struct {
+		name     string
+		order    []string
+		wantCode string
+	}{
+		{name: "every option once", order: []string{"o4", "o3", "o2", "o1"}},
+		{name: "option missing", order: []string{"o1", "o2", "o3"}, wantCode: sharedErrors.ErrInvalidAnswer},
+		{name: "option listed twice", order: []string{"o1", "o1", "o2", "o3"}, wantCode: sharedErrors.ErrDuplicateOption},
+		{name: "option of another question", order: []string{"o1", "o2", "o3", "x"}, wantCode: sharedErrors.ErrOptionNotInQuestion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := orderingGrader{}.parse(question, dto_quiz.Answer{Order: tt.order})
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("parse() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(r.Order, tt.order) {
+				t.Fatalf("parse() = %v, want %v", r.Order, tt.order)
+			}
+		})
+	}
+}
+
+func TestOrderingGraderScore(t *testing.T) {
+	correct := []string{"o1", "o2", "o3", "o4"}
+	halfRight := []string{"o2", "o1", "o3", "o4"}
+	threeWrong := []string{"o1", "o3", "o4", "o2"}
+	noneRight := []string{"o4", "o3", "o2", "o1"}
+
+	tests := []struct {
+		name     string
+		strategy string
+		order    []string
+		want     float64
+	}{
+		{"all or nothing, correct", models.ScoringAllOrNothing, correct, 1},
+		{"all or nothing, two swapped", models.ScoringAllOrNothing, halfRight, 0},
+		{"proportional, correct", models.ScoringProportional, correct, 1},
+		{"proportional, two swapped", models.ScoringProportional, halfRight, 0.5},
+		{"proportional, one in place", models.ScoringProportional, threeWrong, 0.25},
+		{"proportional, none in place", models.ScoringProportional, noneRight, 0},
+		{"right minus wrong, correct", models.ScoringRightMinusWrong, correct, 1},
+		{"right minus wrong, two swapped", models.ScoringRightMinusWrong, halfRight, 0},
+		{"right minus wrong, never below zero", models.ScoringRightMinusWrong, threeWrong, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orderingGrader{}.score(orderingQuestion(tt.strategy), response{Order: tt.order})
+			if got != tt.want {
+				t.Fatalf("score(%v) = %v, want %v", tt.order, got, tt.want)
+			}
+		})
+	}
+}
+
+func matchingQuestion(strategy string) *models.QuestionSnapshot {
+	return &models.QuestionSnapshot{
+		QuestionType:    models.QuestionMatching,
+		ScoringStrategy: strategy,
+		Options: []models.OptionSnapshot{
+			{ID: "m1", Text: "water", MatchText: strPtr("H2O")},
+			{ID: "m2", Text: "carbon dioxide", MatchText: strPtr("CO2")},
+			{ID: "m3", Text: "salt", MatchText: strPtr("NaCl")},
+		},
+	}
+}
+
+func TestMatchingGraderParse(t *testing.T) {
+	question := matchingQuestion("")
+
+	tests := []struct {
+		name     string
+		matches  []dto_quiz.Match
+		wantCode string
+	}{
+		{
+			name:    "every option once",
+			matches: []dto_quiz.Match{{OptionID: "m1", Match: "CO2"}, {OptionID: "m2", Match: "h2o"}, {OptionID: "m3", Match: "NaCl"}},
+		},
+		{
+			name:     "option missing",
+			matches:  []dto_quiz.Match{{OptionID: "m1", Match: "H2O"}, {OptionID: "m2", Match: "CO2"}},
+			wantCode: sharedErrors.ErrInvalidAnswer,
+		},
+		{
+			name:     "option matched twice",
+			matches:  []dto_quiz.Match{{OptionID: "m1", Match: "H2O"}, {OptionID: "m1", Match: "CO2"}, {OptionID: "m3", Match: "NaCl"}},
+			wantCode: sharedErrors.ErrDuplicateOption,
+		},
+		{
+			name:     "option of another question",
+			matches:  []dto_quiz.Match{{OptionID: "m1", Match: "H2O"}, {OptionID: "m2", Match: "CO2"}, {OptionID: "x", Match: "NaCl"}},
+			wantCode: sharedErrors.ErrOptionNotInQuestion,
+		},
+		{
+			name:     "match that is not a choice",
+			matches:  []dto_quiz.Match{{OptionID: "m1", Match: "H2O"}, {OptionID: "m2", Match: "CO2"}, {OptionID: "m3", Match: "KCl"}},
+			wantCode: sharedErrors.ErrInvalidAnswer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := matchingGrader{}.parse(question, dto_quiz.Answer{Matches: tt.matches})
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("parse() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if len(r.Matches) != len(tt.matches) {
+				t.Fatalf("parse() = %v, want %d matches", r.Matches, len(tt.matches))
+			}
+		})
+	}
+}
+
+func TestMatchingGraderScore(t *testing.T) {
+	correct := map[string]string{"m1": "h2o", "m2": " CO2 ", "m3": "NaCl"}
+	oneRight := map[string]string{"m1": "CO2", "m2": "H2O", "m3": "NaCl"}
+	twoRight := map[string]string{"m1": "H2O", "m2": "CO2"}
+
+	tests := []struct {
+		name     string
+		strategy string
+		matches  map[string]string
+		want     float64
+	}{
+		{"all or nothing, correct ignoring case and spacing", models.ScoringAllOrNothing, correct, 1},
+		{"all or nothing, two swapped", models.ScoringAllOrNothing, oneRight, 0},
+		{"proportional, two swapped", models.ScoringProportional, oneRight, 0.33},
+		{"proportional, one left out", models.ScoringProportional, twoRight, 0.67},
+		{"right minus wrong, one left out", models.ScoringRightMinusWrong, twoRight, 0.33},
+		{"right minus wrong, never below zero", models.ScoringRightMinusWrong, oneRight, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchingGrader{}.score(matchingQuestion(tt.strategy), response{Matches: tt.matches})
+			if got != tt.want {
+				t.Fatalf("score(%v) = %v, want %v", tt.matches, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraderValidate(t *testing.T) {
+	twoOptions := func(correct ...bool) []models.OptionSnapshot {
+		options := make([]models.OptionSnapshot, len(correct))
+		for i, c := range correct {
+			options[i] = models.OptionSnapshot{ID: string(rune('a' + i)), IsCorrect: c, MatchText: strPtr("m")}
+		}
+		return options
+	}
+
+	tests := []struct {
+		name     string
+		question models.QuestionSnapshot
+		wantErr  bool
+	}{
+		{"single choice", models.QuestionSnapshot{QuestionType: models.QuestionSingleChoice, Options: twoOptions(true, false)}, false},
+		{"single choice with one option", models.QuestionSnapshot{QuestionType: models.QuestionSingleChoice, Options: twoOptions(true)}, true},
+		{"single choice with two correct", models.QuestionSnapshot{QuestionType: models.QuestionSingleChoice, Options: twoOptions(true, true)}, true},
+		{"multiple choice with two correct", models.QuestionSnapshot{QuestionType: models.QuestionMultipleChoice, Options: twoOptions(true, true)}, false},
+		{"multiple choice without a correct option", models.QuestionSnapshot{QuestionType: models.QuestionMultipleChoice, Options: twoOptions(false, false)}, true},
+		{"true/false", models.QuestionSnapshot{QuestionType: models.QuestionTrueFalse, CorrectAnswer: "true"}, false},
+		{"true/false with another answer", models.QuestionSnapshot{QuestionType: models.QuestionTrueFalse, CorrectAnswer: "yes"}, true},
+		{"true/false with options", models.QuestionSnapshot{QuestionType: models.QuestionTrueFalse, CorrectAnswer: "true", Options: twoOptions(true, false)}, true},
+		{"short answer", models.QuestionSnapshot{QuestionType: models.QuestionShortAnswer, CorrectAnswer: "Paris"}, false},
+		{"short answer without an answer", models.QuestionSnapshot{QuestionType: models.QuestionShortAnswer, CorrectAnswer: "  "}, true},
+		{"short answer with a negative distance", models.QuestionSnapshot{QuestionType: models.QuestionShortAnswer, CorrectAnswer: "Paris", Settings: models.QuestionSettings{MaxEditDistance: -1}}, true},
+		{"numeric", models.QuestionSnapshot{QuestionType: models.QuestionNumeric, CorrectAnswer: "9.81", Settings: models.QuestionSettings{Tolerance: 0.01}}, false},
+		{"numeric without a number", models.QuestionSnapshot{QuestionType: models.QuestionNumeric, CorrectAnswer: "ten"}, true},
+		{"numeric with a negative tolerance", models.QuestionSnapshot{QuestionType: models.QuestionNumeric, CorrectAnswer: "1", Settings: models.QuestionSettings{Tolerance: -1}}, true},
+		{"numeric with a zero unit factor", models.QuestionSnapshot{QuestionType: models.QuestionNumeric, CorrectAnswer: "1", Settings: models.QuestionSettings{UnitFactors: map[string]float64{"km": 0}}}, true},
+		{"ordering", models.QuestionSnapshot{QuestionType: models.QuestionOrdering, Options: twoOptions(false, false)}, false},
+		{"ordering with one option", models.QuestionSnapshot{QuestionType: models.QuestionOrdering, Options: twoOptions(false)}, true},
+		{"matching", models.QuestionSnapshot{QuestionType: models.QuestionMatching, Options: twoOptions(false, false)}, false},
+		{"matching without a match_text", models.QuestionSnapshot{QuestionType: models.QuestionMatching, Options: []models.OptionSnapshot{{ID: "a", MatchText: strPtr("x")}, {ID: "b"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := graderFor(&tt.question).validate(&tt.question)
+			if tt.wantErr {
+				if errCode(err) != sharedErrors.ErrInvalidQuestion {
+					t.Fatalf("validate() error = %v, want code %s", err, sharedErrors.ErrInvalidQuestion)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+		})
+	}
+}
@@ -6,9 +6,11 @@ import (
 	dto_quiz "ecoquiz/internal/dto/quiz"
 	"ecoquiz/internal/models"
 	"ecoquiz/internal/repos"
+	sharedErrors "ecoquiz/internal/shared/errors"
 	"ecoquiz/internal/utils"
 	"errors"
 	"fmt"
+	"strings"
 
 	"time"
 
@@ -16,29 +18,40 @@ import (
 )
 
 type QuizService struct {
-	quizRepo      repos.QuizRepo
-	questionRepo  repos.QuestionRepo
-	optionRepo    repos.OptionRepo
-	userRepo      repos.UserRepo
-	communityRepo repos.CommunityRepo
-	commentRepo   repos.CommentRepo
+	quizRepo        repos.QuizRepo
+	quizVersionRepo repos.QuizVersionRepo
+	questionRepo    repos.QuestionRepo
+	optionRepo      repos.OptionRepo
+	userRepo        repos.UserRepo
+	communityRepo   repos.CommunityRepo
+	commentRepo     repos.CommentRepo
+
+	requireVerifiedEmail bool
+	attemptGrace         time.Duration
 }
 
 func NewQuizService(
 	quizRepo repos.QuizRepo,
+	quizVersionRepo repos.QuizVersionRepo,
 	questionRepo repos.QuestionRepo,
 	optionRepo repos.OptionRepo,
 	userRepo repos.UserRepo,
 	communityRepo repos.CommunityRepo,
 	commentRepo repos.CommentRepo,
+	requireVerifiedEmail bool,
+	attemptGrace time.Duration,
 ) *QuizService {
 	return &QuizService{
-		quizRepo:      quizRepo,
-		questionRepo:  questionRepo,
-		optionRepo:    optionRepo,
-		userRepo:      userRepo,
-		communityRepo: communityRepo,
-		commentRepo:   commentRepo,
+		quizRepo:        quizRepo,
+		quizVersionRepo: quizVersionRepo,
+		questionRepo:    questionRepo,
+		optionRepo:      optionRepo,
+		userRepo:        userRepo,
+		communityRepo:   communityRepo,
+		commentRepo:     commentRepo,
+
+		requireVerifiedEmail: requireVerifiedEmail,
+		attemptGrace:         attemptGrace,
 	}
 }
 
@@ -47,13 +60,27 @@ func (s *QuizService) CreateQuiz(
 	userID string,
 	quizReq *dto_quiz.CreateQuizRequest,
 ) (string, error) {
-	_, err := s.userRepo.FindByID(ctx, userID)
+	return s.createQuiz(ctx, userID, quizReq, nil)
+}
+
+// createQuiz creates the quiz with its questions, options and pools in one
+// transaction. When forkOf is set the quiz is recorded as its fork.
+func (s *QuizService) createQuiz(
+	ctx context.Context,
+	userID string,
+	quizReq *dto_quiz.CreateQuizRequest,
+	forkOf *models.Quiz,
+) (string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err == pgx.ErrNoRows {
 		return "", errors.New("Unauthorized user")
 	}
 	if err != nil {
 		return "", errors.New("Failed to get User")
 	}
+	if s.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return "", sharedErrors.Forbidden(sharedErrors.ErrEmailNotVerified, "verify your email before creating quizzes")
+	}
 
 	if _, err := s.communityRepo.FindByID(ctx, quizReq.CommunityID); err != nil {
 		if err == pgx.ErrNoRows {
@@ -65,6 +92,11 @@ func (s *QuizService) CreateQuiz(
 		return "", errors.New("Quiz must have at least one question")
 	}
 
+	status, err := quizLifecycle(quizReq.Status, quizReq.IsPublished, quizReq.PublishAt, quizReq.CloseAt, time.Now())
+	if err != nil {
+		return "", err
+	}
+
 	tx, err := s.quizRepo.BeginTx(ctx)
 	if err != nil {
 		return "", errors.New("Failed to start transaction")
@@ -72,46 +104,69 @@ func (s *QuizService) CreateQuiz(
 	defer tx.Rollback(ctx)
 
 	quiz := models.Quiz{
-		CommunityID:     quizReq.CommunityID,
-		CreatorID:       userID,
-		Title:           quizReq.Title,
-		Description:     quizReq.Description,
-		DurationMinutes: quizReq.DurationMinutes,
-		IsPublished:     quizReq.IsPublished,
+		CommunityID:      quizReq.CommunityID,
+		CreatorID:        userID,
+		Title:            quizReq.Title,
+		Description:      quizReq.Description,
+		DurationMinutes:  quizReq.DurationMinutes,
+		Status:           status,
+		PublishAt:        quizReq.PublishAt,
+		CloseAt:          quizReq.CloseAt,
+		ShuffleQuestions: quizReq.ShuffleQuestions,
+		ShuffleOptions:   quizReq.ShuffleOptions,
+		MaxAttempts:      quizReq.MaxAttempts,
+		CooldownMinutes:  quizReq.CooldownMinutes,
+		ScoringRule:      scoringRule(quizReq.ScoringRule),
+		PassMark:         quizReq.PassMark,
+		License:          license(quizReq.License),
+	}
+	if forkOf != nil {
+		quiz.ForkedFromQuiz = &forkOf.ID
+		quiz.ForkedFromUser = &forkOf.CreatorID
 	}
 
 	if err := s.quizRepo.CreateTx(ctx, &quiz, tx); err != nil {
 		return "", errors.New("Failed to create quiz")
 	}
 	var questions []models.Question
-	for _, q := range quizReq.Questions {
-		var question models.Question
-		question.QuizID = quiz.ID
-		question.QuestionText = q.QuestionText
-		question.Explanation = q.Explanation
-		question.CorrectAnswer = q.CorrectAnswer
-		question.OrderIndex = q.OrderIndex
+	var questionOptions [][]models.Option
+	for i := range quizReq.Questions {
+		question, options, err := newQuestion(quiz.ID, &quizReq.Questions[i])
+		if err != nil {
+			return "", err
+		}
 		questions = append(questions, question)
+		questionOptions = append(questionOptions, options)
 	}
 
 	if err := s.questionRepo.CreateBatchTx(ctx, questions, tx); err != nil {
 		return "", errors.New("Failed to create question" + err.Error())
 	}
 
-	for i, q := range quizReq.Questions {
-		var options []models.Option
-		for _, o := range q.Options {
-			var option models.Option
-			option.QuestionID = questions[i].ID
-			option.Text = o.Text
-			option.IsCorrect = o.IsCorrect
-			options = append(options, option)
+	for i, options := range questionOptions {
+		for j := range options {
+			options[j].QuestionID = questions[i].ID
 		}
 		err := s.optionRepo.CreateBatchTx(ctx, options, tx)
 		if err != nil {
 			return "", errors.New("Failed to get created question")
 		}
 	}
+	labels := make([]models.QuestionSnapshot, 0, len(questions))
+	for _, q := range questions {
+		labels = append(labels, models.QuestionSnapshot{ID: q.ID, Tag: q.Tag, Difficulty: q.Difficulty})
+	}
+	pools, err := newPools(quizReq.Pools, labels)
+	if err != nil {
+		return "", err
+	}
+	if err := s.quizRepo.ReplacePoolsTx(ctx, quiz.ID, pools, tx); err != nil {
+		return "", errors.New("Failed to create pools")
+	}
+
+	if _, err := s.quizVersionRepo.CreateTx(ctx, quiz.ID, tx); err != nil {
+		return "", errors.New("Failed to create quiz version")
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return "", errors.New("Failed to commit transaction")
 	}
@@ -221,38 +276,54 @@ func (s *QuizService) TakeQuiz(
 		}
 		return nil, errors.New("Failed to get Quiz")
 	}
-	questions, err := s.questionRepo.FindByQuizID(ctx, quizID)
+	if err := s.checkQuizVisible(ctx, userID, quiz, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Serve the questions of the running attempt's version so the option IDs
+	// shown are exactly the ones it will be graded against.
+	attempts, err := s.quizRepo.FindAttemptByUser(ctx, quizID, userID)
+	if err != nil {
+		return nil, errors.New("Failed to get attempts")
+	}
+	var version *models.QuizVersion
+	var current *models.QuizAttempts
+	for _, a := range attempts {
+		if a.Status == models.AttemptInProgress {
+			version, err = s.attemptVersion(ctx, a)
+			current = a
+		}
+	}
+	if version == nil && err == nil {
+		version, err = s.quizVersionRepo.FindLatest(ctx, quizID)
+	}
 	if err != nil {
 		return nil, errors.New("Failed to get Questions")
 	}
+
+	return takeQuizResponse(quiz, version, current), nil
+}
+
+// takeQuizResponse presents the questions of an attempt, or of the whole
+// version when attempt is nil, without their answers.
+func takeQuizResponse(quiz *models.Quiz, version *models.QuizVersion, attempt *models.QuizAttempts) *dto_quiz.TakeQuizResponse {
 	var questionsRes []dto_quiz.QuestionTake
-	for _, q := range questions {
+	for _, q := range attemptQuestions(&version.Snapshot, attempt) {
 		var questionRes dto_quiz.QuestionTake
 		questionRes.QuestionID = q.ID
 		questionRes.QuestionText = q.QuestionText
-		options, err := s.optionRepo.GetByQuestionID(ctx, q.ID)
-		if err != nil {
-			return nil, errors.New("Failed to get Options")
-		}
-		var optionsRes []dto_quiz.OptionTake
-		for _, o := range options {
-			optionRes := dto_quiz.OptionTake{
-				OptionID: o.ID,
-				Text:     o.Text,
-			}
-			optionsRes = append(optionsRes, optionRes)
-		}
-		questionRes.Options = optionsRes
+		questionRes.QuestionType = questionType(&q)
+		questionRes.Points = questionPoints(&q)
+		questionRes.NegativePoints = q.NegativePoints
+		graderFor(&q).present(&q, &questionRes)
 		questionsRes = append(questionsRes, questionRes)
 	}
-	takeQuizRes := &dto_quiz.TakeQuizResponse{
+	return &dto_quiz.TakeQuizResponse{
 		QuizID:    quiz.ID,
-		Title:     quiz.Title,
+		Title:     version.Snapshot.Title,
 		Duration:  quiz.DurationMinutes,
 		Questions: questionsRes,
 	}
-	return takeQuizRes, nil
-
 }
 
 func (s *QuizService) SubmitQuiz(
@@ -276,66 +347,37 @@ func (s *QuizService) SubmitQuiz(
 		return "", errors.New("failed to get quiz")
 	}
 
-	if submitReq.DurationMinutes > quiz.DurationMinutes {
-		return "", errors.New("time out")
-	}
-
-	questions, err := s.questionRepo.FindByQuizID(ctx, quizID)
+	// Elapsed time is measured here, never taken from the client.
+	now := time.Now()
+	attempt, err := s.runningAttempt(ctx, quiz, userID, now, tx)
 	if err != nil {
-		return "", errors.New("failed to get questions")
-	}
-
-	if len(submitReq.Answers) != len(questions) {
-		return "", errors.New("answers count does not match questions count")
-	}
-
-	userAttempts, err := s.quizRepo.FindAttemptByUser(ctx, quizID, userID)
-	if err != nil && err != pgx.ErrNoRows {
-		return "", errors.New("failed to check user attempts: " + err.Error())
-	}
-	isFirstAttempt := len(userAttempts) == 0
-	_ = isFirstAttempt // Suppress unused warning since it's not needed for stats anymore but kept for logic clarity if needed later
-
-	attempt := &models.QuizAttempts{
-		UserID:           userID,
-		QuizID:           quizID,
-		TimeTakenMinutes: submitReq.DurationMinutes,
-		TotalQuestions:   len(questions),
-		Score:            0,
-		Percentage:       0,
-		AttemptCount:     len(userAttempts) + 1,
+		return "", err
 	}
 
-	if err := s.quizRepo.CreateUserAttempt(ctx, attempt, tx); err != nil {
-		return "", errors.New("failed to save user attempt: " + err.Error())
+	// Grade against the version the attempt was started on, so edits made
+	// while it was running cannot change its questions or options.
+	version, err := s.attemptVersion(ctx, attempt)
+	if err != nil {
+		return "", errors.New("failed to get quiz version: " + err.Error())
 	}
+	// Only the questions drawn for this attempt may be answered; any of
+	// them may be left blank.
+	questions := attemptQuestions(&version.Snapshot, attempt)
 
-	score := 0
-	userAnswers := make([]*models.UserAnwer, 0, len(questions))
-	for i, q := range questions {
-		answer := submitReq.Answers[i]
-
-		if answer.AnswerText == q.CorrectAnswer {
-			score++
-		}
-
-		userAnswers = append(userAnswers, &models.UserAnwer{
-			AttemptID:  attempt.ID,
-			QuestionID: q.ID,
-			OptionID:   answer.OptionID,
-		})
+	saved, err := s.quizRepo.FindSavedAnswersTx(ctx, attempt.ID, tx)
+	if err != nil {
+		return "", errors.New("failed to get saved answers: " + err.Error())
 	}
-
-	if err := s.quizRepo.CreateBatchUserAnswer(ctx, userAnswers, tx); err != nil {
-		return "", errors.New("failed to save user answers: " + err.Error())
+	answers, err := withSavedAnswers(submitReq.Answers, saved)
+	if err != nil {
+		return "", err
 	}
 
-	attempt.Score = score
-	attempt.Percentage = (float64(score) / float64(len(questions))) * 100
-	attempt.CompletedAt = time.Now()
-
-	if err := s.quizRepo.UpdateAttempt(ctx, attempt, tx); err != nil {
-		return "", errors.New("failed to update user attempt: " + err.Error())
+	attempt.Status = models.AttemptCompleted
+	attempt.TimeTakenSeconds = elapsedSeconds(attempt, now)
+	attempt.CompletedAt = &now
+	if err := s.finishAttempt(ctx, quiz, attempt, questions, answers, tx); err != nil {
+		return "", err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -385,12 +427,26 @@ func (s *QuizService) GetQuizByID(
 		}
 		return nil, errors.New("failed to get quiz: " + err.Error())
 	}
+	now := time.Now()
+	if err := s.checkQuizVisible(ctx, userID, quiz, now); err != nil {
+		return nil, err
+	}
 
 	quizRes := &dto_quiz.QuizDetailResponse{
 		ID:                quiz.ID,
 		Title:             quiz.Title,
 		Description:       quiz.Description,
 		DurationMinutes:   quiz.DurationMinutes,
+		Status:            quizStatus(quiz, now),
+		PublishAt:         quiz.PublishAt,
+		CloseAt:           quiz.CloseAt,
+		ShuffleQuestions:  quiz.ShuffleQuestions,
+		ShuffleOptions:    quiz.ShuffleOptions,
+		MaxAttempts:       quiz.MaxAttempts,
+		CooldownMinutes:   quiz.CooldownMinutes,
+		ScoringRule:       quiz.ScoringRule,
+		PassMark:          quiz.PassMark,
+		License:           license(quiz.License),
 		LikesCount:        quiz.LikesCount,
 		AverageScore:      quiz.AverageScore,
 		NumberOfQuestions: 0, // Needs a repo method to get actual count if not in quiz model, or additional query
@@ -408,6 +464,17 @@ func (s *QuizService) GetQuizByID(
 	if err == nil {
 		quizRes.NumberOfQuestions = len(questions)
 	}
+	// With pools every attempt draws its own questions, so there is no one
+	// maximum to report.
+	pools, poolsErr := s.quizRepo.FindPools(ctx, quizID)
+	if err == nil && poolsErr == nil && len(pools) == 0 {
+		var points float64
+		for _, q := range questions {
+			points += q.Points
+		}
+		points = roundPoints(points)
+		quizRes.MaxPoints = &points
+	}
 
 	// Community Info
 	community, err := s.communityRepo.FindByID(ctx, quiz.CommunityID)
@@ -472,6 +539,11 @@ func (s *QuizService) GetQuizByID(
 
 	// Check for current attempt
 	attempts, err := s.quizRepo.FindAttemptByUser(ctx, quizID, userID)
+	attempts = finishedAttempts(attempts)
+	quizRes.AttemptsUsed = len(attempts)
+	quizRes.NextAttemptAt = nextAttemptAt(quiz, attempts, now)
+	quizRes.CanFork, _ = s.canFork(ctx, userID, quiz)
+	quizRes.ForkedFrom = s.forkSource(ctx, quiz)
 	if err == nil && len(attempts) > 0 {
 		// Assuming attempts are ordered or we pick the last one.
 		// Detailed logic depends on if multiple attempts are allowed or we just want the latest.
@@ -487,79 +559,143 @@ func (s *QuizService) GetQuizByID(
 	return quizRes, nil
 }
 
-func (s *QuizService) GetQuizResult(ctx context.Context, attemptID string) (*dto_quiz.QuizResultResponse, error) {
+// GetQuizResult returns the graded breakdown of a finished attempt, answer
+// key included. Only the learner who took it and the quiz's editors may see
+// it; to anyone else the attempt does not exist.
+func (s *QuizService) GetQuizResult(ctx context.Context, userID, attemptID string) (*dto_quiz.QuizResultResponse, error) {
 	attempt, err := s.quizRepo.GetAttemptByID(ctx, attemptID)
+	if err == pgx.ErrNoRows {
+		return nil, sharedErrors.NotFound(sharedErrors.ErrAttemptNotFound, "attempt not found")
+	}
 	if err != nil {
 		return nil, errors.New("failed to get attempt: " + err.Error())
 	}
-
 	quiz, err := s.quizRepo.FindByID(ctx, attempt.QuizID)
 	if err != nil {
 		return nil, errors.New("failed to get quiz: " + err.Error())
 	}
+	if attempt.UserID != userID {
+		allowed, err := s.canManage(ctx, userID, quiz)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, sharedErrors.NotFound(sharedErrors.ErrAttemptNotFound, "attempt not found")
+		}
+	}
+	if attempt.Status == models.AttemptInProgress {
+		return nil, sharedErrors.Conflict(sharedErrors.ErrAttemptInProgress, "results are available once the attempt is finished")
+	}
 
-	questions, err := s.questionRepo.FindByQuizID(ctx, attempt.QuizID)
+	// Results are rendered from the version the attempt was taken against,
+	// so later edits to the quiz never rewrite what the user answered.
+	version, err := s.attemptVersion(ctx, attempt)
 	if err != nil {
-		return nil, errors.New("failed to get questions: " + err.Error())
+		return nil, errors.New("failed to get quiz version: " + err.Error())
 	}
+	snapshot := version.Snapshot
 
 	userAnswers, err := s.quizRepo.GetUserAnswersForAttempt(ctx, attemptID)
 	if err != nil {
 		return nil, errors.New("failed to get user answers: " + err.Error())
 	}
 
-	optionStats, err := s.quizRepo.GetOptionStatsForQuiz(ctx, attempt.QuizID)
+	optionStats, shownCounts, err := s.quizRepo.GetOptionStatsForQuiz(ctx, attempt.QuizID)
 	if err != nil {
 		return nil, errors.New("failed to get option stats: " + err.Error())
 	}
 
-	allAttempts, _ := s.quizRepo.FindAttemptByQuiz(ctx, attempt.QuizID)
-	studentCount := len(allAttempts)
-	if studentCount == 0 {
-		studentCount = 1
-	}
-
 	result := &dto_quiz.QuizResultResponse{
 		AttemptID:        attempt.ID,
-		QuizID:           quiz.ID,
-		QuizTitle:        quiz.Title,
+		QuizID:           attempt.QuizID,
+		QuizTitle:        snapshot.Title,
+		QuizVersion:      version.Version,
 		Score:            attempt.Score,
+		MaxPoints:        attempt.MaxPoints,
 		TotalQuestions:   attempt.TotalQuestions,
 		Percentage:       attempt.Percentage,
-		TimeTakenMinutes: attempt.TimeTakenMinutes,
-		CompletedAt:      utils.FormatTime(attempt.CompletedAt),
-		Questions:        make([]dto_quiz.QuestionResult, 0, len(questions)),
+		PassMark:         attempt.PassMark,
+		Passed:           attempt.Passed,
+		Status:           attempt.Status,
+		TimeTakenSeconds: attempt.TimeTakenSeconds,
+		TimeTakenMinutes: attempt.TimeTakenSeconds / 60,
+		Questions:        make([]dto_quiz.QuestionResult, 0, len(snapshot.Questions)),
+	}
+	questions := attemptQuestions(&snapshot, attempt)
+	flagged := make(map[string]bool, len(attempt.FlaggedIDs))
+	for _, id := range attempt.FlaggedIDs {
+		flagged[id] = true
+	}
+	if attempt.CompletedAt != nil {
+		result.CompletedAt = utils.FormatTime(*attempt.CompletedAt)
 	}
 
-	for _, q := range questions {
+	for i := range questions {
+		q := &questions[i]
 		qRes := dto_quiz.QuestionResult{
-			QuestionID:    q.ID,
-			QuestionText:  q.QuestionText,
-			Explanation:   q.Explanation,
-			CorrectAnswer: q.CorrectAnswer,
-			UserAnswer:    nil,
-			Options:       make([]dto_quiz.OptionWithStats, 0),
-			Comments:      make([]dto_quiz.CommentRes, 0),
+			QuestionID:      q.ID,
+			QuestionText:    q.QuestionText,
+			QuestionType:    questionType(q),
+			ScoringStrategy: scoringStrategy(q),
+			Explanation:     q.Explanation,
+			CorrectAnswer:   q.CorrectAnswer,
+			UserAnswer:      nil,
+			UserAnswers:     make([]string, 0),
+			AcceptedAnswers: q.Settings.AcceptedAnswers,
+			Unit:            q.Settings.Unit,
+			Flagged:         flagged[q.ID],
+			TimesShown:      shownCounts[q.ID],
+			MaxPoints:       questionPoints(q),
+			NegativePoints:  q.NegativePoints,
+			Options:         make([]dto_quiz.OptionWithStats, 0),
+			Comments:        make([]dto_quiz.CommentRes, 0),
+		}
+		// Percentages are over the attempts that were asked the question,
+		// which with pools is only some of them.
+		shown := qRes.TimesShown
+		if shown == 0 {
+			shown = 1
 		}
 
-		if ans, ok := userAnswers[q.ID]; ok {
-			qRes.UserAnswer = &ans
+		rows := userAnswers[q.ID]
+		if len(rows) > 0 {
+			r := responseFromRows(rows)
+			if len(r.OptionIDs) > 0 {
+				qRes.UserAnswer = &r.OptionIDs[0]
+				qRes.UserAnswers = r.OptionIDs
+			}
+			qRes.TextAnswer = r.Text
+			qRes.Order = r.Order
+			if r.Matches != nil {
+				for _, o := range q.Options {
+					if match, ok := r.Matches[o.ID]; ok {
+						qRes.Matches = append(qRes.Matches, dto_quiz.MatchResult{
+							OptionID:  o.ID,
+							Match:     match,
+							IsCorrect: matchCorrect(&o, r.Matches),
+						})
+					}
+				}
+			}
+			share := graderFor(q).score(q, r)
+			qRes.Points = earnedPoints(q, share)
+			qRes.IsCorrect = share == maxQuestionPoints
+			qRes.Result = answerResult(true, share)
+		} else {
+			qRes.Result = answerResult(false, 0)
+			result.Unanswered++
 		}
 
-		options, _ := s.optionRepo.GetByQuestionID(ctx, q.ID)
-		for _, o := range options {
+		for _, o := range q.Options {
 			count := optionStats[o.ID]
 			oStats := dto_quiz.OptionWithStats{
 				OptionID:       o.ID,
 				Text:           o.Text,
 				IsCorrect:      o.IsCorrect,
+				Position:       o.Position,
+				MatchText:      o.MatchText,
 				SelectionCount: count,
-				Percentage:     (float64(count) / float64(studentCount)) * 100,
-			}
-			if qRes.UserAnswer != nil && *qRes.UserAnswer == o.ID {
-				if o.IsCorrect {
-					qRes.IsCorrect = true
-				}
+				Percentage:     (float64(count) / float64(shown)) * 100,
 			}
 			qRes.Options = append(qRes.Options, oStats)
 		}
@@ -576,42 +712,76 @@ func (s *QuizService) GetQuizResult(ctx context.Context, attemptID string) (*dto
 	return result, nil
 }
 
-func (s *QuizService) AddQuestion(ctx context.Context, quizID string, qReq *dto_quiz.Question) error {
+// AddQuestion adds a question with its options and returns its ID. A
+// question without an order index goes after the quiz's last one.
+func (s *QuizService) AddQuestion(ctx context.Context, userID, quizID string, qReq *dto_quiz.Question) (string, error) {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return "", err
+	}
+
 	tx, err := s.quizRepo.BeginTx(ctx)
 	if err != nil {
-		return errors.New("failed to start transaction")
+		return "", errors.New("failed to start transaction")
 	}
 	defer tx.Rollback(ctx)
 
-	return s.addQuestionInternal(ctx, quizID, qReq, tx)
-}
+	questions, err := s.addQuestions(ctx, quizID, []dto_quiz.Question{*qReq}, tx)
+	if err != nil {
+		return "", err
+	}
 
-func (s *QuizService) addQuestionInternal(ctx context.Context, quizID string, qReq *dto_quiz.Question, tx pgx.Tx) error {
-	question := models.Question{
-		QuizID:        quizID,
-		QuestionText:  qReq.QuestionText,
-		Explanation:   qReq.Explanation,
-		CorrectAnswer: qReq.CorrectAnswer,
-		OrderIndex:    qReq.OrderIndex,
+	if err := s.commitWithVersion(ctx, quizID, tx); err != nil {
+		return "", err
 	}
+	return questions[0].ID, nil
+}
 
-	qs := []models.Question{question}
-	if err := s.questionRepo.CreateBatchTx(ctx, qs, tx); err != nil {
-		return errors.New("failed to create question: " + err.Error())
+// newQuestion builds a question and its options from a request and checks
+// that they can be graded as the requested type. Options keep the order they
+// were sent in, which is the correct order for ordering questions.
+func newQuestion(quizID string, qReq *dto_quiz.Question) (models.Question, []models.Option, error) {
+	questionType, scoringStrategy, err := questionKind(qReq.QuestionType, qReq.ScoringStrategy)
+	if err != nil {
+		return models.Question{}, nil, err
 	}
 
-	options := make([]models.Option, 0, len(qReq.Options))
-	for _, o := range qReq.Options {
+	question := models.Question{
+		QuizID:          quizID,
+		QuestionText:    qReq.QuestionText,
+		Explanation:     qReq.Explanation,
+		CorrectAnswer:   qReq.CorrectAnswer,
+		OrderIndex:      qReq.OrderIndex,
+		QuestionType:    questionType,
+		ScoringStrategy: scoringStrategy,
+		Settings:        questionSettings(qReq.Settings),
+		Tag:             strings.TrimSpace(qReq.Tag),
+		Difficulty:      qReq.Difficulty,
+		Points:          pointsOrDefault(qReq.Points),
+		NegativePoints:  qReq.NegativePoints,
+	}
+	if err := validDifficulty(question.Difficulty); err != nil {
+		return models.Question{}, nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidQuestion, err.Error())
+	}
+	if err := validPoints(question.Points, question.NegativePoints); err != nil {
+		return models.Question{}, nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidQuestion, err.Error())
+	}
+
+	options := newOptions(qReq.Options)
+	if err := validateQuestion(&question, options); err != nil {
+		return models.Question{}, nil, err
+	}
+	return question, options, nil
+}
+
+func newOptions(reqs []dto_quiz.Option) []models.Option {
+	options := make([]models.Option, 0, len(reqs))
+	for i, o := range reqs {
 		options = append(options, models.Option{
-			QuestionID: qs[0].ID,
-			Text:       o.Text,
-			IsCorrect:  o.IsCorrect,
+			Text:      o.Text,
+			IsCorrect: o.IsCorrect,
+			Position:  i,
+			MatchText: o.MatchText,
 		})
 	}
-
-	if err := s.optionRepo.CreateBatchTx(ctx, options, tx); err != nil {
-		return errors.New("failed to create options: " + err.Error())
-	}
-
-	return tx.Commit(ctx)
+	return options
 }
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StartQuiz opens a timed attempt pinned to the quiz's current version. If
+// the user already has an attempt running it is returned instead, so
+// reloading the page does not restart the clock.
+func (s *QuizService) StartQuiz(ctx context.Context, userID, quizID string) (*dto_quiz.StartQuizResponse, error) {
+	quiz, err := s.quizRepo.FindByID(ctx, quizID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sharedErrors.NotFound(sharedErrors.ErrQuizNotFound, "quiz not found")
+		}
+		return nil, errors.New("failed to get quiz")
+	}
+
+	now := time.Now()
+	if err := s.checkQuizVisible(ctx, userID, quiz, now); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuizOpen(quiz, now); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.quizRepo.FindInProgressAttemptTx(ctx, quizID, userID, tx)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, errors.New("failed to get attempt: " + err.Error())
+	}
+	if current != nil {
+		if !s.attemptOverdue(current, now) {
+			return startQuizResponse(current, now), nil
+		}
+		if err := s.expireAttempt(ctx, quiz, current, now, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	version, err := s.quizVersionRepo.FindLatest(ctx, quizID)
+	if err != nil {
+		return nil, errors.New("failed to get quiz version: " + err.Error())
+	}
+
+	previous, err := s.quizRepo.FindAttemptByUser(ctx, quizID, userID)
+	if err != nil {
+		return nil, errors.New("failed to check user attempts: " + err.Error())
+	}
+	// previous is read outside tx, so it still has the attempt just expired
+	// above as in progress. It counts as finished for the policy.
+	for i, a := range previous {
+		if current != nil && a.ID == current.ID {
+			previous[i] = current
+		}
+	}
+	if err := checkAttemptPolicy(quiz, finishedAttempts(previous), now); err != nil {
+		return nil, err
+	}
+
+	seed := rand.Int64()
+	questionIDs := drawQuestions(&version.Snapshot, seed)
+	if len(questionIDs) == 0 {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrQuizNeedsQuestion, "no questions could be drawn for this quiz")
+	}
+
+	attempt := &models.QuizAttempts{
+		QuizID:         quizID,
+		UserID:         userID,
+		TotalQuestions: len(questionIDs),
+		AttemptCount:   len(previous) + 1,
+		QuizVersionID:  &version.ID,
+		Status:         models.AttemptInProgress,
+		StartedAt:      now,
+		ShuffleSeed:    &seed,
+		QuestionIDs:    questionIDs,
+	}
+	if quiz.DurationMinutes > 0 {
+		deadline := now.Add(time.Duration(quiz.DurationMinutes) * time.Minute)
+		attempt.DeadlineAt = &deadline
+	}
+
+	if err := s.quizRepo.CreateUserAttempt(ctx, attempt, tx); err != nil {
+		if isUniqueViolation(err) {
+			return nil, sharedErrors.Conflict(sharedErrors.ErrAttemptInProgress, "another attempt was started at the same time")
+		}
+		return nil, errors.New("failed to save user attempt: " + err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, errors.New("failed to commit transaction: " + err.Error())
+	}
+
+	return startQuizResponse(attempt, now), nil
+}
+
+// ExpireOverdueAttempts is run by the background sweeper to close attempts
+// that were abandoned past their deadline, or left running when their quiz
+// closed, once the grace window is over. What was saved on each is graded.
+func (s *QuizService) ExpireOverdueAttempts(ctx context.Context) error {
+	now := time.Now()
+	overdue, err := s.quizRepo.FindOverdueAttempts(ctx, now.Add(-s.attemptGrace))
+	if err != nil {
+		return err
+	}
+
+	expired := 0
+	for _, a := range overdue {
+		ok, err := s.expireOverdueAttempt(ctx, a, now)
+		if err != nil {
+			log.Printf("failed to expire quiz attempt %s: %v", a.ID, err)
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+	if expired > 0 {
+		log.Printf("expired %d overdue quiz attempts", expired)
+	}
+	return nil
+}
+
+// expireOverdueAttempt expires one attempt found by the sweeper, unless it
+// was submitted or expired in the meantime.
+func (s *QuizService) expireOverdueAttempt(ctx context.Context, overdue *models.QuizAttempts, now time.Time) (bool, error) {
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	attempt, err := s.quizRepo.FindInProgressAttemptTx(ctx, overdue.QuizID, overdue.UserID, tx)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if attempt.ID != overdue.ID {
+		return false, nil
+	}
+	quiz, err := s.quizRepo.FindByID(ctx, attempt.QuizID)
+	if err != nil {
+		return false, err
+	}
+	if !s.attemptEnded(quiz, attempt, now) {
+		return false, nil
+	}
+
+	if err := s.expireAttempt(ctx, quiz, attempt, now, tx); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// attemptOverdue reports whether a submission at now falls outside the
+// attempt's deadline plus the grace window allowed for network latency.
+func (s *QuizService) attemptOverdue(attempt *models.QuizAttempts, now time.Time) bool {
+	return attempt.DeadlineAt != nil && now.After(attempt.DeadlineAt.Add(s.attemptGrace))
+}
+
+// attemptEnded reports whether the attempt can no longer be handed in at
+// now, because it ran out of time or its quiz closed, grace window included.
+func (s *QuizService) attemptEnded(quiz *models.Quiz, attempt *models.QuizAttempts, now time.Time) bool {
+	return s.attemptOverdue(attempt, now) || quizEnded(quiz, now.Add(-s.attemptGrace))
+}
+
+// attemptEnd is when the attempt stopped taking answers: its deadline, or
+// the quiz's close_at if that came first. An attempt with neither ended when
+// the quiz was closed by hand, which is known only to be before now.
+func attemptEnd(quiz *models.Quiz, attempt *models.QuizAttempts, now time.Time) time.Time {
+	end := now
+	if attempt.DeadlineAt != nil && attempt.DeadlineAt.Before(end) {
+		end = *attempt.DeadlineAt
+	}
+	if quiz.CloseAt != nil && quiz.CloseAt.Before(end) && quiz.CloseAt.After(attempt.StartedAt) {
+		end = *quiz.CloseAt
+	}
+	return end
+}
+
+// expireAttempt closes an attempt that ran out of time or outlived its quiz.
+// Whatever answers were saved before it ended are graded; the rest count as
+// unanswered.
+func (s *QuizService) expireAttempt(ctx context.Context, quiz *models.Quiz, attempt *models.QuizAttempts, now time.Time, tx pgx.Tx) error {
+	version, err := s.attemptVersion(ctx, attempt)
+	if err != nil {
+		return errors.New("failed to get quiz version: " + err.Error())
+	}
+	saved, err := s.quizRepo.FindSavedAnswersTx(ctx, attempt.ID, tx)
+	if err != nil {
+		return errors.New("failed to get saved answers: " + err.Error())
+	}
+	answers, err := withSavedAnswers(nil, saved)
+	if err != nil {
+		return err
+	}
+
+	end := attemptEnd(quiz, attempt, now)
+	attempt.Status = models.AttemptExpired
+	attempt.CompletedAt = &end
+	attempt.TimeTakenSeconds = elapsedSeconds(attempt, end)
+	return s.finishAttempt(ctx, quiz, attempt, attemptQuestions(&version.Snapshot, attempt), answers, tx)
+}
+
+// finishAttempt grades the answers, stores them in place of the saved ones
+// and records the score on the attempt, whose status and timing the caller
+// has already set. The pass mark is the quiz's at the time of grading and
+// is kept on the attempt.
+func (s *QuizService) finishAttempt(ctx context.Context, quiz *models.Quiz, attempt *models.QuizAttempts, questions []models.QuestionSnapshot, answers []dto_quiz.Answer, tx pgx.Tx) error {
+	graded, score, err := gradeAnswers(questions, answers)
+	if err != nil {
+		return err
+	}
+
+	userAnswers := answerRows(attempt.ID, graded)
+	if err := s.quizRepo.CreateBatchUserAnswer(ctx, userAnswers, tx); err != nil {
+		return errors.New("failed to save user answers: " + err.Error())
+	}
+	if err := s.quizRepo.DeleteSavedAnswersTx(ctx, attempt.ID, tx); err != nil {
+		return errors.New("failed to clear saved answers: " + err.Error())
+	}
+
+	attempt.Score = score
+	attempt.MaxPoints = maxPoints(questions)
+	attempt.TotalQuestions = len(questions)
+	attempt.Percentage = percentageOf(score, attempt.MaxPoints)
+	attempt.Passed = nil
+	attempt.PassMark = quiz.PassMark
+	if quiz.PassMark != nil {
+		passed := attempt.Percentage >= *quiz.PassMark
+		attempt.Passed = &passed
+	}
+	attempt.FlaggedIDs = flaggedQuestions(answers)
+
+	if err := s.quizRepo.UpdateAttempt(ctx, attempt, tx); err != nil {
+		return errors.New("failed to update user attempt: " + err.Error())
+	}
+	return nil
+}
+
+// elapsedSeconds is the time spent on the attempt up to now, capped at the
+// time limit so submissions inside the grace window are not penalised.
+func elapsedSeconds(attempt *models.QuizAttempts, now time.Time) int {
+	end := now
+	if attempt.DeadlineAt != nil && end.After(*attempt.DeadlineAt) {
+		end = *attempt.DeadlineAt
+	}
+	elapsed := int(end.Sub(attempt.StartedAt).Seconds())
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed
+}
+
+// attemptVersion returns the quiz version an attempt is pinned to. Attempts
+// predating versioning fall back to the latest version.
+func (s *QuizService) attemptVersion(ctx context.Context, attempt *models.QuizAttempts) (*models.QuizVersion, error) {
+	if attempt.QuizVersionID != nil {
+		return s.quizVersionRepo.FindByID(ctx, *attempt.QuizVersionID)
+	}
+	return s.quizVersionRepo.FindLatest(ctx, attempt.QuizID)
+}
+
+// attemptQuestions returns the questions an attempt was drawn, in the order
+// it presents them. With a seed, questions and options are shuffled as the
+// version's flags ask, and the same seed always gives the same order, so
+// resuming, grading and reviewing the attempt all see what the learner saw.
+// Ordering questions always have their items scrambled, since their stored
+// order is the answer. A nil attempt previews every question unshuffled.
+func attemptQuestions(snapshot *models.QuizSnapshot, attempt *models.QuizAttempts) []models.QuestionSnapshot {
+	var drawn map[string]bool
+	if attempt != nil && attempt.QuestionIDs != nil {
+		drawn = make(map[string]bool, len(attempt.QuestionIDs))
+		for _, id := range attempt.QuestionIDs {
+			drawn[id] = true
+		}
+	}
+
+	questions := make([]models.QuestionSnapshot, 0, len(snapshot.Questions))
+	for _, q := range snapshot.Questions {
+		if drawn != nil && !drawn[q.ID] {
+			continue
+		}
+		q.Options = append([]models.OptionSnapshot(nil), q.Options...)
+		questions = append(questions, q)
+	}
+
+	var seed *int64
+	if attempt != nil {
+		seed = attempt.ShuffleSeed
+	}
+	if seed == nil {
+		for i := range questions {
+			if questionType(&questions[i]) == models.QuestionOrdering {
+				options := questions[i].Options
+				sort.Slice(options, func(a, b int) bool { return options[a].ID < options[b].ID })
+			}
+		}
+		return questions
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(*seed), 0))
+	if snapshot.ShuffleQuestions {
+		rng.Shuffle(len(questions), func(a, b int) {
+			questions[a], questions[b] = questions[b], questions[a]
+		})
+	}
+	for i := range questions {
+		options := questions[i].Options
+		if snapshot.ShuffleOptions || questionType(&questions[i]) == models.QuestionOrdering {
+			rng.Shuffle(len(options), func(a, b int) {
+				options[a], options[b] = options[b], options[a]
+			})
+		}
+	}
+	return questions
+}
+
+// checkAttemptPolicy rejects a new attempt once the quiz's attempt limit is
+// used up or while the cooldown after the last finished attempt runs.
+func checkAttemptPolicy(quiz *models.Quiz, finished []*models.QuizAttempts, now time.Time) error {
+	if quiz.MaxAttempts != nil && len(finished) >= *quiz.MaxAttempts {
+		return sharedErrors.Forbidden(sharedErrors.ErrMaxAttempts, "no attempts left for this quiz")
+	}
+	if next := nextAttemptAt(quiz, finished, now); next != nil {
+		return sharedErrors.TooManyRequests(sharedErrors.ErrAttemptCooldown, "wait before attempting this quiz again", next.Sub(now))
+	}
+	return nil
+}
+
+// nextAttemptAt is when the cooldown after the last finished attempt ends,
+// or nil when it is not running. It is also nil once the limit is reached,
+// since no later time allows another attempt.
+func nextAttemptAt(quiz *models.Quiz, finished []*models.QuizAttempts, now time.Time) *time.Time {
+	if quiz.MaxAttempts != nil && len(finished) >= *quiz.MaxAttempts {
+		return nil
+	}
+	if quiz.CooldownMinutes == 0 {
+		return nil
+	}
+	var last *time.Time
+	for _, a := range finished {
+		if a.CompletedAt != nil && (last == nil || a.CompletedAt.After(*last)) {
+			last = a.CompletedAt
+		}
+	}
+	if last == nil {
+		return nil
+	}
+	next := last.Add(time.Duration(quiz.CooldownMinutes) * time.Minute)
+	if !next.After(now) {
+		return nil
+	}
+	return &next
+}
+
+// scoringRule defaults quizzes that do not pick a rule to the first attempt,
+// which is how results were counted before rules existed.
+func scoringRule(rule string) string {
+	if rule == "" {
+		return models.ScoringRuleFirst
+	}
+	return rule
+}
+
+func finishedAttempts(attempts []*models.QuizAttempts) []*models.QuizAttempts {
+	finished := make([]*models.QuizAttempts, 0, len(attempts))
+	for _, a := range attempts {
+		if a.Status != models.AttemptInProgress {
+			finished = append(finished, a)
+		}
+	}
+	return finished
+}
+
+func startQuizResponse(attempt *models.QuizAttempts, now time.Time) *dto_quiz.StartQuizResponse {
+	return &dto_quiz.StartQuizResponse{
+		AttemptID:     attempt.ID,
+		AttemptNumber: attempt.AttemptCount,
+		StartedAt:     attempt.StartedAt,
+		DeadlineAt:    attempt.DeadlineAt,
+		ServerTime:    now,
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveAnswer autosaves the answer to one question of the user's running
+// attempt, replacing what was saved for it before. The answer is checked
+// the same way a submission is, so a saved answer can always be graded. A
+// blank answer keeps just the question's review flag.
+func (s *QuizService) SaveAnswer(ctx context.Context, userID, quizID string, answer *dto_quiz.Answer) (*dto_quiz.SavedAnswer, error) {
+	quiz, err := s.findQuiz(ctx, quizID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	attempt, err := s.runningAttempt(ctx, quiz, userID, now, tx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.attemptVersion(ctx, attempt)
+	if err != nil {
+		return nil, errors.New("failed to get quiz version: " + err.Error())
+	}
+
+	var question *models.QuestionSnapshot
+	questions := attemptQuestions(&version.Snapshot, attempt)
+	for i := range questions {
+		if questions[i].ID == answer.QuestionID {
+			question = &questions[i]
+		}
+	}
+	if question == nil {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrUnknownQuestion, "answer refers to a question that is not part of this attempt")
+	}
+	if !answerBlank(*answer) {
+		if _, err := graderFor(question).parse(question, *answer); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return nil, errors.New("failed to encode answer: " + err.Error())
+	}
+	saved := &models.SavedAnswer{
+		AttemptID:  attempt.ID,
+		QuestionID: question.ID,
+		Answer:     data,
+		SavedAt:    now,
+	}
+	if err := s.quizRepo.SaveAnswerTx(ctx, saved, tx); err != nil {
+		return nil, errors.New("failed to save answer: " + err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, errors.New("failed to commit transaction: " + err.Error())
+	}
+
+	return &dto_quiz.SavedAnswer{Answer: *answer, SavedAt: now}, nil
+}
+
+// ClearAnswer removes the saved answer to one question of the user's running
+// attempt. Clearing a question with nothing saved is not an error.
+func (s *QuizService) ClearAnswer(ctx context.Context, userID, quizID, questionID string) error {
+	quiz, err := s.findQuiz(ctx, quizID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	attempt, err := s.runningAttempt(ctx, quiz, userID, time.Now(), tx)
+	if err != nil {
+		return err
+	}
+	if err := s.quizRepo.DeleteSavedAnswerTx(ctx, attempt.ID, questionID, tx); err != nil {
+		return errors.New("failed to clear answer: " + err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.New("failed to commit transaction: " + err.Error())
+	}
+	return nil
+}
+
+// ResumeQuiz returns the user's running attempt as it was left: the time
+// remaining on the server's clock, the questions in the order they were
+// first shown and every answer saved so far.
+func (s *QuizService) ResumeQuiz(ctx context.Context, userID, quizID string) (*dto_quiz.ResumeQuizResponse, error) {
+	quiz, err := s.findQuiz(ctx, quizID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	attempt, err := s.runningAttempt(ctx, quiz, userID, now, tx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.attemptVersion(ctx, attempt)
+	if err != nil {
+		return nil, errors.New("failed to get quiz version: " + err.Error())
+	}
+	saved, err := s.quizRepo.FindSavedAnswersTx(ctx, attempt.ID, tx)
+	if err != nil {
+		return nil, errors.New("failed to get saved answers: " + err.Error())
+	}
+
+	res := &dto_quiz.ResumeQuizResponse{
+		Attempt: *startQuizResponse(attempt, now),
+		Quiz:    *takeQuizResponse(quiz, version, attempt),
+		Answers: make([]dto_quiz.SavedAnswer, 0, len(saved)),
+	}
+	if attempt.DeadlineAt != nil {
+		remaining := max(int(attempt.DeadlineAt.Sub(now).Seconds()), 0)
+		res.RemainingSeconds = &remaining
+	}
+	for _, sa := range saved {
+		var answer dto_quiz.Answer
+		if err := json.Unmarshal(sa.Answer, &answer); err != nil {
+			return nil, errors.New("failed to read saved answer: " + err.Error())
+		}
+		answer.QuestionID = sa.QuestionID
+		res.Answers = append(res.Answers, dto_quiz.SavedAnswer{Answer: answer, SavedAt: sa.SavedAt})
+	}
+	return res, nil
+}
+
+// runningAttempt locks the user's in-progress attempt on the quiz so answers
+// can be saved to or graded on it. An attempt found past its deadline, or on
+// a quiz that has closed, is expired and graded, and the error returned once
+// that is committed.
+func (s *QuizService) runningAttempt(ctx context.Context, quiz *models.Quiz, userID string, now time.Time, tx pgx.Tx) (*models.QuizAttempts, error) {
+	attempt, err := s.quizRepo.FindInProgressAttemptTx(ctx, quiz.ID, userID, tx)
+	if err == pgx.ErrNoRows {
+		return nil, sharedErrors.Conflict(sharedErrors.ErrAttemptNotStarted, "start the quiz first")
+	}
+	if err != nil {
+		return nil, errors.New("failed to get attempt: " + err.Error())
+	}
+
+	// Attempts running when the quiz closes get the same grace window as
+	// those running out of time.
+	if s.attemptEnded(quiz, attempt, now) {
+		if err := s.expireAttempt(ctx, quiz, attempt, now, tx); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, errors.New("failed to commit transaction: " + err.Error())
+		}
+		if !s.attemptOverdue(attempt, now) {
+			return nil, s.checkQuizOpen(quiz, now.Add(-s.attemptGrace))
+		}
+		return nil, sharedErrors.Conflict(sharedErrors.ErrAttemptExpired, "the time limit for this attempt has passed")
+	}
+	if err := s.checkQuizOpen(quiz, now.Add(-s.attemptGrace)); err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// withSavedAnswers adds the autosaved answers to those submitted. A submitted
+// answer replaces the one saved for the same question.
+func withSavedAnswers(submitted []dto_quiz.Answer, saved []models.SavedAnswer) ([]dto_quiz.Answer, error) {
+	answers := append([]dto_quiz.Answer(nil), submitted...)
+	answered := make(map[string]bool, len(submitted))
+	for _, a := range submitted {
+		answered[a.QuestionID] = true
+	}
+	for _, sa := range saved {
+		if answered[sa.QuestionID] {
+			continue
+		}
+		var answer dto_quiz.Answer
+		if err := json.Unmarshal(sa.Answer, &answer); err != nil {
+			return nil, errors.New("failed to read saved answer: " + err.Error())
+		}
+		answer.QuestionID = sa.QuestionID
+		answers = append(answers, answer)
+	}
+	return answers, nil
+}
+
+func (s *QuizService) findQuiz(ctx context.Context, quizID string) (*models.Quiz, error) {
+	quiz, err := s.quizRepo.FindByID(ctx, quizID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sharedErrors.NotFound(sharedErrors.ErrQuizNotFound, "quiz not found")
+		}
+		return nil, errors.New("failed to get quiz")
+	}
+	return quiz, nil
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UpdateQuiz replaces the quiz's title, description, duration, lifecycle
+// state and availability window. Like every edit below it writes a new quiz
+// version in the same transaction, leaving earlier attempts pinned to the
+// content they saw.
+//
+// Requests with neither status nor is_published, as sent by clients that
+// predate the lifecycle, leave the state and window alone. So do requests
+// that only send is_published for a closed or archived quiz, which the flag
+// cannot express.
+func (s *QuizService) UpdateQuiz(ctx context.Context, userID, quizID string, req *dto_quiz.UpdateQuizRequest) error {
+	quiz, err := s.manageableQuiz(ctx, userID, quizID)
+	if err != nil {
+		return err
+	}
+
+	quiz.Title = req.Title
+	quiz.Description = req.Description
+	quiz.DurationMinutes = req.DurationMinutes
+	now := time.Now()
+	if req.Status != "" || (req.IsPublished != nil && !quizEnded(quiz, now)) {
+		quiz.PublishAt = req.PublishAt
+		quiz.CloseAt = req.CloseAt
+		isPublished := req.IsPublished != nil && *req.IsPublished
+		quiz.Status, err = quizLifecycle(req.Status, isPublished, req.PublishAt, req.CloseAt, now)
+		if err != nil {
+			return err
+		}
+	}
+	if req.ShuffleQuestions != nil {
+		quiz.ShuffleQuestions = *req.ShuffleQuestions
+	}
+	if req.ShuffleOptions != nil {
+		quiz.ShuffleOptions = *req.ShuffleOptions
+	}
+	if req.MaxAttempts != nil {
+		quiz.MaxAttempts = req.MaxAttempts
+		if *req.MaxAttempts == 0 {
+			quiz.MaxAttempts = nil
+		}
+	}
+	if req.CooldownMinutes != nil {
+		quiz.CooldownMinutes = *req.CooldownMinutes
+	}
+	if req.ScoringRule != nil {
+		quiz.ScoringRule = scoringRule(*req.ScoringRule)
+	}
+	if req.PassMark != nil {
+		quiz.PassMark = req.PassMark
+		if *req.PassMark == 0 {
+			quiz.PassMark = nil
+		}
+	}
+	if req.License != "" {
+		quiz.License = req.License
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.quizRepo.UpdateTx(ctx, quiz, tx); err != nil {
+		return errors.New("failed to update quiz: " + err.Error())
+	}
+
+	return s.commitWithVersion(ctx, quizID, tx)
+}
+
+// DeleteQuiz removes the quiz together with its questions, versions and
+// attempts.
+func (s *QuizService) DeleteQuiz(ctx context.Context, userID, quizID string) error {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return err
+	}
+
+	if err := s.quizRepo.Delete(ctx, quizID); err != nil {
+		return errors.New("failed to delete quiz: " + err.Error())
+	}
+	return nil
+}
+
+// UpdateQuestion replaces the question's fields and, when req lists
+// options, its options as well. An order index of 0 keeps the question
+// where it is.
+func (s *QuizService) UpdateQuestion(ctx context.Context, userID, quizID, questionID string, req *dto_quiz.UpdateQuestionRequest) error {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return err
+	}
+	question, err := s.questionInQuiz(ctx, quizID, questionID)
+	if err != nil {
+		return err
+	}
+
+	question.QuestionText = req.QuestionText
+	question.Explanation = req.Explanation
+	question.CorrectAnswer = req.CorrectAnswer
+	if req.OrderIndex != 0 {
+		question.OrderIndex = req.OrderIndex
+	}
+	question.QuestionType, question.ScoringStrategy, err = questionKind(req.QuestionType, req.ScoringStrategy)
+	if err != nil {
+		return err
+	}
+	question.Settings = questionSettings(req.Settings)
+	question.Tag = strings.TrimSpace(req.Tag)
+	question.Difficulty = req.Difficulty
+	if err := validDifficulty(question.Difficulty); err != nil {
+		return sharedErrors.BadRequest(sharedErrors.ErrInvalidQuestion, err.Error())
+	}
+	question.Points = pointsOrDefault(req.Points)
+	question.NegativePoints = req.NegativePoints
+	if err := validPoints(question.Points, question.NegativePoints); err != nil {
+		return sharedErrors.BadRequest(sharedErrors.ErrInvalidQuestion, err.Error())
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.quizRepo.LockTx(ctx, quizID, tx); err != nil {
+		return errors.New("failed to lock quiz: " + err.Error())
+	}
+	var options []models.Option
+	if req.Options != nil {
+		options = newOptions(req.Options)
+	} else {
+		options, err = s.optionRepo.GetByQuestionIDTx(ctx, questionID, tx)
+		if err != nil {
+			return errors.New("failed to get options: " + err.Error())
+		}
+	}
+	if err := validateQuestion(question, options); err != nil {
+		return err
+	}
+	if err := s.questionRepo.UpdateTx(ctx, question, tx); err != nil {
+		if isUniqueViolation(err) {
+			return sharedErrors.Conflict(sharedErrors.ErrOrderIndexTaken, "another question already uses this order index")
+		}
+		return errors.New("failed to update question: " + err.Error())
+	}
+
+	// New options replace the old ones. Results are graded against quiz
+	// versions, which keep the options they were taken with.
+	if req.Options != nil {
+		if err := s.optionRepo.DeleteByQuestionIDTx(ctx, questionID, tx); err != nil {
+			return errors.New("failed to replace options: " + err.Error())
+		}
+		for i := range options {
+			options[i].QuestionID = questionID
+		}
+		if err := s.optionRepo.CreateBatchTx(ctx, options, tx); err != nil {
+			return errors.New("failed to replace options: " + err.Error())
+		}
+	}
+
+	return s.commitWithVersion(ctx, quizID, tx)
+}
+
+// DeleteQuestion removes a question and its options, then renumbers the
+// remaining questions from 1 so the order has no gap. A quiz must keep at
+// least one question, matching the rule enforced on creation.
+func (s *QuizService) DeleteQuestion(ctx context.Context, userID, quizID, questionID string) error {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return err
+	}
+	if _, err := s.questionInQuiz(ctx, quizID, questionID); err != nil {
+		return err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.quizRepo.LockTx(ctx, quizID, tx); err != nil {
+		return errors.New("failed to lock quiz: " + err.Error())
+	}
+	questions, err := s.questionRepo.FindIDsByQuizIDTx(ctx, quizID, tx)
+	if err != nil {
+		return errors.New("failed to get questions")
+	}
+	if len(questions) <= 1 {
+		return sharedErrors.BadRequest(sharedErrors.ErrQuizNeedsQuestion, "a quiz must have at least one question")
+	}
+
+	if err := s.questionRepo.DeleteTx(ctx, questionID, tx); err != nil {
+		return errors.New("failed to delete question: " + err.Error())
+	}
+	if err := s.questionRepo.ReindexTx(ctx, quizID, tx); err != nil {
+		return errors.New("failed to reindex questions: " + err.Error())
+	}
+
+	return s.commitWithVersion(ctx, quizID, tx)
+}
+
+func (s *QuizService) UpdateOption(ctx context.Context, userID, quizID, questionID, optionID string, req *dto_quiz.UpdateOptionRequest) error {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return err
+	}
+	option, err := s.optionInQuestion(ctx, quizID, questionID, optionID)
+	if err != nil {
+		return err
+	}
+
+	option.Text = req.Text
+	option.IsCorrect = req.IsCorrect
+	option.MatchText = req.MatchText
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.quizRepo.LockTx(ctx, quizID, tx); err != nil {
+		return errors.New("failed to lock quiz: " + err.Error())
+	}
+	if err := s.validateOptionChange(ctx, quizID, questionID, option, false, tx); err != nil {
+		return err
+	}
+	if err := s.optionRepo.UpdateTx(ctx, []models.Option{*option}, tx); err != nil {
+		return errors.New("failed to update option: " + err.Error())
+	}
+
+	return s.commitWithVersion(ctx, quizID, tx)
+}
+
+func (s *QuizService) DeleteOption(ctx context.Context, userID, quizID, questionID, optionID string) error {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return err
+	}
+	option, err := s.optionInQuestion(ctx, quizID, questionID, optionID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.quizRepo.LockTx(ctx, quizID, tx); err != nil {
+		return errors.New("failed to lock quiz: " + err.Error())
+	}
+	if err := s.validateOptionChange(ctx, quizID, questionID, option, true, tx); err != nil {
+		return err
+	}
+	if err := s.optionRepo.DeleteTx(ctx, optionID, tx); err != nil {
+		return errors.New("failed to delete option: " + err.Error())
+	}
+
+	return s.commitWithVersion(ctx, quizID, tx)
+}
+
+// manageableQuiz loads the quiz and checks that userID may edit it: the
+// quiz's creator, or the creator or an admin of its community.
+func (s *QuizService) manageableQuiz(ctx context.Context, userID, quizID string) (*models.Quiz, error) {
+	quiz, err := s.quizRepo.FindByID(ctx, quizID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sharedErrors.NotFound(sharedErrors.ErrQuizNotFound, "quiz not found")
+		}
+		return nil, errors.New("failed to get quiz")
+	}
+
+	allowed, err := s.canManage(ctx, userID, quiz)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, sharedErrors.Forbidden(sharedErrors.ErrForbidden, "only the quiz creator or a community admin can edit this quiz")
+	}
+	return quiz, nil
+}
+
+func (s *QuizService) canManage(ctx context.Context, userID string, quiz *models.Quiz) (bool, error) {
+	if quiz.CreatorID == userID {
+		return true, nil
+	}
+
+	community, err := s.communityRepo.FindByID(ctx, quiz.CommunityID)
+	if err != nil {
+		return false, errors.New("failed to get community")
+	}
+	if community.CreatorID == userID {
+		return true, nil
+	}
+
+	role, err := s.communityRepo.UserRole(ctx, quiz.CommunityID, userID)
+	if err != nil && err != pgx.ErrNoRows {
+		return false, errors.New("failed to check community membership")
+	}
+	return role == "creator" || role == "admin", nil
+}
+
+func (s *QuizService) questionInQuiz(ctx context.Context, quizID, questionID string) (*models.Question, error) {
+	question, err := s.questionRepo.GetByID(ctx, questionID)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, errors.New("failed to get question")
+	}
+	if question == nil || question.QuizID != quizID {
+		return nil, sharedErrors.NotFound(sharedErrors.ErrQuestionNotFound, "question not found")
+	}
+	return question, nil
+}
+
+func (s *QuizService) optionInQuestion(ctx context.Context, quizID, questionID, optionID string) (*models.Option, error) {
+	if _, err := s.questionInQuiz(ctx, quizID, questionID); err != nil {
+		return nil, err
+	}
+
+	option, err := s.optionRepo.GetByID(ctx, optionID)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, errors.New("failed to get option")
+	}
+	if option == nil || option.QuestionID != questionID {
+		return nil, sharedErrors.NotFound(sharedErrors.ErrOptionNotFound, "option not found")
+	}
+	return option, nil
+}
+
+// validateOptionChange checks that the question is still gradable once the
+// option is replaced by its edited form, or removed. It reads the options
+// through tx, which must already hold the quiz's lock.
+func (s *QuizService) validateOptionChange(ctx context.Context, quizID, questionID string, option *models.Option, remove bool, tx pgx.Tx) error {
+	question, err := s.questionInQuiz(ctx, quizID, questionID)
+	if err != nil {
+		return err
+	}
+	current, err := s.optionRepo.GetByQuestionIDTx(ctx, questionID, tx)
+	if err != nil {
+		return errors.New("failed to get options: " + err.Error())
+	}
+
+	found := false
+	options := make([]models.Option, 0, len(current))
+	for _, o := range current {
+		if o.ID != option.ID {
+			options = append(options, o)
+			continue
+		}
+		found = true
+		if !remove {
+			options = append(options, *option)
+		}
+	}
+	if !found {
+		return sharedErrors.NotFound(sharedErrors.ErrOptionNotFound, "option not found")
+	}
+	return validateQuestion(question, options)
+}
+
+// commitWithVersion snapshots the quiz as tx sees it and commits.
+func (s *QuizService) commitWithVersion(ctx context.Context, quizID string, tx pgx.Tx) error {
+	if _, err := s.quizVersionRepo.CreateTx(ctx, quizID, tx); err != nil {
+		return errors.New("failed to create quiz version: " + err.Error())
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return errors.New("failed to commit transaction")
+	}
+	return nil
+}
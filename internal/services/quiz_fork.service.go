@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ForkQuiz copies a quiz's current version, with its questions, options and
+// pools, into a community the user can post to. The copy starts as a draft
+// and records the quiz and author it came from.
+func (s *QuizService) ForkQuiz(ctx context.Context, userID, quizID string, req *dto_quiz.ForkQuizRequest) (string, error) {
+	source, err := s.findQuiz(ctx, quizID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkQuizVisible(ctx, userID, source, time.Now()); err != nil {
+		return "", err
+	}
+
+	allowed, err := s.canFork(ctx, userID, source)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", sharedErrors.Forbidden(sharedErrors.ErrForkNotAllowed, "the license of this quiz does not allow forking")
+	}
+	if err := s.checkCanPost(ctx, userID, req.CommunityID); err != nil {
+		return "", err
+	}
+
+	version, err := s.quizVersionRepo.FindLatest(ctx, quizID)
+	if err != nil {
+		return "", sharedErrors.BadRequest(sharedErrors.ErrQuizNeedsQuestion, "the quiz has no content to fork yet")
+	}
+
+	createReq := draftRequest(req.CommunityID, exportedQuiz(source, &version.Snapshot))
+	if req.Title != "" {
+		createReq.Title = req.Title
+	}
+	createReq.License = license(source.License)
+	if req.License != "" {
+		if licenseRank(req.License) < licenseRank(createReq.License) {
+			return "", sharedErrors.BadRequest(sharedErrors.ErrInvalidLicense, "a fork cannot have a less restrictive license than "+createReq.License)
+		}
+		createReq.License = req.License
+	}
+
+	return s.createQuiz(ctx, userID, &createReq, source)
+}
+
+// canFork reports whether the user may fork the quiz: its editors always
+// may, anyone else only under a license that allows it.
+func (s *QuizService) canFork(ctx context.Context, userID string, quiz *models.Quiz) (bool, error) {
+	if license(quiz.License) != models.LicenseAllRightsReserved {
+		return true, nil
+	}
+	return s.canManage(ctx, userID, quiz)
+}
+
+// checkCanPost allows the community's creator and members to add quizzes
+// to it.
+func (s *QuizService) checkCanPost(ctx context.Context, userID, communityID string) error {
+	community, err := s.communityRepo.FindByID(ctx, communityID)
+	if err == pgx.ErrNoRows {
+		return sharedErrors.NotFound(sharedErrors.ErrCommunityNotFound, "community not found")
+	}
+	if err != nil {
+		return errors.New("failed to get community")
+	}
+	if community.CreatorID == userID {
+		return nil
+	}
+
+	if _, err := s.communityRepo.UserRole(ctx, communityID, userID); err != nil {
+		if err == pgx.ErrNoRows {
+			return sharedErrors.Forbidden(sharedErrors.ErrForbidden, "join the community before posting quizzes to it")
+		}
+		return errors.New("failed to check community membership")
+	}
+	return nil
+}
+
+// forkSource credits the quiz and author a fork came from, as far as they
+// still exist.
+func (s *QuizService) forkSource(ctx context.Context, quiz *models.Quiz) *dto_quiz.ForkSource {
+	if quiz.ForkedFromQuiz == nil && quiz.ForkedFromUser == nil {
+		return nil
+	}
+
+	source := &dto_quiz.ForkSource{}
+	if quiz.ForkedFromQuiz != nil {
+		if original, err := s.quizRepo.FindByID(ctx, *quiz.ForkedFromQuiz); err == nil {
+			source.QuizID = original.ID
+			source.Title = original.Title
+		}
+	}
+	if quiz.ForkedFromUser != nil {
+		if author, err := s.userRepo.FindByID(ctx, *quiz.ForkedFromUser); err == nil {
+			source.Author = &dto_quiz.User{
+				ID:       author.ID,
+				Username: author.Username,
+				Email:    author.Email,
+				Avatar:   author.Avatar,
+			}
+		}
+	}
+	return source
+}
+
+// license defaults quizzes that do not pick one to all rights reserved.
+func license(l string) string {
+	if l == "" {
+		return models.LicenseAllRightsReserved
+	}
+	return l
+}
+
+// licenseRank orders licenses from the least restrictive up. A fork keeps
+// its source's license or picks a more restrictive one, so cc_by content
+// cannot be passed on as cc0.
+func licenseRank(l string) int {
+	switch license(l) {
+	case models.LicenseCC0:
+		return 0
+	case models.LicenseCCBY:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+)
+
+// ApplyQuizSchedules is run by the background scheduler to publish quizzes
+// whose publish_at has come and close those whose close_at has passed.
+// Closing waits out the submission grace window, so attempts still running
+// at close_at can be handed in.
+func (s *QuizService) ApplyQuizSchedules(ctx context.Context) error {
+	now := time.Now()
+	published, closed, err := s.quizRepo.ApplySchedules(ctx, now, now.Add(-s.attemptGrace))
+	if err != nil {
+		return err
+	}
+	if published > 0 || closed > 0 {
+		log.Printf("published %d scheduled quizzes, closed %d quizzes", published, closed)
+	}
+	return nil
+}
+
+// quizLifecycle works out the state to store for a requested status and
+// window. Clients that predate the lifecycle only send is_published, which
+// maps to published or draft. A published quiz whose publish_at is still
+// ahead is stored as scheduled, a scheduled one whose time has come as
+// published, and a published one whose close_at has passed as closed.
+func quizLifecycle(status string, isPublished bool, publishAt, closeAt *time.Time, now time.Time) (string, error) {
+	if status == "" {
+		status = models.QuizDraft
+		if isPublished {
+			status = models.QuizPublished
+		}
+	}
+	if publishAt != nil && closeAt != nil && !closeAt.After(*publishAt) {
+		return "", sharedErrors.BadRequest(sharedErrors.ErrInvalidSchedule, "close_at must be after publish_at")
+	}
+
+	switch status {
+	case models.QuizScheduled:
+		if publishAt == nil {
+			return "", sharedErrors.BadRequest(sharedErrors.ErrInvalidSchedule, "a scheduled quiz needs publish_at")
+		}
+		if !publishAt.After(now) {
+			status = models.QuizPublished
+		}
+	case models.QuizPublished:
+		if publishAt != nil && publishAt.After(now) {
+			status = models.QuizScheduled
+		}
+	}
+	if status == models.QuizPublished && closeAt != nil && !closeAt.After(now) {
+		status = models.QuizClosed
+	}
+	return status, nil
+}
+
+// quizStatus is the quiz's state at now. It runs ahead of the stored status
+// between scheduler runs, so a quiz opens and closes on time to the second.
+func quizStatus(quiz *models.Quiz, now time.Time) string {
+	status := quiz.Status
+	if status == models.QuizScheduled && quiz.PublishAt != nil && !quiz.PublishAt.After(now) {
+		status = models.QuizPublished
+	}
+	if status == models.QuizPublished && quiz.CloseAt != nil && !quiz.CloseAt.After(now) {
+		status = models.QuizClosed
+	}
+	return status
+}
+
+// quizEnded reports whether the quiz is closed or archived at now.
+func quizEnded(quiz *models.Quiz, now time.Time) bool {
+	switch quizStatus(quiz, now) {
+	case models.QuizClosed, models.QuizArchived:
+		return true
+	}
+	return false
+}
+
+// checkQuizVisible hides drafts and quizzes that are not live yet from
+// everyone but the people who may edit them. They get the same answer as for
+// a quiz that does not exist, so unpublished quizzes cannot be probed.
+func (s *QuizService) checkQuizVisible(ctx context.Context, userID string, quiz *models.Quiz, now time.Time) error {
+	switch quizStatus(quiz, now) {
+	case models.QuizDraft, models.QuizScheduled:
+	default:
+		return nil
+	}
+
+	allowed, err := s.canManage(ctx, userID, quiz)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return sharedErrors.NotFound(sharedErrors.ErrQuizNotFound, "quiz not found")
+	}
+	return nil
+}
+
+// checkQuizOpen rejects attempts and submissions unless the quiz is
+// published and inside its window at now.
+func (s *QuizService) checkQuizOpen(quiz *models.Quiz, now time.Time) error {
+	switch quizStatus(quiz, now) {
+	case models.QuizPublished:
+		return nil
+	case models.QuizClosed, models.QuizArchived:
+		return sharedErrors.Forbidden(sharedErrors.ErrQuizNotAvailable, "this quiz is closed")
+	default:
+		return sharedErrors.Forbidden(sharedErrors.ErrQuizNotAvailable, "this quiz is not open yet")
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+)
+
+// SetPools replaces the quiz's question pools. An empty list removes them,
+// so every attempt is asked every question again.
+func (s *QuizService) SetPools(ctx context.Context, userID, quizID string, req *dto_quiz.SetPoolsRequest) error {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return err
+	}
+
+	questions, err := s.questionRepo.FindByQuizID(ctx, quizID)
+	if err != nil {
+		return errors.New("failed to get questions: " + err.Error())
+	}
+	labels := make([]models.QuestionSnapshot, 0, len(questions))
+	for _, q := range questions {
+		labels = append(labels, models.QuestionSnapshot{ID: q.ID, Tag: q.Tag, Difficulty: q.Difficulty})
+	}
+
+	pools, err := newPools(req.Pools, labels)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.quizRepo.ReplacePoolsTx(ctx, quizID, pools, tx); err != nil {
+		return errors.New("failed to save pools: " + err.Error())
+	}
+
+	return s.commitWithVersion(ctx, quizID, tx)
+}
+
+// newPools builds pools from a request and checks that each one can always
+// draw its count. Pools draw in order and never draw a question twice, so
+// a pool can count only on the matching questions that the pools before it
+// cannot have taken, however their random draws fall.
+func newPools(req []dto_quiz.Pool, questions []models.QuestionSnapshot) ([]models.QuizPool, error) {
+	pools := make([]models.QuizPool, 0, len(req))
+	snapshots := make([]models.PoolSnapshot, 0, len(req))
+	for i, p := range req {
+		if err := validDifficulty(p.Difficulty); err != nil {
+			return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidPool, err.Error())
+		}
+		pool := models.PoolSnapshot{Tag: strings.TrimSpace(p.Tag), Difficulty: p.Difficulty, DrawCount: p.DrawCount}
+
+		matching, available := poolSupply(snapshots, &pool, questions)
+		if available < pool.DrawCount {
+			msg := "pool " + strconv.Itoa(i+1) + " draws " + strconv.Itoa(pool.DrawCount) + " questions but only " + strconv.Itoa(matching) + " match it"
+			if available < matching {
+				msg = "pool " + strconv.Itoa(i+1) + " draws " + strconv.Itoa(pool.DrawCount) + " questions but earlier pools may leave only " +
+					strconv.Itoa(available) + " of the " + strconv.Itoa(matching) + " that match it"
+			}
+			return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidPool, msg)
+		}
+
+		snapshots = append(snapshots, pool)
+		pools = append(pools, models.QuizPool{
+			Position:   i,
+			Tag:        pool.Tag,
+			Difficulty: pool.Difficulty,
+			DrawCount:  pool.DrawCount,
+		})
+	}
+	return pools, nil
+}
+
+// poolSupply counts the questions matching pool, and how many of them are
+// certain to be left once the earlier pools have drawn. Each earlier pool
+// takes at most its count from the questions it shares with pool, and all
+// of them together at most the shared questions.
+func poolSupply(earlier []models.PoolSnapshot, pool *models.PoolSnapshot, questions []models.QuestionSnapshot) (matching, available int) {
+	shared := make([]int, len(earlier))
+	sharedAny := 0
+	for i := range questions {
+		q := &questions[i]
+		if !poolMatches(pool, q) {
+			continue
+		}
+		matching++
+		overlaps := false
+		for j := range earlier {
+			if poolMatches(&earlier[j], q) {
+				shared[j]++
+				overlaps = true
+			}
+		}
+		if overlaps {
+			sharedAny++
+		}
+	}
+
+	taken := 0
+	for j := range earlier {
+		taken += min(earlier[j].DrawCount, shared[j])
+	}
+	return matching, matching - min(taken, sharedAny)
+}
+
+func validDifficulty(difficulty string) error {
+	switch difficulty {
+	case "", models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard:
+		return nil
+	}
+	return errors.New("unknown difficulty: " + difficulty)
+}
+
+func poolMatches(pool *models.PoolSnapshot, question *models.QuestionSnapshot) bool {
+	if pool.Tag != "" && !strings.EqualFold(pool.Tag, question.Tag) {
+		return false
+	}
+	return pool.Difficulty == "" || pool.Difficulty == question.Difficulty
+}
+
+// drawQuestions picks the questions of a new attempt. Pools draw in order,
+// each from the matching questions not drawn by an earlier pool, and take
+// fewer when questions were removed since the pool was set up. Without pools
+// every question is drawn. IDs come back in the version's question order.
+func drawQuestions(snapshot *models.QuizSnapshot, seed int64) []string {
+	drawn := make(map[string]bool, len(snapshot.Questions))
+	if len(snapshot.Pools) == 0 {
+		for _, q := range snapshot.Questions {
+			drawn[q.ID] = true
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(seed), 1))
+	for i := range snapshot.Pools {
+		pool := &snapshot.Pools[i]
+		candidates := make([]string, 0, len(snapshot.Questions))
+		for j := range snapshot.Questions {
+			q := &snapshot.Questions[j]
+			if !drawn[q.ID] && poolMatches(pool, q) {
+				candidates = append(candidates, q.ID)
+			}
+		}
+		rng.Shuffle(len(candidates), func(a, b int) {
+			candidates[a], candidates[b] = candidates[b], candidates[a]
+		})
+		for _, id := range candidates[:min(pool.DrawCount, len(candidates))] {
+			drawn[id] = true
+		}
+	}
+
+	ids := make([]string, 0, len(drawn))
+	for _, q := range snapshot.Questions {
+		if drawn[q.ID] {
+			ids = append(ids, q.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AddQuestions adds several questions with their options in one
+// transaction, so either all of them are added or none are. It returns
+// their IDs in the order they were sent.
+func (s *QuizService) AddQuestions(ctx context.Context, userID, quizID string, req *dto_quiz.AddQuestionsRequest) ([]string, error) {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	questions, err := s.addQuestions(ctx, quizID, req.Questions, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.commitWithVersion(ctx, quizID, tx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(questions))
+	for _, q := range questions {
+		ids = append(ids, q.ID)
+	}
+	return ids, nil
+}
+
+// ReorderQuestions numbers the quiz's questions from 1 in the order given.
+// Every question of the quiz must be listed exactly once.
+func (s *QuizService) ReorderQuestions(ctx context.Context, userID, quizID string, req *dto_quiz.ReorderQuestionsRequest) error {
+	if _, err := s.manageableQuiz(ctx, userID, quizID); err != nil {
+		return err
+	}
+
+	tx, err := s.quizRepo.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.quizRepo.LockTx(ctx, quizID, tx); err != nil {
+		return errors.New("failed to lock quiz: " + err.Error())
+	}
+	current, err := s.questionRepo.FindIDsByQuizIDTx(ctx, quizID, tx)
+	if err != nil {
+		return errors.New("failed to get questions: " + err.Error())
+	}
+	if err := checkQuestionOrder(current, req.QuestionIDs); err != nil {
+		return err
+	}
+
+	if err := s.questionRepo.ReorderTx(ctx, quizID, req.QuestionIDs, tx); err != nil {
+		return errors.New("failed to reorder questions: " + err.Error())
+	}
+
+	return s.commitWithVersion(ctx, quizID, tx)
+}
+
+// addQuestions creates the questions and their options in tx. It locks the
+// quiz first, so questions without an order index of their own go after
+// its last one even when others add questions at the same time.
+func (s *QuizService) addQuestions(ctx context.Context, quizID string, reqs []dto_quiz.Question, tx pgx.Tx) ([]models.Question, error) {
+	if err := s.quizRepo.LockTx(ctx, quizID, tx); err != nil {
+		return nil, errors.New("failed to lock quiz: " + err.Error())
+	}
+	last, err := s.questionRepo.MaxOrderIndexTx(ctx, quizID, tx)
+	if err != nil {
+		return nil, errors.New("failed to get questions: " + err.Error())
+	}
+
+	questions := make([]models.Question, 0, len(reqs))
+	questionOptions := make([][]models.Option, 0, len(reqs))
+	for i := range reqs {
+		question, options, err := newQuestion(quizID, &reqs[i])
+		if err != nil {
+			if len(reqs) > 1 {
+				return nil, atQuestion(i, err)
+			}
+			return nil, err
+		}
+		if question.OrderIndex == 0 {
+			last++
+			question.OrderIndex = last
+		}
+		questions = append(questions, question)
+		questionOptions = append(questionOptions, options)
+	}
+
+	if err := s.questionRepo.CreateBatchTx(ctx, questions, tx); err != nil {
+		if isUniqueViolation(err) {
+			return nil, sharedErrors.Conflict(sharedErrors.ErrOrderIndexTaken, "another question already uses this order index")
+		}
+		return nil, errors.New("failed to create question: " + err.Error())
+	}
+
+	for i, options := range questionOptions {
+		for j := range options {
+			options[j].QuestionID = questions[i].ID
+		}
+		if err := s.optionRepo.CreateBatchTx(ctx, options, tx); err != nil {
+			return nil, errors.New("failed to create options: " + err.Error())
+		}
+	}
+
+	return questions, nil
+}
+
+// checkQuestionOrder checks that order lists each of the current questions
+// once and nothing else.
+func checkQuestionOrder(current, order []string) error {
+	invalid := sharedErrors.BadRequest(sharedErrors.ErrInvalidOrder, "list every question of the quiz exactly once")
+	if len(order) != len(current) {
+		return invalid
+	}
+
+	inQuiz := make(map[string]bool, len(current))
+	for _, id := range current {
+		inQuiz[id] = true
+	}
+	seen := make(map[string]bool, len(order))
+	for _, id := range order {
+		id = strings.ToLower(id)
+		if !inQuiz[id] || seen[id] {
+			return invalid
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// atQuestion says which question of a batch err is about.
+func atQuestion(i int, err error) error {
+	var appErr *sharedErrors.AppError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+	withIndex := *appErr
+	withIndex.Message = fmt.Sprintf("question %d: %s", i+1, appErr.Message)
+	return &withIndex
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+)
+
+// maxImportBytes caps uploaded question banks.
+const maxImportBytes = 5 << 20
+
+// quizFormat is implemented once per file format quizzes can be exported to
+// and imported from.
+type quizFormat interface {
+	contentType() string
+	extension() string
+	// encode writes the quiz in this format.
+	encode(quiz *dto_quiz.ExportedQuiz) ([]byte, error)
+	// decode reads back as much of the file as it can. Anything that cannot
+	// be read is reported as an issue rather than an error, so a dry run can
+	// list every problem at once.
+	decode(data []byte) parsedQuiz
+}
+
+var quizFormats = map[string]quizFormat{
+	"json": jsonFormat{},
+	"csv":  csvFormat{},
+	"gift": giftFormat{},
+	"qti":  qtiFormat{},
+}
+
+// parsedQuiz is a decoded file. sources[i] is where question i starts.
+type parsedQuiz struct {
+	quiz    dto_quiz.ExportedQuiz
+	sources []source
+	issues  []dto_quiz.ImportIssue
+}
+
+// source is a position in an imported file. file is only set for files
+// read out of an archive.
+type source struct {
+	file string
+	line int
+}
+
+func (p *parsedQuiz) addQuestion(q dto_quiz.Question, at source) {
+	p.quiz.Questions = append(p.quiz.Questions, q)
+	p.sources = append(p.sources, at)
+}
+
+func (p *parsedQuiz) fail(at source, msg string) {
+	p.issues = append(p.issues, dto_quiz.ImportIssue{File: at.file, Line: at.line, Message: msg})
+}
+
+// ExportQuiz writes the quiz's current version in the given format. Exports
+// include the answers, so only the quiz's editors may make them.
+func (s *QuizService) ExportQuiz(ctx context.Context, userID, quizID, format string) (*dto_quiz.QuizExport, error) {
+	f, ok := quizFormats[format]
+	if !ok {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrUnsupportedFormat, "format must be json, csv, gift or qti")
+	}
+
+	quiz, err := s.manageableQuiz(ctx, userID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.quizVersionRepo.FindLatest(ctx, quizID)
+	if err != nil {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrQuizNeedsQuestion, "the quiz has no content to export yet")
+	}
+
+	exported := exportedQuiz(quiz, &version.Snapshot)
+	data, err := f.encode(&exported)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto_quiz.QuizExport{
+		Filename:    exportFilename(exported.Title) + "." + f.extension(),
+		ContentType: f.contentType(),
+		Data:        data,
+	}, nil
+}
+
+// ImportQuiz reads a question bank and, unless it is a dry run or the file
+// has errors, creates a draft quiz from it. The report lists every problem
+// found, each with the line it is on.
+func (s *QuizService) ImportQuiz(ctx context.Context, userID string, req *dto_quiz.ImportQuizRequest, file *multipart.FileHeader) (*dto_quiz.ImportReport, error) {
+	if !req.DryRun && req.CommunityID == "" {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidImport, "community_id is required unless dry_run is set")
+	}
+	if file.Size > maxImportBytes {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidImport, "file too large")
+	}
+
+	name := req.Format
+	if name == "" {
+		name = formatFromFilename(file.Filename)
+	}
+	f, ok := quizFormats[name]
+	if !ok {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrUnsupportedFormat, "cannot tell the file format, send format as json, csv, gift or qti")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidImport, "failed to read file")
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxImportBytes))
+	if err != nil {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrInvalidImport, "failed to read file")
+	}
+
+	parsed := f.decode(data)
+	quiz := parsed.quiz
+	if req.Title != "" {
+		quiz.Title = req.Title
+	}
+	createReq := draftRequest(req.CommunityID, quiz)
+
+	issues := append(parsed.issues, checkImport(&createReq, parsed.sources)...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+
+	report := &dto_quiz.ImportReport{
+		Format:    name,
+		Valid:     len(issues) == 0,
+		Questions: len(createReq.Questions),
+		Errors:    issues,
+		Quiz:      createReq,
+	}
+	if report.Errors == nil {
+		report.Errors = []dto_quiz.ImportIssue{}
+	}
+	if !report.Valid || req.DryRun {
+		return report, nil
+	}
+
+	quizID, err := s.CreateQuiz(ctx, userID, &createReq)
+	if err != nil {
+		return nil, err
+	}
+	report.QuizID = quizID
+	return report, nil
+}
+
+// draftRequest asks for quiz to be created as a draft in the community.
+// Questions keep the order they come in.
+func draftRequest(communityID string, quiz dto_quiz.ExportedQuiz) dto_quiz.CreateQuizRequest {
+	req := dto_quiz.CreateQuizRequest{
+		CommunityID:      communityID,
+		Title:            strings.TrimSpace(quiz.Title),
+		Description:      quiz.Description,
+		DurationMinutes:  quiz.DurationMinutes,
+		Status:           models.QuizDraft,
+		ShuffleQuestions: quiz.ShuffleQuestions,
+		ShuffleOptions:   quiz.ShuffleOptions,
+		MaxAttempts:      quiz.MaxAttempts,
+		CooldownMinutes:  quiz.CooldownMinutes,
+		ScoringRule:      quiz.ScoringRule,
+		PassMark:         quiz.PassMark,
+		Pools:            quiz.Pools,
+		Questions:        quiz.Questions,
+	}
+	for i := range req.Questions {
+		req.Questions[i].OrderIndex = i + 1
+	}
+	return req
+}
+
+// checkImport applies the checks CreateQuiz and its request binding would,
+// reporting each failure at the line of the question it concerns.
+func checkImport(req *dto_quiz.CreateQuizRequest, sources []source) []dto_quiz.ImportIssue {
+	var issues []dto_quiz.ImportIssue
+	quizIssue := func(msg string) {
+		issues = append(issues, dto_quiz.ImportIssue{Message: msg})
+	}
+
+	switch {
+	case req.Title == "":
+		quizIssue("the quiz needs a title; send one in the title field")
+	case len(req.Title) > 200:
+		quizIssue("the title is longer than 200 characters")
+	}
+	if len(req.Description) > 1000 {
+		quizIssue("the description is longer than 1000 characters")
+	}
+	if req.DurationMinutes < 0 {
+		quizIssue("duration_minutes cannot be negative")
+	}
+	if req.MaxAttempts != nil && *req.MaxAttempts < 1 {
+		quizIssue("max_attempts must be at least 1")
+	}
+	if req.CooldownMinutes < 0 {
+		quizIssue("cooldown_minutes cannot be negative")
+	}
+	switch req.ScoringRule {
+	case "", models.ScoringRuleFirst, models.ScoringRuleBest, models.ScoringRuleLatest, models.ScoringRuleAverage:
+	default:
+		quizIssue("unknown scoring_rule: " + req.ScoringRule)
+	}
+	if req.PassMark != nil && (*req.PassMark < 0 || *req.PassMark > 100) {
+		quizIssue("pass_mark must be between 0 and 100")
+	}
+	if len(req.Questions) == 0 {
+		quizIssue("the file has no questions")
+		return issues
+	}
+
+	labels := make([]models.QuestionSnapshot, 0, len(req.Questions))
+	for i := range req.Questions {
+		q := &req.Questions[i]
+		questionIssue := func(msg string) {
+			issue := dto_quiz.ImportIssue{Question: i + 1, Message: msg}
+			if i < len(sources) {
+				issue.File, issue.Line = sources[i].file, sources[i].line
+			}
+			issues = append(issues, issue)
+		}
+
+		if strings.TrimSpace(q.QuestionText) == "" {
+			questionIssue("question text is empty")
+		}
+		if len(q.Tag) > 50 {
+			questionIssue("tag is longer than 50 characters")
+		}
+		for j, o := range q.Options {
+			if strings.TrimSpace(o.Text) == "" {
+				questionIssue("option " + strconv.Itoa(j+1) + " has no text")
+			}
+		}
+		if _, _, err := newQuestion("", q); err != nil {
+			questionIssue(err.Error())
+		}
+		labels = append(labels, models.QuestionSnapshot{ID: strconv.Itoa(i), Tag: strings.TrimSpace(q.Tag), Difficulty: q.Difficulty})
+	}
+
+	if _, err := newPools(req.Pools, labels); err != nil {
+		quizIssue(err.Error())
+	}
+	return issues
+}
+
+// exportedQuiz is the quiz as exported: settings from the quiz itself and
+// content from its current version.
+func exportedQuiz(quiz *models.Quiz, snapshot *models.QuizSnapshot) dto_quiz.ExportedQuiz {
+	exported := dto_quiz.ExportedQuiz{
+		Title:            snapshot.Title,
+		Description:      snapshot.Description,
+		DurationMinutes:  snapshot.DurationMinutes,
+		ShuffleQuestions: snapshot.ShuffleQuestions,
+		ShuffleOptions:   snapshot.ShuffleOptions,
+		MaxAttempts:      quiz.MaxAttempts,
+		CooldownMinutes:  quiz.CooldownMinutes,
+		ScoringRule:      scoringRule(quiz.ScoringRule),
+		PassMark:         quiz.PassMark,
+		Pools:            make([]dto_quiz.Pool, 0, len(snapshot.Pools)),
+		Questions:        make([]dto_quiz.Question, 0, len(snapshot.Questions)),
+	}
+	for _, p := range snapshot.Pools {
+		exported.Pools = append(exported.Pools, dto_quiz.Pool{Tag: p.Tag, Difficulty: p.Difficulty, DrawCount: p.DrawCount})
+	}
+
+	for i := range snapshot.Questions {
+		q := &snapshot.Questions[i]
+		options := append([]models.OptionSnapshot(nil), q.Options...)
+		sort.SliceStable(options, func(a, b int) bool { return options[a].Position < options[b].Position })
+
+		question := dto_quiz.Question{
+			QuestionText:    q.QuestionText,
+			Explanation:     q.Explanation,
+			CorrectAnswer:   q.CorrectAnswer,
+			OrderIndex:      i + 1,
+			QuestionType:    questionType(q),
+			ScoringStrategy: scoringStrategy(q),
+			Settings: dto_quiz.QuestionSettings{
+				AcceptedAnswers: q.Settings.AcceptedAnswers,
+				MaxEditDistance: q.Settings.MaxEditDistance,
+				Tolerance:       q.Settings.Tolerance,
+				Unit:            q.Settings.Unit,
+				UnitFactors:     q.Settings.UnitFactors,
+			},
+			Tag:            q.Tag,
+			Difficulty:     q.Difficulty,
+			Points:         questionPoints(q),
+			NegativePoints: q.NegativePoints,
+			Options:        make([]dto_quiz.Option, 0, len(options)),
+		}
+		for _, o := range options {
+			question.Options = append(question.Options, dto_quiz.Option{Text: o.Text, IsCorrect: o.IsCorrect, MatchText: o.MatchText})
+		}
+		exported.Questions = append(exported.Questions, question)
+	}
+	return exported
+}
+
+func formatFromFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	case ".gift", ".txt":
+		return "gift"
+	case ".zip", ".xml":
+		return "qti"
+	}
+	return ""
+}
+
+// exportFilename turns a quiz title into a file name: lower case letters
+// and digits separated by dashes.
+func exportFilename(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "quiz"
+	}
+	return b.String()
+}
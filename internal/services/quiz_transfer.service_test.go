@@ -0,0 +1,179 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	dto_quiz "ecoquiz/internal/dto/quiz"
+	"ecoquiz/internal/models"
+)
+
+// sampleQuiz has one question of every type, with the settings each format
+// may or may not carry.
+func sampleQuiz() dto_quiz.ExportedQuiz {
+	maxAttempts := 3
+	passMark := 60.0
+	return dto_quiz.ExportedQuiz{
+		Title:            "Climate basics",
+		Description:      "A short quiz on energy and climate.",
+		DurationMinutes:  15,
+		ShuffleQuestions: true,
+		MaxAttempts:      &maxAttempts,
+		CooldownMinutes:  10,
+		ScoringRule:      models.ScoringRuleBest,
+		PassMark:         &passMark,
+		Pools:            []dto_quiz.Pool{{Tag: "energy", DrawCount: 2}},
+		Questions: []dto_quiz.Question{
+			{
+				QuestionText: "Which gas {CO2 or O2} do plants take in = absorb?",
+				Explanation:  "Plants use it for photosynthesis: see chapter #2.",
+				QuestionType: models.QuestionSingleChoice,
+				Tag:          "energy",
+				Difficulty:   "easy",
+				Points:       2,
+				Options: []dto_quiz.Option{
+					{Text: "CO2", IsCorrect: true},
+					{Text: "O2"},
+					{Text: "*N2"},
+				},
+			},
+			{
+				QuestionText:    "Which sources are renewable?",
+				QuestionType:    models.QuestionMultipleChoice,
+				ScoringStrategy: models.ScoringProportional,
+				Tag:             "energy",
+				NegativePoints:  0.5,
+				Options: []dto_quiz.Option{
+					{Text: "Solar", IsCorrect: true},
+					{Text: "Wind", IsCorrect: true},
+					{Text: "Coal"},
+				},
+			},
+			{
+				QuestionText:  "The ozone layer blocks most UV light.",
+				CorrectAnswer: "true",
+				QuestionType:  models.QuestionTrueFalse,
+				Tag:           "climate",
+			},
+			{
+				QuestionText:  "Which greenhouse gas do cows produce?",
+				CorrectAnswer: "methane",
+				QuestionType:  models.QuestionShortAnswer,
+				Tag:           "climate",
+				Difficulty:    "medium",
+				Settings: dto_quiz.QuestionSettings{
+					AcceptedAnswers: []string{"CH4"},
+					MaxEditDistance: 1,
+				},
+			},
+			{
+				QuestionText:  "How far is the tropopause above the poles?",
+				CorrectAnswer: "9",
+				QuestionType:  models.QuestionNumeric,
+				Tag:           "climate",
+				Settings: dto_quiz.QuestionSettings{
+					Tolerance:   0.5,
+					Unit:        "km",
+					UnitFactors: map[string]float64{"m": 0.001},
+				},
+			},
+			{
+				QuestionText: "Order these from the smallest carbon footprint up.",
+				QuestionType: models.QuestionOrdering,
+				Tag:          "climate",
+				Options: []dto_quiz.Option{
+					{Text: "Train"},
+					{Text: "Car"},
+					{Text: "Plane"},
+				},
+			},
+			{
+				QuestionText:    "Match each source with what it uses.",
+				QuestionType:    models.QuestionMatching,
+				ScoringStrategy: models.ScoringRightMinusWrong,
+				Tag:             "climate",
+				Options: []dto_quiz.Option{
+					{Text: "Solar panel", MatchText: strPtr("Sunlight")},
+					{Text: "Turbine", MatchText: strPtr("Wind")},
+				},
+			},
+		},
+	}
+}
+
+// roundTrip exports the quiz in format and imports it again, failing the
+// test on any import issue.
+func roundTrip(t *testing.T, format quizFormat, quiz dto_quiz.ExportedQuiz) parsedQuiz {
+	t.Helper()
+	data, err := format.encode(&quiz)
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	parsed := format.decode(data)
+	if len(parsed.issues) > 0 {
+		t.Fatalf("decode() issues = %+v\n%s", parsed.issues, data)
+	}
+	if len(parsed.sources) != len(parsed.quiz.Questions) {
+		t.Fatalf("decode() gave %d sources for %d questions", len(parsed.sources), len(parsed.quiz.Questions))
+	}
+	return parsed
+}
+
+// checkQuestions compares imported questions with what the format carries
+// of the exported ones.
+func checkQuestions(t *testing.T, got []dto_quiz.Question, exported []dto_quiz.Question, carried func(q dto_quiz.Question) dto_quiz.Question) {
+	t.Helper()
+	if len(got) != len(exported) {
+		t.Fatalf("imported %d questions, want %d", len(got), len(exported))
+	}
+	for i := range exported {
+		want := carried(exported[i])
+		if !reflect.DeepEqual(got[i], want) {
+			t.Errorf("question %d:\n got %+v\nwant %+v", i+1, got[i], want)
+		}
+	}
+}
+
+// issueLines lists the lines of the issues reported.
+func issueLines(issues []dto_quiz.ImportIssue) []int {
+	lines := make([]int, 0, len(issues))
+	for _, issue := range issues {
+		lines = append(lines, issue.Line)
+	}
+	return lines
+}
+
+func TestCheckImportReportsQuestionLines(t *testing.T) {
+	parsed := jsonFormat{}.decode([]byte(`{
+  "format": "ecoquiz.quiz",
+  "version": 1,
+  "quiz": {
+    "title": "Lines",
+    "questions": [
+      {"question_text": "fine", "question_type": "true_false", "correct_answer": "true"},
+
+      {
+        "question_text": "no correct option",
+        "options": [{"text": "a"}, {"text": "b"}]
+      },
+      {"question_text": "fine", "question_type": "true_false", "correct_answer": "false"}, {"question_text": "", "question_type": "true_false", "correct_answer": "true"}
+    ]
+  }
+}`))
+	if len(parsed.issues) > 0 {
+		t.Fatalf("decode() issues = %+v", parsed.issues)
+	}
+	if want := []source{{line: 7}, {line: 9}, {line: 13}, {line: 13}}; !reflect.DeepEqual(parsed.sources, want) {
+		t.Fatalf("decode() sources = %+v, want %+v", parsed.sources, want)
+	}
+
+	req := draftRequest("", parsed.quiz)
+	issues := checkImport(&req, parsed.sources)
+	want := []dto_quiz.ImportIssue{
+		{Line: 9, Question: 2, Message: "single_choice questions need at least one correct option"},
+		{Line: 13, Question: 4, Message: "question text is empty"},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Fatalf("checkImport() = %+v, want %+v", issues, want)
+	}
+}
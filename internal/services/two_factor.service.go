@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"ecoquiz/internal/models"
+	sharedErrors "ecoquiz/internal/shared/errors"
+	"ecoquiz/internal/utils"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	twoFactorChallengePurpose = "2fa_challenge"
+	twoFactorChallengeTTL     = 5 * time.Minute
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// 32 characters without l, o, 0 and 1, so v%32 is unbiased.
+	recoveryCodeChars = "abcdefghijkmnpqrstuvwxyz23456789"
+)
+
+// SignInResult is returned by the password and Google sign-in flows. When the
+// account has two-factor authentication enabled no session is created yet;
+// ChallengeToken must be exchanged together with a code through
+// VerifyTwoFactorLogin.
+type SignInResult struct {
+	Tokens         *AuthTokens
+	ChallengeToken string
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// beginSignIn is the last step of every interactive login: it either starts
+// the session or, for two-factor accounts, hands out a challenge token.
+func (s *AuthService) beginSignIn(ctx context.Context, user *models.User, meta SessionMeta) (*SignInResult, error) {
+	enabled, err := s.twoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		challenge, err := utils.GeneratePurposeToken(twoFactorChallengePurpose, user.ID, nil, s.settings.JwtSecret, twoFactorChallengeTTL)
+		if err != nil {
+			return nil, errors.New("failed to generate challenge token")
+		}
+		return &SignInResult{ChallengeToken: challenge}, nil
+	}
+
+	tokens, err := s.startSession(ctx, user, meta)
+	if err != nil {
+		return nil, err
+	}
+	return &SignInResult{Tokens: tokens}, nil
+}
+
+// VerifyTwoFactorLogin completes a login that returned a challenge token. The
+// code may be a current authenticator code or an unused recovery code.
+func (s *AuthService) VerifyTwoFactorLogin(ctx context.Context, challengeToken, code string, meta SessionMeta) (*AuthTokens, error) {
+	claims, err := utils.ParsePurposeToken(challengeToken, twoFactorChallengePurpose, s.settings.JwtSecret)
+	if err != nil {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrInvalidToken, "login challenge expired, sign in again")
+	}
+	userID, _ := claims["sub"].(string)
+
+	totp, err := s.confirmedTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to get user")
+	}
+	return s.startSession(ctx, user, meta)
+}
+
+func (s *AuthService) TwoFactorStatus(ctx context.Context, userID string) (*TwoFactorStatus, error) {
+	enabled, err := s.twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: enabled}
+	if enabled {
+		status.RecoveryCodesRemaining, err = s.twoFactorRepo.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, errors.New("failed to count recovery codes")
+		}
+	}
+	return status, nil
+}
+
+// SetupTwoFactor generates a new secret for the user. Two-factor login is not
+// enforced until the user proves their app works with ConfirmTwoFactor.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, userID string) (*TwoFactorSetup, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to get user")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate secret")
+	}
+	sealed, err := s.secretBox.Seal(secret)
+	if err != nil {
+		return nil, errors.New("failed to encrypt secret")
+	}
+
+	err = s.twoFactorRepo.SavePending(ctx, userID, sealed)
+	if err == pgx.ErrNoRows {
+		return nil, sharedErrors.Conflict(sharedErrors.ErrTwoFactorEnabled, "two-factor authentication is already enabled")
+	}
+	if err != nil {
+		return nil, errors.New("failed to save secret")
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.settings.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor login and returns the recovery codes.
+// They are only stored hashed, so this is the one time they can be shown.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, userID, code string) ([]string, error) {
+	totp, err := s.twoFactorRepo.FindByUserID(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrTwoFactorNotEnabled, "start two-factor setup first")
+	}
+	if err != nil {
+		return nil, errors.New("failed to get two-factor settings")
+	}
+	if totp.ConfirmedAt != nil {
+		return nil, sharedErrors.Conflict(sharedErrors.ErrTwoFactorEnabled, "two-factor authentication is already enabled")
+	}
+
+	step, err := s.validateTOTP(totp, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.twoFactorRepo.Confirm(ctx, userID, step, hashes)
+	if err == pgx.ErrNoRows {
+		return nil, sharedErrors.Unauthorized(sharedErrors.ErrInvalidTwoFactorCode, "invalid two-factor code")
+	}
+	if err != nil {
+		return nil, errors.New("failed to enable two-factor authentication")
+	}
+	return codes, nil
+}
+
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	totp, err := s.confirmedTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkSecondFactor(ctx, totp, code); err != nil {
+		return err
+	}
+	if err := s.twoFactorRepo.Delete(ctx, userID); err != nil {
+		return errors.New("failed to disable two-factor authentication")
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	totp, err := s.confirmedTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, errors.New("failed to save recovery codes")
+	}
+	return codes, nil
+}
+
+func (s *AuthService) twoFactorEnabled(ctx context.Context, userID string) (bool, error) {
+	totp, err := s.twoFactorRepo.FindByUserID(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New("failed to get two-factor settings")
+	}
+	return totp.ConfirmedAt != nil, nil
+}
+
+func (s *AuthService) confirmedTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	totp, err := s.twoFactorRepo.FindByUserID(ctx, userID)
+	if err == pgx.ErrNoRows || (err == nil && totp.ConfirmedAt == nil) {
+		return nil, sharedErrors.BadRequest(sharedErrors.ErrTwoFactorNotEnabled, "two-factor authentication is not enabled")
+	}
+	if err != nil {
+		return nil, errors.New("failed to get two-factor settings")
+	}
+	return totp, nil
+}
+
+// checkSecondFactor verifies code, locking the account's second factor out
+// after repeated failures so the 6-digit code space cannot be brute-forced.
+func (s *AuthService) checkSecondFactor(ctx context.Context, totp *models.UserTOTP, code string) error {
+	key := "2fa:" + totp.UserID
+	if err := s.checkLockout(ctx, key); err != nil {
+		return err
+	}
+
+	err := s.verifySecondFactor(ctx, totp, code)
+	var appErr *sharedErrors.AppError
+	if errors.As(err, &appErr) && appErr.Code == sharedErrors.ErrInvalidTwoFactorCode {
+		if lockErr := s.recordFailure(ctx, key); lockErr != nil {
+			return lockErr
+		}
+		return err
+	}
+	if err == nil {
+		s.clearFailures(ctx, key)
+	}
+	return err
+}
+
+// verifySecondFactor accepts either an authenticator code, which is burned so
+// it cannot be replayed within its validity window, or a recovery code.
+func (s *AuthService) verifySecondFactor(ctx context.Context, totp *models.UserTOTP, code string) error {
+	code = strings.TrimSpace(code)
+
+	if isTOTPCode(code) {
+		step, err := s.validateTOTP(totp, code)
+		if err != nil {
+			return err
+		}
+		err = s.twoFactorRepo.UseStep(ctx, totp.UserID, step)
+		if err == pgx.ErrNoRows {
+			return sharedErrors.Unauthorized(sharedErrors.ErrInvalidTwoFactorCode, "this code has already been used")
+		}
+		if err != nil {
+			return errors.New("failed to record two-factor code")
+		}
+		return nil
+	}
+
+	err := s.twoFactorRepo.UseRecoveryCode(ctx, totp.UserID, hashRecoveryCode(code))
+	if err == pgx.ErrNoRows {
+		return sharedErrors.Unauthorized(sharedErrors.ErrInvalidTwoFactorCode, "invalid two-factor code")
+	}
+	if err != nil {
+		return errors.New("failed to check recovery code")
+	}
+	return nil
+}
+
+func (s *AuthService) validateTOTP(totp *models.UserTOTP, code string) (int64, error) {
+	secret, err := s.secretBox.Open(totp.SecretEncrypted)
+	if err != nil {
+		return 0, errors.New("failed to decrypt two-factor secret")
+	}
+	step, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= totp.LastUsedStep {
+		return 0, sharedErrors.Unauthorized(sharedErrors.ErrInvalidTwoFactorCode, "invalid two-factor code")
+	}
+	return step, nil
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRecoveryCodes returns the codes to show the user, formatted as
+// xxxxx-xxxxx, and the hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	buf := make([]byte, recoveryCodeLength)
+
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, errors.New("failed to generate recovery codes")
+		}
+		var b strings.Builder
+		for j, v := range buf {
+			if j == recoveryCodeLength/2 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(recoveryCodeChars[int(v)%32])
+		}
+		codes[i] = b.String()
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed the
+// way they were written down.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	return utils.HashToken(normalized)
+}
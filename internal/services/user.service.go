@@ -4,23 +4,43 @@ import (
 	"context"
 	dto_user "ecoquiz/internal/dto/user"
 	"ecoquiz/internal/repos"
+	sharedErrors "ecoquiz/internal/shared/errors"
 	"ecoquiz/internal/utils"
 	"errors"
 	"fmt"
+	"log"
 	"mime/multipart"
+	"time"
 )
 
 type UserService struct {
-	userRepo repos.UserRepo
-	commRepo repos.CommunityRepo
-	quizRepo repos.QuizRepo
+	userRepo        repos.UserRepo
+	commRepo        repos.CommunityRepo
+	quizRepo        repos.QuizRepo
+	sessionRepo     repos.SessionRepo
+	accessTokenRepo repos.AccessTokenRepo
+	exportRepo      repos.ExportRepo
+
+	deletionGrace time.Duration
 }
 
-func NewUserService(userRepo repos.UserRepo, commRepo repos.CommunityRepo, quizRepo repos.QuizRepo) *UserService {
+func NewUserService(
+	userRepo repos.UserRepo,
+	commRepo repos.CommunityRepo,
+	quizRepo repos.QuizRepo,
+	sessionRepo repos.SessionRepo,
+	accessTokenRepo repos.AccessTokenRepo,
+	exportRepo repos.ExportRepo,
+	deletionGrace time.Duration,
+) *UserService {
 	return &UserService{
-		userRepo: userRepo,
-		commRepo: commRepo,
-		quizRepo: quizRepo,
+		userRepo:        userRepo,
+		commRepo:        commRepo,
+		quizRepo:        quizRepo,
+		sessionRepo:     sessionRepo,
+		accessTokenRepo: accessTokenRepo,
+		exportRepo:      exportRepo,
+		deletionGrace:   deletionGrace,
 	}
 }
 
@@ -38,7 +58,10 @@ func (s *UserService) Profile(ctx context.Context, userID string) (*dto_user.Pro
 		Banner:      existUser.Banner,
 		Communities: make([]dto_user.Community, 0),
 		Attempts:    make([]dto_user.Attempt, 0),
+		Results:     make([]dto_user.Result, 0),
 		CreatedAt:   existUser.CreatedAt,
+
+		DeletionScheduledAt: existUser.DeletionScheduledAt,
 	}
 
 	// Fetch communities with full details
@@ -73,6 +96,7 @@ func (s *UserService) Profile(ctx context.Context, userID string) (*dto_user.Pro
 					QuestionsCount: a.Quiz.QuestionsCount,
 				},
 				Score:            a.Score,
+				TimeTakenSeconds: a.TimeTakenSeconds,
 				TimeTakenMinutes: a.TimeTakenMinutes,
 				AttemptNumber:    a.AttemptNumber,
 				Percentage:       int(a.Percentage),
@@ -82,6 +106,25 @@ func (s *UserService) Profile(ctx context.Context, userID string) (*dto_user.Pro
 		}
 	}
 
+	results, err := s.quizRepo.FindOfficialResultsByUserID(ctx, userID)
+	if err == nil {
+		for _, r := range results {
+			ProfileRes.Results = append(ProfileRes.Results, dto_user.Result{
+				Quiz: dto_user.Quiz{
+					ID:             r.Quiz.ID,
+					Title:          r.Quiz.Title,
+					QuestionsCount: r.Quiz.QuestionsCount,
+				},
+				AttemptID:     r.AttemptID,
+				ScoringRule:   r.ScoringRule,
+				Score:         r.Score,
+				Percentage:    r.Percentage,
+				AttemptsCount: r.AttemptsCount,
+				CompletedAt:   r.CompletedAt,
+			})
+		}
+	}
+
 	return &ProfileRes, nil
 }
 
@@ -122,3 +165,68 @@ func (s *UserService) UpdateBanner(ctx context.Context, userID string, file *mul
 	}
 	return BannerURL, nil
 }
+
+// ScheduleDeletion starts the deletion grace period and signs the user out
+// everywhere. Signing in again during the grace period is allowed so the
+// deletion can be cancelled; afterwards PurgeDeletedAccounts removes the
+// account and everything it owns.
+func (s *UserService) ScheduleDeletion(ctx context.Context, userID string) (time.Time, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, errors.New("failed to get user")
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	deleteAt := time.Now().Add(s.deletionGrace)
+	if err := s.userRepo.ScheduleDeletion(ctx, userID, deleteAt); err != nil {
+		return time.Time{}, errors.New("failed to schedule account deletion")
+	}
+	if err := s.sessionRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return time.Time{}, errors.New("failed to revoke sessions")
+	}
+	if err := s.accessTokenRepo.DeleteAllForUser(ctx, userID); err != nil {
+		return time.Time{}, errors.New("failed to revoke access tokens")
+	}
+	return deleteAt, nil
+}
+
+func (s *UserService) CancelDeletion(ctx context.Context, userID string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return errors.New("failed to get user")
+	}
+	if user.DeletionScheduledAt == nil {
+		return sharedErrors.BadRequest(sharedErrors.ErrDeletionNotScheduled, "account is not scheduled for deletion")
+	}
+	if err := s.userRepo.CancelDeletion(ctx, userID); err != nil {
+		return errors.New("failed to cancel account deletion")
+	}
+	return nil
+}
+
+// PurgeDeletedAccounts deletes the accounts whose grace period has ended. It
+// runs as a background job.
+func (s *UserService) PurgeDeletedAccounts(ctx context.Context) error {
+	deleted, err := s.userRepo.DeleteScheduled(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("purged %d deleted accounts", deleted)
+	}
+	return nil
+}
+
+func (s *UserService) ExportData(ctx context.Context, userID string) (*dto_user.UserExport, error) {
+	sections, err := s.exportRepo.ExportUser(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to export user data")
+	}
+	return &dto_user.UserExport{
+		FormatVersion: dto_user.ExportFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		Sections:      sections,
+	}, nil
+}
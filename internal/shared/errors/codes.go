@@ -7,12 +7,82 @@ const (
 	ErrWeakPassword  = "WEAK_PASSWORD"
 	ErrEmailExists   = "EMAIL_ALREADY_EXISTS"
 	ErrUnauthorized  = "UNAUTHORIZED"
+	ErrInvalidToken  = "INVALID_TOKEN"
+
+	ErrEmailNotVerified     = "EMAIL_NOT_VERIFIED"
+	ErrEmailAlreadyVerified = "EMAIL_ALREADY_VERIFIED"
+)
+
+// Two-factor errors
+const (
+	ErrTwoFactorEnabled     = "TWO_FACTOR_ALREADY_ENABLED"
+	ErrTwoFactorNotEnabled  = "TWO_FACTOR_NOT_ENABLED"
+	ErrInvalidTwoFactorCode = "INVALID_TWO_FACTOR_CODE"
+)
+
+// Identity errors
+const (
+	ErrIdentityLinked   = "IDENTITY_ALREADY_LINKED"
+	ErrIdentityNotFound = "IDENTITY_NOT_FOUND"
+	ErrLastSignInMethod = "LAST_SIGN_IN_METHOD"
+)
+
+// Access token errors
+const (
+	ErrInvalidScope         = "INVALID_SCOPE"
+	ErrInsufficientScope    = "INSUFFICIENT_SCOPE"
+	ErrSessionRequired      = "SESSION_REQUIRED"
+	ErrAccessTokenNameTaken = "ACCESS_TOKEN_NAME_TAKEN"
+	ErrAccessTokenNotFound  = "ACCESS_TOKEN_NOT_FOUND"
 )
 
 // User errors
 const (
 	ErrUserNotFound = "USER_NOT_FOUND"
 	ErrForbidden    = "FORBIDDEN"
+
+	ErrReauthRequired       = "REAUTHENTICATION_REQUIRED"
+	ErrDeletionNotScheduled = "DELETION_NOT_SCHEDULED"
+)
+
+// Quiz errors
+const (
+	ErrQuizNotFound        = "QUIZ_NOT_FOUND"
+	ErrQuestionNotFound    = "QUESTION_NOT_FOUND"
+	ErrOptionNotFound      = "OPTION_NOT_FOUND"
+	ErrQuizNeedsQuestion   = "QUIZ_NEEDS_QUESTION"
+	ErrOrderIndexTaken     = "ORDER_INDEX_TAKEN"
+	ErrInvalidOrder        = "INVALID_ORDER"
+	ErrInvalidQuestionType = "INVALID_QUESTION_TYPE"
+	ErrInvalidQuestion     = "INVALID_QUESTION"
+	ErrInvalidPool         = "INVALID_POOL"
+	ErrInvalidSchedule     = "INVALID_SCHEDULE"
+	ErrQuizNotAvailable    = "QUIZ_NOT_AVAILABLE"
+	ErrUnsupportedFormat   = "UNSUPPORTED_FORMAT"
+	ErrInvalidImport       = "INVALID_IMPORT"
+	ErrForkNotAllowed      = "FORK_NOT_ALLOWED"
+	ErrInvalidLicense      = "INVALID_LICENSE"
+	ErrCommunityNotFound   = "COMMUNITY_NOT_FOUND"
+
+	ErrAttemptNotStarted = "ATTEMPT_NOT_STARTED"
+	ErrAttemptNotFound   = "ATTEMPT_NOT_FOUND"
+	ErrAttemptInProgress = "ATTEMPT_IN_PROGRESS"
+	ErrAttemptExpired    = "ATTEMPT_EXPIRED"
+	ErrMaxAttempts       = "MAX_ATTEMPTS_REACHED"
+	ErrAttemptCooldown   = "ATTEMPT_COOLDOWN"
+
+	ErrUnknownQuestion     = "UNKNOWN_QUESTION"
+	ErrDuplicateAnswer     = "DUPLICATE_ANSWER"
+	ErrOptionNotInQuestion = "OPTION_NOT_IN_QUESTION"
+	ErrDuplicateOption     = "DUPLICATE_OPTION"
+	ErrInvalidSelection    = "INVALID_SELECTION"
+	ErrInvalidAnswer       = "INVALID_ANSWER"
+)
+
+// Rate limit errors
+const (
+	ErrRateLimited   = "RATE_LIMITED"
+	ErrAccountLocked = "ACCOUNT_LOCKED"
 )
 
 // System errors
@@ -1,11 +1,16 @@
 package sharedErrors
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type AppError struct {
 	Code       string
 	Message    string
 	StatusCode int
+	// RetryAfter is sent as the Retry-After header when set.
+	RetryAfter time.Duration
 }
 
 func (e *AppError) Error() string {
@@ -13,23 +18,27 @@ func (e *AppError) Error() string {
 }
 
 func BadRequest(code, msg string) *AppError {
-	return &AppError{code, msg, http.StatusBadRequest}
+	return &AppError{Code: code, Message: msg, StatusCode: http.StatusBadRequest}
 }
 
 func Unauthorized(code, msg string) *AppError {
-	return &AppError{code, msg, http.StatusUnauthorized}
+	return &AppError{Code: code, Message: msg, StatusCode: http.StatusUnauthorized}
 }
 
 func Forbidden(code, msg string) *AppError {
-	return &AppError{code, msg, http.StatusForbidden}
+	return &AppError{Code: code, Message: msg, StatusCode: http.StatusForbidden}
 }
 
 func NotFound(code, msg string) *AppError {
-	return &AppError{code, msg, http.StatusNotFound}
+	return &AppError{Code: code, Message: msg, StatusCode: http.StatusNotFound}
 }
 
 func Conflict(code, msg string) *AppError {
-	return &AppError{code, msg, http.StatusConflict}
+	return &AppError{Code: code, Message: msg, StatusCode: http.StatusConflict}
+}
+
+func TooManyRequests(code, msg string, retryAfter time.Duration) *AppError {
+	return &AppError{Code: code, Message: msg, StatusCode: http.StatusTooManyRequests, RetryAfter: retryAfter}
 }
 
 func Internal() *AppError {
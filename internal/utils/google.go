@@ -7,7 +7,16 @@ import (
 	"golang.org/x/oauth2/google"
 )
 
-func GoogleConfig(clientID, clientSecret, redirectURL string) *oauth2.Config {
+// GoogleConfig builds the OAuth2 client config. authURL and tokenURL default
+// to Google's endpoints and can be overridden to run against a fake provider.
+func GoogleConfig(clientID, clientSecret, redirectURL, authURL, tokenURL string) *oauth2.Config {
+	endpoint := google.Endpoint
+	if authURL != "" {
+		endpoint.AuthURL = authURL
+	}
+	if tokenURL != "" {
+		endpoint.TokenURL = tokenURL
+	}
 	return &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
@@ -17,7 +26,7 @@ func GoogleConfig(clientID, clientSecret, redirectURL string) *oauth2.Config {
 			"email",
 			"profile",
 		},
-		Endpoint: google.Endpoint,
+		Endpoint: endpoint,
 	}
 }
 
@@ -1,19 +1,60 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
-func GenerateToken(email, userId, secret string) (string, error) {
+// GenerateToken mints a short-lived access token bound to a server-side
+// session, so revoking the session also invalidates the token.
+func GenerateToken(email, userId, sessionID, secret string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": userId,
 		"em":  email,
-		"exp": time.Now().Add(7 * 24 * time.Hour).Unix(),
+		"sid": sessionID,
+		"exp": time.Now().Add(ttl).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString([]byte(secret))
 
 }
+
+// GeneratePurposeToken signs a short-lived token that is only accepted by
+// ParsePurposeToken for the same purpose, e.g. confirming an account link.
+func GeneratePurposeToken(purpose, subject string, extra map[string]string, secret string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": subject,
+		"pur": purpose,
+		"exp": time.Now().Add(ttl).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(secret))
+}
+
+func ParsePurposeToken(tokenString, purpose, secret string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["pur"] != purpose {
+		return nil, errors.New("invalid or expired token")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("invalid or expired token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://idp.test"
+	testAudience = "ecoquiz-client"
+	testKeyID    = "key-1"
+	testNonce    = "nonce-123"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func validClaims(now time.Time) GoogleClaims {
+	return GoogleClaims{
+		Email:         "learner@example.com",
+		EmailVerified: true,
+		Nonce:         testNonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   "subject-1",
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func signIDToken(t *testing.T, key *rsa.PrivateKey, kid string, claims GoogleClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return raw
+}
+
+func TestIDTokenVerifier(t *testing.T) {
+	key := newTestKey(t)
+	verifier := NewIDTokenVerifier(StaticKeySource{testKeyID: &key.PublicKey}, testAudience, testIssuer)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		kid     string
+		nonce   string
+		modify  func(*GoogleClaims)
+		wantErr string
+	}{
+		{
+			name:  "valid token",
+			kid:   testKeyID,
+			nonce: testNonce,
+		},
+		{
+			name:    "wrong audience",
+			kid:     testKeyID,
+			nonce:   testNonce,
+			modify:  func(c *GoogleClaims) { c.Audience = jwt.ClaimStrings{"someone-else"} },
+			wantErr: "audience",
+		},
+		{
+			name:    "wrong issuer",
+			kid:     testKeyID,
+			nonce:   testNonce,
+			modify:  func(c *GoogleClaims) { c.Issuer = "https://evil.test" },
+			wantErr: "unexpected issuer",
+		},
+		{
+			name:  "expired token",
+			kid:   testKeyID,
+			nonce: testNonce,
+			modify: func(c *GoogleClaims) {
+				c.IssuedAt = jwt.NewNumericDate(now.Add(-2 * time.Hour))
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour))
+			},
+			wantErr: "expired",
+		},
+		{
+			name:    "nonce mismatch",
+			kid:     testKeyID,
+			nonce:   "another-nonce",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "no expected nonce",
+			kid:     testKeyID,
+			nonce:   "",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "unknown key id",
+			kid:     "key-2",
+			nonce:   testNonce,
+			wantErr: "unknown signing key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(now)
+			if tt.modify != nil {
+				tt.modify(&claims)
+			}
+			raw := signIDToken(t, key, tt.kid, claims)
+
+			got, err := verifier.Verify(context.Background(), raw, tt.nonce)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v, want nil", err)
+				}
+				if got.Subject != "subject-1" || got.Email != "learner@example.com" {
+					t.Fatalf("Verify() claims = %+v", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIDTokenVerifierRejectsOtherSigner(t *testing.T) {
+	key := newTestKey(t)
+	verifier := NewIDTokenVerifier(StaticKeySource{testKeyID: &newTestKey(t).PublicKey}, testAudience, testIssuer)
+
+	raw := signIDToken(t, key, testKeyID, validClaims(time.Now()))
+	if _, err := verifier.Verify(context.Background(), raw, testNonce); err == nil {
+		t.Fatal("Verify() accepted a token signed by another key")
+	}
+}
+
+// fakeIdP serves the JWKS of its keys and counts how often it is fetched.
+type fakeIdP struct {
+	keys    map[string]*rsa.PublicKey
+	fetches int
+}
+
+func (f *fakeIdP) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	f.fetches++
+	type jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	}
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range f.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(set)
+}
+
+func TestIDTokenVerifierWithJWKS(t *testing.T) {
+	key := newTestKey(t)
+	idp := &fakeIdP{keys: map[string]*rsa.PublicKey{testKeyID: &key.PublicKey}}
+	server := httptest.NewServer(idp)
+	defer server.Close()
+
+	verifier := NewIDTokenVerifier(NewJWKSKeySource(server.URL, server.Client()), testAudience, testIssuer)
+	raw := signIDToken(t, key, testKeyID, validClaims(time.Now()))
+
+	for i := 0; i < 2; i++ {
+		if _, err := verifier.Verify(context.Background(), raw, testNonce); err != nil {
+			t.Fatalf("Verify() error = %v, want nil", err)
+		}
+	}
+	if idp.fetches != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1 while cached", idp.fetches)
+	}
+
+	unknown := signIDToken(t, key, "key-2", validClaims(time.Now()))
+	if _, err := verifier.Verify(context.Background(), unknown, testNonce); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("Verify() error = %v, want unknown signing key", err)
+	}
+}
+
+func TestStaticKeySource(t *testing.T) {
+	key := newTestKey(t)
+	var public crypto.PublicKey = &key.PublicKey
+	keys := StaticKeySource{testKeyID: public}
+
+	got, err := keys.PublicKey(context.Background(), testKeyID)
+	if err != nil || got != public {
+		t.Fatalf("PublicKey(%q) = %v, %v", testKeyID, got, err)
+	}
+	if _, err := keys.PublicKey(context.Background(), "missing"); err == nil {
+		t.Fatal("PublicKey() found a key that is not there")
+	}
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmArgon2id = "argon2id"
+)
+
+const argon2SaltLength = 16
+const argon2KeyLength = 32
+
+// PasswordHasher hashes new passwords with the configured algorithm and
+// verifies stored hashes of any supported algorithm, including legacy
+// plaintext rows written before hashing was enforced.
+type PasswordHasher struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32 // KiB
+	Argon2Threads uint8
+}
+
+func NewPasswordHasher(algorithm string, bcryptCost int, argon2Time, argon2Memory uint32, argon2Threads uint8) (*PasswordHasher, error) {
+	h := &PasswordHasher{
+		Algorithm:     strings.ToLower(algorithm),
+		BcryptCost:    bcryptCost,
+		Argon2Time:    argon2Time,
+		Argon2Memory:  argon2Memory,
+		Argon2Threads: argon2Threads,
+	}
+	switch h.Algorithm {
+	case HashAlgorithmBcrypt:
+		if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case HashAlgorithmArgon2id:
+		if h.Argon2Time == 0 || h.Argon2Memory == 0 || h.Argon2Threads == 0 {
+			return nil, errors.New("argon2id time, memory and threads must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", algorithm)
+	}
+	return h, nil
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.Algorithm == HashAlgorithmArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.Argon2Time, h.Argon2Memory, h.Argon2Threads, argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version,
+			h.Argon2Memory,
+			h.Argon2Time,
+			h.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify reports whether password matches the stored hash and whether the
+// stored value should be replaced with a fresh hash using the current
+// settings (legacy plaintext, another algorithm or outdated parameters).
+func (h *PasswordHasher) Verify(password, stored string) (match bool, needsRehash bool) {
+	switch {
+	case isBcryptHash(stored):
+		if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+			return false, false
+		}
+		if h.Algorithm != HashAlgorithmBcrypt {
+			return true, true
+		}
+		cost, err := bcrypt.Cost([]byte(stored))
+		return true, err != nil || cost != h.BcryptCost
+
+	case strings.HasPrefix(stored, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(stored)
+		if err != nil {
+			return false, false
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, false
+		}
+		if h.Algorithm != HashAlgorithmArgon2id {
+			return true, true
+		}
+		return true, params.time != h.Argon2Time || params.memory != h.Argon2Memory || params.threads != h.Argon2Threads
+
+	default:
+		// Legacy rows stored the raw password.
+		if stored == "" || subtle.ConstantTimeCompare([]byte(stored), []byte(password)) != 1 {
+			return false, false
+		}
+		return true, true
+	}
+}
+
+func isBcryptHash(s string) bool {
+	return strings.HasPrefix(s, "$2a$") || strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$")
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func decodeArgon2id(encoded string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, err
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonPasswords is a short list of the most used and leaked passwords,
+// compared case-insensitively.
+//
+//go:embed common_passwords.txt
+var commonPasswords string
+
+var commonPasswordSet = func() map[string]struct{} {
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswords))
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			set[strings.ToLower(word)] = struct{}{}
+		}
+	}
+	return set
+}()
+
+// bcrypt only uses the first 72 bytes of a password and rejects longer ones.
+const maxPasswordBytes = 72
+
+// PasswordPolicy describes what a new password must satisfy. Character
+// classes are lowercase, uppercase, digits and everything else.
+type PasswordPolicy struct {
+	MinLength          int
+	MinCharClasses     int
+	RejectCommon       bool
+	RejectPersonalInfo bool
+}
+
+// Check returns a user-facing reason the password is rejected, or nil.
+// username and email are the account's own, used to reject passwords built
+// from them.
+func (p PasswordPolicy) Check(password, username, email string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	if classes := charClasses(password); classes < p.MinCharClasses {
+		return fmt.Errorf("password must mix at least %d of: lowercase letters, uppercase letters, digits, symbols", p.MinCharClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if p.RejectCommon && isCommonPassword(lower) {
+		return fmt.Errorf("password is too common")
+	}
+
+	if p.RejectPersonalInfo {
+		localPart, _, _ := strings.Cut(strings.ToLower(email), "@")
+		for _, part := range []string{strings.ToLower(username), localPart} {
+			if len(part) >= 3 && strings.Contains(lower, part) {
+				return fmt.Errorf("password must not contain your username or email")
+			}
+		}
+	}
+	return nil
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	count := 0
+	for _, has := range []bool{lower, upper, digit, other} {
+		if has {
+			count++
+		}
+	}
+	return count
+}
+
+// isCommonPassword also catches list entries decorated with trailing digits
+// or symbols, such as "dragon2024!".
+func isCommonPassword(lower string) bool {
+	if _, ok := commonPasswordSet[lower]; ok {
+		return true
+	}
+	trimmed := strings.TrimRightFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(trimmed) >= 4 {
+		if _, ok := commonPasswordSet[trimmed]; ok {
+			return true
+		}
+	}
+	return false
+}